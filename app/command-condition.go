@@ -2,18 +2,26 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+
 	"github.com/integrii/flaggy"
 	log "github.com/sirupsen/logrus"
 )
 
 // ConditionCommand represents the 'condition' subcommand.
 type ConditionCommand struct {
-	inFilePath     string             // the file to process
-	outAtvFilePath string             // the file receiving the conditioned result (ATV format)
-	outEcsFilePath string             // the file receiving the conditioned result (ECS container, unencrypted)
-	subcommand     *flaggy.Subcommand // flaggy's subcommand representing the 'condition' subcommand
+	inFilePath      string             // the file to process
+	outAtvFilePath  string             // the file receiving the conditioned result (ATV format)
+	outEcsFilePath  string             // the file receiving the conditioned result (ECS container, unencrypted)
+	outJSONFilePath string             // the file receiving the conditioned result (ATV document in JSON representation)
+	outYAMLFilePath string             // the file receiving the conditioned result (ATV document in YAML representation)
+	targetVersion   string             // the version to migrate the configuration to (optional)
+	outReportPath   string             // the file receiving the migration report (JSON format)
+	printReport     bool               // true to print the migration report
+	subcommand      *flaggy.Subcommand // flaggy's subcommand representing the 'condition' subcommand
 }
 
 // NewConditionCommand creates a new command handling the 'condition' subcommand.
@@ -26,9 +34,14 @@ func (cmd *ConditionCommand) AddFlaggySubcommand() *flaggy.Subcommand {
 
 	cmd.subcommand = flaggy.NewSubcommand("condition")
 	cmd.subcommand.Description = "Condition and/or convert a mGuard configuration file"
-	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration to condition (ATV format or unencrypted ECS container)")
+	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration to condition (ATV format, unencrypted ECS container or JSON/YAML representation)")
+	cmd.subcommand.String(&cmd.targetVersion, "", "target-version", "Version to migrate the configuration to (upwards or downwards)")
+	cmd.subcommand.String(&cmd.outReportPath, "", "migration-report", "File receiving the migration report listing all changes made by the migration (JSON format)")
+	cmd.subcommand.Bool(&cmd.printReport, "", "print-migration", "Print the migration report to stderr")
 	cmd.subcommand.String(&cmd.outAtvFilePath, "", "atv-out", "File receiving the conditioned configuration (ATV format, instead of stdout)")
 	cmd.subcommand.String(&cmd.outEcsFilePath, "", "ecs-out", "File receiving the conditioned configuration (ECS container, unencrypted, instead of stdout)")
+	cmd.subcommand.String(&cmd.outJSONFilePath, "", "json-out", "File receiving the conditioned configuration (ATV document in JSON representation, instead of stdout)")
+	cmd.subcommand.String(&cmd.outYAMLFilePath, "", "yaml-out", "File receiving the conditioned configuration (ATV document in YAML representation, instead of stdout)")
 
 	flaggy.AttachSubcommand(cmd.subcommand, 1)
 
@@ -43,6 +56,19 @@ func (cmd *ConditionCommand) IsSubcommandUsed() bool {
 // ValidateArguments checks whether the specified arguments for the 'condition' subcommand are valid.
 func (cmd *ConditionCommand) ValidateArguments() error {
 
+	// ensure that the target version is valid
+	if len(cmd.targetVersion) > 0 {
+		_, err := atv.ParseVersion(cmd.targetVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the migration report is available only when migrating
+	if (len(cmd.outReportPath) > 0 || cmd.printReport) && len(cmd.targetVersion) == 0 {
+		return fmt.Errorf("The migration report requires --target-version")
+	}
+
 	// ensure that the specified files exist and are readable
 	files := []string{cmd.inFilePath}
 	for _, path := range files {
@@ -70,6 +96,31 @@ func (cmd *ConditionCommand) ExecuteCommand() error {
 		return err
 	}
 
+	// migrate the configuration to the requested version, if necessary
+	if len(cmd.targetVersion) > 0 {
+		targetVersion, _ := atv.ParseVersion(cmd.targetVersion)
+		log.Infof("Migrating configuration to version %s...", targetVersion)
+		result, err := ecs.Atv.MigrateWithResult(targetVersion)
+		if err != nil {
+			return err
+		}
+		ecs.Atv = result.File
+
+		// print/write the migration report, if requested
+		if cmd.printReport {
+			printMigrationReport(result.Report)
+		}
+		if len(cmd.outReportPath) > 0 {
+			err := writeMigrationReport(cmd.outReportPath, result.Report)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// flag SNMPv3 users that still use the passphrase of the factory settings
+	warnAboutFactorySnmpUsers(ecs)
+
 	// write ATV file, if requested
 	if len(cmd.outAtvFilePath) > 0 {
 		fileWritten = true
@@ -92,6 +143,28 @@ func (cmd *ConditionCommand) ExecuteCommand() error {
 		}
 	}
 
+	// write JSON file, if requested
+	if len(cmd.outJSONFilePath) > 0 {
+		fileWritten = true
+		log.Infof("Writing JSON file (%s)...", cmd.outJSONFilePath)
+		err := writeAtvFile(ecs.Atv, jsonFormat, cmd.outJSONFilePath)
+		if err != nil {
+			log.Errorf("Writing JSON file (%s) failed: %s", cmd.outJSONFilePath, err)
+			return err
+		}
+	}
+
+	// write YAML file, if requested
+	if len(cmd.outYAMLFilePath) > 0 {
+		fileWritten = true
+		log.Infof("Writing YAML file (%s)...", cmd.outYAMLFilePath)
+		err := writeAtvFile(ecs.Atv, yamlFormat, cmd.outYAMLFilePath)
+		if err != nil {
+			log.Errorf("Writing YAML file (%s) failed: %s", cmd.outYAMLFilePath, err)
+			return err
+		}
+	}
+
 	// write the ECS container to stdout, if no output file was specified
 	if !fileWritten {
 		log.Info("Writing ECS file to stdout...")
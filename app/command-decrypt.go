@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
+)
+
+// DecryptCommand represents the 'decrypt' subcommand.
+type DecryptCommand struct {
+	inFilePath          string             // the file to process
+	privateKeyFilePath  string             // the file containing the private key of the device (PEM format)
+	certificateFilePath string             // the file containing the device certificate (PEM or DER format, optional)
+	outAtvFilePath      string             // the file receiving the decrypted configuration (ATV format)
+	outEcsFilePath      string             // the file receiving the decrypted configuration (ECS container, unencrypted)
+	subcommand          *flaggy.Subcommand // flaggy's subcommand representing the 'decrypt' subcommand
+}
+
+// NewDecryptCommand creates a new command handling the 'decrypt' subcommand.
+func NewDecryptCommand() *DecryptCommand {
+	return &DecryptCommand{}
+}
+
+// AddFlaggySubcommand adds the 'decrypt' subcommand to flaggy.
+func (cmd *DecryptCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("decrypt")
+	cmd.subcommand.Description = "Decrypt an encrypted mGuard configuration using the private key of the mGuard"
+	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration to decrypt (ECS container, encrypted)")
+	cmd.subcommand.String(&cmd.privateKeyFilePath, "", "key", "File containing the private key of the mGuard (PEM format, PKCS#1 or PKCS#8)")
+	cmd.subcommand.String(&cmd.certificateFilePath, "", "cert", "File containing the certificate of the mGuard (PEM or DER format, optional)")
+	cmd.subcommand.String(&cmd.outAtvFilePath, "", "atv-out", "File receiving the decrypted configuration (ATV format, instead of stdout)")
+	cmd.subcommand.String(&cmd.outEcsFilePath, "", "ecs-out", "File receiving the decrypted configuration (ECS container, unencrypted, instead of stdout)")
+
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'decrypt' subcommand was used in the command line.
+func (cmd *DecryptCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'decrypt' subcommand are valid.
+func (cmd *DecryptCommand) ValidateArguments() error {
+
+	// the private key is required
+	if len(cmd.privateKeyFilePath) == 0 {
+		return fmt.Errorf("The private key was not specified, please add '--key <path>' to the command line")
+	}
+
+	// ensure that the specified files exist and are readable
+	files := []string{cmd.inFilePath, cmd.privateKeyFilePath, cmd.certificateFilePath}
+	for _, path := range files {
+		if len(path) > 0 {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			file.Close()
+		}
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'decrypt' subcommand.
+func (cmd *DecryptCommand) ExecuteCommand() error {
+
+	fileWritten := false
+
+	// load the private key (and the certificate, if the file contains it)
+	log.Infof("Loading private key (%s)...", cmd.privateKeyFilePath)
+	privateKey, certificate, err := loadPrivateKeyFile(cmd.privateKeyFilePath)
+	if err != nil {
+		return err
+	}
+
+	// load the certificate, if specified explicitly
+	if len(cmd.certificateFilePath) > 0 {
+		log.Infof("Loading certificate (%s)...", cmd.certificateFilePath)
+		certificate, err = loadCertificateFile(cmd.certificateFilePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// load configuration file (can be ATV, ECS or encrypted ECS)
+	// (the configuration is always loaded into an ECS container, missing parts are filled with defaults)
+	ecs, err := loadEncryptedConfigurationFile(cmd.inFilePath, certificate, privateKey)
+	if err != nil {
+		return err
+	}
+
+	// write ATV file, if requested
+	if len(cmd.outAtvFilePath) > 0 {
+		fileWritten = true
+		log.Infof("Writing ATV file (%s)...", cmd.outAtvFilePath)
+		err := ecs.Atv.ToFile(cmd.outAtvFilePath)
+		if err != nil {
+			log.Errorf("Writing ATV file (%s) failed: %s", cmd.outAtvFilePath, err)
+			return err
+		}
+	}
+
+	// write ECS file, if requested
+	if len(cmd.outEcsFilePath) > 0 {
+		fileWritten = true
+		log.Infof("Writing ECS file (%s)...", cmd.outEcsFilePath)
+		err := ecs.ToFile(cmd.outEcsFilePath)
+		if err != nil {
+			log.Errorf("Writing ECS file (%s) failed: %s", cmd.outEcsFilePath, err)
+			return err
+		}
+	}
+
+	// write the ECS container to stdout, if no output file was specified
+	if !fileWritten {
+		log.Info("Writing ECS file to stdout...")
+		buffer := bytes.Buffer{}
+		err := ecs.ToWriter(&buffer)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(buffer.Bytes())
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+
+	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
+)
+
+// DiffCommand represents the 'diff' subcommand.
+type DiffCommand struct {
+	inFilePath1 string             // the first file to compare
+	inFilePath2 string             // the second file to compare
+	outFilePath string             // the file receiving the differences
+	json        bool               // true to write the differences in JSON format, otherwise false (plain text)
+	subcommand  *flaggy.Subcommand // flaggy's subcommand representing the 'diff' subcommand
+}
+
+// diffReport is the structure of the report written by the 'diff' subcommand in JSON format.
+type diffReport struct {
+	File1       string           `json:"file1"`
+	File2       string           `json:"file2"`
+	Version     string           `json:"version"`
+	Differences []atv.Difference `json:"differences"`
+}
+
+// NewDiffCommand creates a new command handling the 'diff' subcommand.
+func NewDiffCommand() *DiffCommand {
+	return &DiffCommand{}
+}
+
+// AddFlaggySubcommand adds the 'diff' subcommand to flaggy.
+func (cmd *DiffCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("diff")
+	cmd.subcommand.Description = "Compare the settings of two mGuard configuration files"
+	cmd.subcommand.AddPositionalValue(&cmd.inFilePath1, "1st-file", 1, true, "First configuration file to compare")
+	cmd.subcommand.AddPositionalValue(&cmd.inFilePath2, "2nd-file", 2, true, "Second configuration file to compare")
+	cmd.subcommand.Bool(&cmd.json, "", "json", "Write the differences in JSON format (instead of plain text)")
+	cmd.subcommand.String(&cmd.outFilePath, "", "out", "File receiving the differences (instead of stdout)")
+
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'diff' subcommand was used in the command line.
+func (cmd *DiffCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'diff' subcommand are valid.
+func (cmd *DiffCommand) ValidateArguments() error {
+
+	// ensure that the specified files exist and are readable
+	files := []string{cmd.inFilePath1, cmd.inFilePath2}
+	for _, path := range files {
+		if len(path) > 0 {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			file.Close()
+		}
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'diff' subcommand.
+func (cmd *DiffCommand) ExecuteCommand() error {
+
+	// load the first file (can be ATV or ECS)
+	ecs1, err := loadConfigurationFile(cmd.inFilePath1)
+	if err != nil {
+		return err
+	}
+
+	// determine the version of the first file
+	version1, err := ecs1.Atv.GetVersion()
+	if err != nil {
+		return err
+	}
+
+	// load the second file (can be ATV or ECS)
+	ecs2, err := loadConfigurationFile(cmd.inFilePath2)
+	if err != nil {
+		return err
+	}
+
+	// determine the version of the second file
+	version2, err := ecs2.Atv.GetVersion()
+	if err != nil {
+		return err
+	}
+
+	// migrate the older file to the version of the newer file, if necessary
+	atv1 := ecs1.Atv
+	atv2 := ecs2.Atv
+	version := version1
+	if version1.Compare(version2) < 0 {
+		log.Infof("Migrating first file (%s) from version %s to version %s...", cmd.inFilePath1, version1, version2)
+		atv1, err = atv1.Migrate(version2)
+		if err != nil {
+			return err
+		}
+		version = version2
+	} else if version1.Compare(version2) > 0 {
+		log.Infof("Migrating second file (%s) from version %s to version %s...", cmd.inFilePath2, version2, version1)
+		atv2, err = atv2.Migrate(version1)
+		if err != nil {
+			return err
+		}
+	}
+
+	// compare the configurations
+	diffs, err := atv1.Diff(atv2)
+	if err != nil {
+		return err
+	}
+
+	// format the differences
+	buffer := bytes.Buffer{}
+	if cmd.json {
+		report := diffReport{
+			File1:       cmd.inFilePath1,
+			File2:       cmd.inFilePath2,
+			Version:     version.String(),
+			Differences: diffs,
+		}
+		if report.Differences == nil {
+			report.Differences = []atv.Difference{}
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	} else {
+		for _, diff := range diffs {
+			buffer.WriteString(formatDifference(diff))
+		}
+	}
+
+	// write the differences
+	if len(cmd.outFilePath) > 0 {
+		log.Infof("Writing differences (%s)...", cmd.outFilePath)
+		err := ioutil.WriteFile(cmd.outFilePath, buffer.Bytes(), 0644)
+		if err != nil {
+			log.Errorf("Writing differences (%s) failed: %s", cmd.outFilePath, err)
+			return err
+		}
+	} else {
+		log.Info("Writing differences to stdout...")
+		os.Stdout.Write(buffer.Bytes())
+	}
+
+	// set the exit code to signal whether the configurations differ
+	if len(diffs) > 0 {
+		log.Infof("The configurations differ in %d settings.", len(diffs))
+		ExitCode = 1
+	} else {
+		log.Infof("The configurations are equal.")
+		ExitCode = 0
+	}
+
+	return nil
+}
+
+// formatDifference formats the specified difference as plain text.
+// Values spanning multiple lines (e.g. table rows) are written indented below the path.
+func formatDifference(diff atv.Difference) string {
+
+	builder := strings.Builder{}
+	writeValue := func(prefix string, value string) {
+		if strings.Contains(value, "\n") {
+			builder.WriteString("\n")
+			for _, line := range strings.Split(value, "\n") {
+				builder.WriteString(fmt.Sprintf("    %s %s\n", prefix, line))
+			}
+		} else {
+			builder.WriteString(fmt.Sprintf(" %s\n", value))
+		}
+	}
+
+	switch diff.Kind {
+	case atv.SettingAdded:
+		builder.WriteString(fmt.Sprintf("+ %s:", diff.Path))
+		writeValue("+", diff.NewValue)
+	case atv.SettingRemoved:
+		builder.WriteString(fmt.Sprintf("- %s:", diff.Path))
+		writeValue("-", diff.OldValue)
+	case atv.SettingChanged:
+		if strings.Contains(diff.OldValue, "\n") || strings.Contains(diff.NewValue, "\n") {
+			builder.WriteString(fmt.Sprintf("~ %s:", diff.Path))
+			writeValue("-", diff.OldValue)
+			builder.WriteString(fmt.Sprintf("~ %s:", diff.Path))
+			writeValue("+", diff.NewValue)
+		} else {
+			builder.WriteString(fmt.Sprintf("~ %s: %s => %s\n", diff.Path, diff.OldValue, diff.NewValue))
+		}
+	default:
+		panic("Unhandled difference kind")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
+)
+
+// EcsCommand represents the 'ecs' subcommand.
+type EcsCommand struct {
+	inFilePath        string             // the ECS container to process
+	outFilePath       string             // the file receiving the extracted entry
+	outDirectoryPath  string             // the directory receiving all extracted entries
+	entryName         string             // name of the entry to extract
+	subcommand        *flaggy.Subcommand // flaggy's subcommand representing the 'ecs' subcommand
+	lsSubcommand      *flaggy.Subcommand // flaggy's subcommand representing the 'ecs ls' subcommand
+	extractSubcommand *flaggy.Subcommand // flaggy's subcommand representing the 'ecs extract' subcommand
+}
+
+// NewEcsCommand creates a new command handling the 'ecs' subcommand.
+func NewEcsCommand() *EcsCommand {
+	return &EcsCommand{}
+}
+
+// AddFlaggySubcommand adds the 'ecs' subcommand to flaggy.
+func (cmd *EcsCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("ecs")
+	cmd.subcommand.Description = "List and extract the entries of an ECS container"
+
+	cmd.lsSubcommand = flaggy.NewSubcommand("ls")
+	cmd.lsSubcommand.Description = "List the entries of an ECS container"
+	cmd.lsSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+
+	cmd.extractSubcommand = flaggy.NewSubcommand("extract")
+	cmd.extractSubcommand.Description = "Extract an entry (or all entries) of an ECS container"
+	cmd.extractSubcommand.AddPositionalValue(&cmd.entryName, "entry", 1, false, "Name of the entry to extract (e.g. aca/cfg, default: all entries)")
+	cmd.extractSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.extractSubcommand.String(&cmd.outFilePath, "", "out", "File receiving the extracted entry (instead of stdout)")
+	cmd.extractSubcommand.String(&cmd.outDirectoryPath, "", "dir", "Directory receiving all entries (if no entry is specified)")
+
+	// attach subcommands to flaggy
+	cmd.subcommand.AttachSubcommand(cmd.lsSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.extractSubcommand, 1)
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'ecs' subcommand was used in the command line.
+func (cmd *EcsCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'ecs' subcommand are valid.
+func (cmd *EcsCommand) ValidateArguments() error {
+
+	// ensure that one of the subcommands is specified
+	if !cmd.lsSubcommand.Used && !cmd.extractSubcommand.Used {
+		flaggy.ShowHelpAndExit("")
+	}
+
+	// ensure that either a single entry or all entries are extracted
+	if cmd.extractSubcommand.Used {
+		if len(cmd.entryName) > 0 && len(cmd.outDirectoryPath) > 0 {
+			return fmt.Errorf("--dir can only be used when extracting all entries")
+		}
+		if len(cmd.entryName) == 0 && len(cmd.outDirectoryPath) == 0 {
+			return fmt.Errorf("Please specify the entry to extract or the directory receiving all entries (--dir)")
+		}
+		if len(cmd.entryName) == 0 && len(cmd.outFilePath) > 0 {
+			return fmt.Errorf("--out can only be used when extracting a single entry")
+		}
+	}
+
+	// ensure that the specified file exists and is readable
+	if len(cmd.inFilePath) > 0 {
+		file, err := os.Open(cmd.inFilePath)
+		if err != nil {
+			return err
+		}
+		file.Close()
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'ecs' subcommand.
+func (cmd *EcsCommand) ExecuteCommand() error {
+
+	// load configuration file (can be ATV or ECS)
+	// (the configuration is always loaded into an ECS container, missing parts are filled with defaults)
+	ecs, err := loadConfigurationFile(cmd.inFilePath)
+	if err != nil {
+		return err
+	}
+
+	entries, err := ecs.Entries()
+	if err != nil {
+		return err
+	}
+
+	// list entries
+	if cmd.lsSubcommand.Used {
+		log.Infof("Writing %d entries to stdout...", len(entries))
+		for _, entry := range entries {
+			owner := fmt.Sprintf("%d/%d", entry.UID, entry.GID)
+			line := fmt.Sprintf("%s %-9s %8d %s %s",
+				entry.Mode, owner, entry.Size, entry.ModTime.Format("2006-01-02 15:04:05"), entry.Name)
+			if len(entry.Linkname) > 0 {
+				line += " -> " + entry.Linkname
+			}
+			fmt.Fprintln(os.Stdout, line)
+		}
+		return nil
+	}
+
+	// extract a single entry
+	if len(cmd.entryName) > 0 {
+		data, err := ecs.ReadEntry(cmd.entryName)
+		if err != nil {
+			return err
+		}
+		if len(cmd.outFilePath) > 0 {
+			log.Infof("Writing entry '%s' to file (%s)...", cmd.entryName, cmd.outFilePath)
+			return ioutil.WriteFile(cmd.outFilePath, data, 0600)
+		}
+		log.Infof("Writing entry '%s' to stdout...", cmd.entryName)
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	// extract all entries
+	// (entries are extracted with their permissions, links are not extracted)
+	for _, entry := range entries {
+
+		path, err := extractionPath(cmd.outDirectoryPath, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.Mode.IsDir():
+			log.Infof("Creating directory (%s)...", path)
+			err = os.MkdirAll(path, entry.Mode.Perm()|0700)
+		case entry.Mode.IsRegular():
+			log.Infof("Writing entry '%s' to file (%s)...", entry.Name, path)
+			var data []byte
+			data, err = ecs.ReadEntry(entry.Name)
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(path), 0700)
+			}
+			if err == nil {
+				err = ioutil.WriteFile(path, data, entry.Mode.Perm())
+			}
+		default:
+			log.Warnf("Entry '%s' is not a regular file or a directory, skipping it.", entry.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractionPath returns the path of the file an entry of an ECS container is extracted to.
+// Entries that would be extracted outside the specified directory are refused.
+func extractionPath(directory string, name string) (string, error) {
+
+	path := filepath.Join(directory, filepath.FromSlash(name))
+	relativePath, err := filepath.Rel(directory, path)
+	if err != nil || filepath.IsAbs(name) || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("The entry '%s' cannot be extracted, it is outside the target directory", name)
+	}
+
+	return path, nil
+}
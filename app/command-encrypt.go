@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/griffinplus/mguard-config-tool/mguard/certmgr"
+	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
 	"github.com/integrii/flaggy"
 	log "github.com/sirupsen/logrus"
 )
@@ -16,6 +17,7 @@ type EncryptCommand struct {
 	outEcsFilePath string             // the file receiving the conditioned result (ECS container, encrypted)
 	serial         string             // serial number of the mGuard to encrypt for
 	cacheDirectory string             // path of the directory where certificates are cached
+	useOpenssl     bool               // true to encrypt using the openssl executable, false to use the built-in implementation
 	subcommand     *flaggy.Subcommand // flaggy's subcommand representing the 'encrypt' subcommand
 }
 
@@ -33,6 +35,7 @@ func (cmd *EncryptCommand) AddFlaggySubcommand() *flaggy.Subcommand {
 	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration to encrypt (ATV format or unencrypted ECS container)")
 	cmd.subcommand.String(&cmd.outEcsFilePath, "", "ecs-out", "File receiving the encrypted configuration (ECS container, encrypted, instead of stdout)")
 	cmd.subcommand.String(&cmd.cacheDirectory, "", "cache", "Directory where certificates are cached")
+	cmd.subcommand.Bool(&cmd.useOpenssl, "", "openssl", "Encrypt using the openssl executable (instead of the built-in implementation)")
 
 	flaggy.AttachSubcommand(cmd.subcommand, 1)
 
@@ -67,6 +70,16 @@ func (cmd *EncryptCommand) ExecuteCommand() error {
 
 	fileWritten := false
 
+	// use the openssl executable for encryption, if requested
+	if cmd.useOpenssl {
+		opensslExecutablePath, err := ecs.GetOpensslExecutablePath()
+		if err != nil {
+			return fmt.Errorf("The openssl executable was not found: %v", err)
+		}
+		log.Infof("Using openssl executable (%s) for encryption...", opensslExecutablePath)
+		ecs.EnableOpensslEncryption(true)
+	}
+
 	// initialize the certificate manager
 	// (load credentials from mguard-device-database.yaml)
 	certificateManager, err := certmgr.NewCertificateManager(cmd.cacheDirectory, "", "")
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetCommand represents the 'get' subcommand.
+type GetCommand struct {
+	inFilePath  string             // the file to read the setting from
+	settingPath string             // path of the setting to get
+	subcommand  *flaggy.Subcommand // flaggy's subcommand representing the 'get' subcommand
+}
+
+// NewGetCommand creates a new command handling the 'get' subcommand.
+func NewGetCommand() *GetCommand {
+	return &GetCommand{}
+}
+
+// AddFlaggySubcommand adds the 'get' subcommand to flaggy.
+func (cmd *GetCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("get")
+	cmd.subcommand.Description = "Print the value of a setting (or of all settings matching a query) in a mGuard configuration file"
+	cmd.subcommand.AddPositionalValue(&cmd.settingPath, "path", 1, true, "Path of the setting (e.g. VPN_CONNECTION.0.VPN_START or VPN_CONNECTION[NAME=\"plant-a\"].VPN_START)")
+	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration (ATV format or unencrypted ECS container, instead of stdin)")
+
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'get' subcommand was used in the command line.
+func (cmd *GetCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'get' subcommand are valid.
+func (cmd *GetCommand) ValidateArguments() error {
+
+	// ensure that the specified file exists and is readable
+	if len(cmd.inFilePath) > 0 {
+		file, err := os.Open(cmd.inFilePath)
+		if err != nil {
+			return err
+		}
+		file.Close()
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'get' subcommand.
+func (cmd *GetCommand) ExecuteCommand() error {
+
+	// load configuration file (can be ATV or ECS)
+	ecs, err := loadConfigurationFile(cmd.inFilePath)
+	if err != nil {
+		return err
+	}
+
+	// get the values of the settings (the path may select multiple settings)
+	values, err := ecs.Atv.GetSettingValues(cmd.settingPath)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("The setting '%s' does not exist", cmd.settingPath)
+	}
+
+	log.Infof("Writing %d value(s) of setting '%s' to stdout...", len(values), cmd.settingPath)
+	for _, value := range values {
+		fmt.Fprintln(os.Stdout, value)
+	}
+	return nil
+}
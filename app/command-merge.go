@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/griffinplus/mguard-config-tool/mguard/atv"
@@ -18,9 +20,20 @@ type MergeCommand struct {
 	inMergeConfigPath string             // the configuration file controlling the merge process (optional)
 	outAtvFilePath    string             // the file receiving the merged result (ATV format)
 	outEcsFilePath    string             // the file receiving the merged result (ECS container, unencrypted)
+	outReportPath     string             // the file receiving the merge report (JSON format)
+	outMigrationPath  string             // the file receiving the migration report (JSON format)
+	printMigration    bool               // true to print the migration report
+	remapRowIDs       bool               // true to rename row ids of the second file that are used in the first file as well
+	targetVersion     string             // the version to migrate the merged result to (optional)
 	subcommand        *flaggy.Subcommand // flaggy's subcommand representing the 'merge' subcommand
 }
 
+// mergeMigrationReport is the structure of the migration report written by the 'merge' subcommand in JSON format.
+type mergeMigrationReport struct {
+	SecondFile *atv.MigrationReport `json:"secondFile"`       // migration of the second file to the version of the first file
+	Merged     *atv.MigrationReport `json:"merged,omitempty"` // migration of the merged configuration to the target version
+}
+
 // NewMergeCommand creates a new command handling the 'merge' subcommand.
 func NewMergeCommand() *MergeCommand {
 	return &MergeCommand{}
@@ -34,8 +47,13 @@ func (cmd *MergeCommand) AddFlaggySubcommand() *flaggy.Subcommand {
 	cmd.subcommand.AddPositionalValue(&cmd.inFilePath1, "1st-file", 1, true, "First configuration file to merge")
 	cmd.subcommand.AddPositionalValue(&cmd.inFilePath2, "2nd-file", 2, true, "Second configuration file to merge")
 	cmd.subcommand.String(&cmd.inMergeConfigPath, "", "config", "Merge configuration file")
+	cmd.subcommand.Bool(&cmd.remapRowIDs, "", "remap-rids", "Rename row ids of the second file that are used in the first file as well")
+	cmd.subcommand.String(&cmd.targetVersion, "", "target-version", "Version to migrate the merged configuration to (default: version of the first file)")
 	cmd.subcommand.String(&cmd.outAtvFilePath, "", "atv-out", "File receiving the merged configuration (ATV format)")
 	cmd.subcommand.String(&cmd.outEcsFilePath, "", "ecs-out", "File receiving the merged configuration (ECS container, unencrypted, instead of stdout)")
+	cmd.subcommand.String(&cmd.outReportPath, "", "report", "File receiving the merge report listing access violations (JSON format)")
+	cmd.subcommand.String(&cmd.outMigrationPath, "", "migration-report", "File receiving the migration report listing all changes made by migrations (JSON format)")
+	cmd.subcommand.Bool(&cmd.printMigration, "", "print-migration", "Print the migration report to stderr")
 
 	flaggy.AttachSubcommand(cmd.subcommand, 1)
 
@@ -50,6 +68,14 @@ func (cmd *MergeCommand) IsSubcommandUsed() bool {
 // ValidateArguments checks whether the specified arguments for the 'merge' subcommand are valid.
 func (cmd *MergeCommand) ValidateArguments() error {
 
+	// ensure that the target version is valid
+	if len(cmd.targetVersion) > 0 {
+		_, err := atv.ParseVersion(cmd.targetVersion)
+		if err != nil {
+			return err
+		}
+	}
+
 	// ensure that the specified files exist and are readable
 	files := []string{cmd.inFilePath1, cmd.inFilePath2, cmd.inMergeConfigPath}
 	for _, path := range files {
@@ -104,22 +130,78 @@ func (cmd *MergeCommand) ExecuteCommand() error {
 		}
 	}
 
-	// ensure that the first file has the same or a higher version than the second file
-	if version1.Compare(version2) < 0 {
+	// ensure that both files are made for the same family of devices
+	// (migrating between device families drops settings, so merging would produce a configuration neither device expects)
+	if version1.DeviceFamily() != version2.DeviceFamily() {
 		return fmt.Errorf(
-			"The first file (%s, version: %s) must have the same or a higher version than the second file (%s, version: %s)",
-			cmd.inFilePath1, version1,
-			cmd.inFilePath2, version2)
+			"The files cannot be merged, the first file (%s, version: %s) is made for %s, but the second file (%s, version: %s) is made for %s",
+			cmd.inFilePath1, version1, version1.DeviceFamily(),
+			cmd.inFilePath2, version2, version2.DeviceFamily())
 	}
 
 	// migrate second file to the version of the first file, if necessary
-	atv2, err := ecs2.Atv.Migrate(version1)
+	// (settings that do not exist in an older version of the first file are lost)
+	if version1.CompareRelease(version2) < 0 {
+		log.Warnf(
+			"The second file (%s, version: %s) has a higher version than the first file (%s, version: %s), migrating it down...",
+			cmd.inFilePath2, version2,
+			cmd.inFilePath1, version1)
+	}
+	migration2, err := ecs2.Atv.MigrateWithResult(version1)
 	if err != nil {
 		return err
 	}
+	atv2 := migration2.File
+	migrationReport := mergeMigrationReport{SecondFile: migration2.Report}
+
+	// rename row ids of the second file that collide with row ids of the first file, if requested
+	if cmd.remapRowIDs {
+		atv2, _, err = atv2.RemapCollidingRowIDs(ecs1.Atv)
+		if err != nil {
+			return err
+		}
+	}
 
 	// merge the configuration stored in both ECS containers
-	mergedAtv, err := ecs1.Atv.MergeSelectively(atv2, mergeConfig)
+	mergedAtv, report, err := ecs1.Atv.MergeSelectively(atv2, mergeConfig)
+	if len(cmd.outReportPath) > 0 && report != nil {
+		reportErr := writeMergeReport(cmd.outReportPath, report)
+		if reportErr != nil {
+			return reportErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// migrate the merged configuration to the requested version, if necessary
+	if len(cmd.targetVersion) > 0 {
+		targetVersion, _ := atv.ParseVersion(cmd.targetVersion)
+		log.Infof("Migrating merged configuration to version %s...", targetVersion)
+		migration, err := mergedAtv.MigrateWithResult(targetVersion)
+		if err != nil {
+			return err
+		}
+		mergedAtv = migration.File
+		migrationReport.Merged = migration.Report
+	}
+
+	// print/write the migration report, if requested
+	if cmd.printMigration {
+		printMigrationReport(migrationReport.SecondFile)
+		if migrationReport.Merged != nil {
+			printMigrationReport(migrationReport.Merged)
+		}
+	}
+	if len(cmd.outMigrationPath) > 0 {
+		err := writeMigrationReport(cmd.outMigrationPath, migrationReport)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ensure that the merged configuration is consistent
+	err = validateMergedConfiguration(mergedAtv)
 	if err != nil {
 		return err
 	}
@@ -131,6 +213,9 @@ func (cmd *MergeCommand) ExecuteCommand() error {
 		return err
 	}
 
+	// flag SNMPv3 users that still use the passphrase of the factory settings
+	warnAboutFactorySnmpUsers(mergedEcs)
+
 	// write ATV file, if requested
 	fileWritten := false
 	if len(cmd.outAtvFilePath) > 0 {
@@ -167,3 +252,25 @@ func (cmd *MergeCommand) ExecuteCommand() error {
 
 	return nil
 }
+
+// writeMergeReport writes the specified merge report to the specified file (JSON format).
+func writeMergeReport(path string, report *atv.MergeReport) error {
+
+	if report.Violations == nil {
+		report.Violations = []atv.AccessViolation{}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Writing merge report (%s)...", path)
+	err = ioutil.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		log.Errorf("Writing merge report (%s) failed: %s", path, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/integrii/flaggy"
+)
+
+// RemoveCommand represents the 'remove' subcommand.
+type RemoveCommand struct {
+	inFilePath     string             // the file to edit
+	outAtvFilePath string             // the file receiving the edited configuration (ATV format)
+	outEcsFilePath string             // the file receiving the edited configuration (ECS container, unencrypted)
+	batchFilePath  string             // the file containing edits to apply
+	settingPath    string             // path of the setting to remove
+	subcommand     *flaggy.Subcommand // flaggy's subcommand representing the 'remove' subcommand
+}
+
+// NewRemoveCommand creates a new command handling the 'remove' subcommand.
+func NewRemoveCommand() *RemoveCommand {
+	return &RemoveCommand{}
+}
+
+// AddFlaggySubcommand adds the 'remove' subcommand to flaggy.
+func (cmd *RemoveCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("remove")
+	cmd.subcommand.Description = "Remove a setting or a table row from a mGuard configuration file"
+	cmd.subcommand.AddPositionalValue(&cmd.settingPath, "path", 1, false, "Path of the setting or table row (e.g. VPN_CONNECTION.0)")
+	cmd.subcommand.String(&cmd.inFilePath, "", "in", "File containing the mGuard configuration (ATV format or unencrypted ECS container, instead of stdin)")
+	cmd.subcommand.String(&cmd.batchFilePath, "", "batch", "File containing edits to apply (one 'set <path> <value>' or 'remove <path>' per line)")
+	cmd.subcommand.String(&cmd.outAtvFilePath, "", "atv-out", "File receiving the edited configuration (ATV format)")
+	cmd.subcommand.String(&cmd.outEcsFilePath, "", "ecs-out", "File receiving the edited configuration (ECS container, unencrypted)")
+
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'remove' subcommand was used in the command line.
+func (cmd *RemoveCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'remove' subcommand are valid.
+func (cmd *RemoveCommand) ValidateArguments() error {
+
+	// ensure that a setting or a batch file is specified
+	if len(cmd.settingPath) == 0 && len(cmd.batchFilePath) == 0 {
+		return fmt.Errorf("Neither a setting nor a batch file was specified")
+	}
+
+	// ensure that the specified files exist and are readable
+	files := []string{cmd.inFilePath, cmd.batchFilePath}
+	for _, path := range files {
+		if len(path) > 0 {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			file.Close()
+		}
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'remove' subcommand.
+func (cmd *RemoveCommand) ExecuteCommand() error {
+
+	// load configuration file (can be ATV or ECS)
+	ecs, format, err := loadConfigurationFileWithFormat(cmd.inFilePath, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	// remove the setting
+	if len(cmd.settingPath) > 0 {
+		err := ecs.Atv.RemoveSetting(cmd.settingPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// apply edits in the batch file
+	if len(cmd.batchFilePath) > 0 {
+		err := applyEditBatchFile(ecs.Atv, cmd.batchFilePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write the edited configuration
+	return writeEditedConfigurationFile(ecs, format, cmd.inFilePath, cmd.outAtvFilePath, cmd.outEcsFilePath)
+}
@@ -3,34 +3,259 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/template"
+
 	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
 )
 
 // ServiceCommand represents the 'service' subcommand.
 type ServiceCommand struct {
+	serviceSettings                          // settings loaded from the service configuration file
+	serviceName           string             // name of the service (name of the systemd unit)
+	serviceDescription    string             // description of the service
+	configPath            string             // path of the service configuration file
+	unitFilePath          string             // path of the systemd unit file to write (empty to write to stdout)
+	unitUser              string             // user the service should run as (empty to let systemd run it as root)
+	subcommand            *flaggy.Subcommand // flaggy's subcommand representing the 'service' subcommand
+	runSubcommand         *flaggy.Subcommand // flaggy's subcommand representing the 'service run' subcommand
+	systemdUnitSubcommand *flaggy.Subcommand // flaggy's subcommand representing the 'service systemd-unit' subcommand
 }
 
+// systemdUnitTemplate is the template of the systemd unit file running the service.
+const systemdUnitTemplate = `[Unit]
+Description={{.Description}}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart="{{.ExecutablePath}}" service run --config "{{.ConfigPath}}"
+ExecReload=/bin/kill -HUP $MAINPID
+WorkingDirectory={{.WorkingDirectory}}
+{{if .User}}User={{.User}}
+{{end}}Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`
+
 // NewServiceCommand creates a new command handling the 'service' subcommand.
 func NewServiceCommand() *ServiceCommand {
-	return &ServiceCommand{}
+	exePath, _ := exePath()
+	defaultConfigPath := filepath.Join(filepath.Dir(exePath), "mguard-config-tool.yaml")
+	return &ServiceCommand{
+		configPath:         defaultConfigPath,
+		serviceName:        "mguard-config-tool",
+		serviceDescription: "mGuard Configuration Preparation Service (CPS)",
+	}
 }
 
 // AddFlaggySubcommand adds the 'service' subcommand to flaggy.
 func (cmd *ServiceCommand) AddFlaggySubcommand() *flaggy.Subcommand {
-	return nil // do not add subcommand
+
+	cmd.subcommand = flaggy.NewSubcommand("service")
+	cmd.subcommand.Description = "Controls the mGuard Configuration Preparation Service (CPS)"
+
+	cmd.runSubcommand = flaggy.NewSubcommand("run")
+	cmd.runSubcommand.Description = "Run the service in the foreground (as a systemd service or for debugging purposes)"
+	cmd.runSubcommand.String(&cmd.configPath, "", "config", "Service configuration file")
+
+	cmd.systemdUnitSubcommand = flaggy.NewSubcommand("systemd-unit")
+	cmd.systemdUnitSubcommand.Description = "Generate a systemd unit file running the service"
+	cmd.systemdUnitSubcommand.String(&cmd.configPath, "", "config", "Service configuration file")
+	cmd.systemdUnitSubcommand.String(&cmd.unitFilePath, "", "out", "File receiving the systemd unit (instead of stdout)")
+	cmd.systemdUnitSubcommand.String(&cmd.unitUser, "", "user", "User the service should run as (default: root)")
+
+	cmd.subcommand.AttachSubcommand(cmd.runSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.systemdUnitSubcommand, 1)
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
 }
 
 // IsSubcommandUsed checks whether the 'service' subcommand was used in the command line.
 func (cmd *ServiceCommand) IsSubcommandUsed() bool {
-	return false
+	return cmd.subcommand.Used
 }
 
 // ValidateArguments checks whether the specified arguments for the 'service' subcommand are valid.
 func (cmd *ServiceCommand) ValidateArguments() error {
+
+	// ensure that one of the subcommands is specified
+	if !cmd.runSubcommand.Used && !cmd.systemdUnitSubcommand.Used {
+		flaggy.ShowHelpAndExit("")
+	}
+
+	// the service configuration file must be specified
+	if len(cmd.configPath) == 0 {
+		return fmt.Errorf("The service configuration file was not specified, please add '--config <path>' to the command line")
+	}
+
+	// make the path of the service configuration file absolute as the service might run in a different directory
+	path, err := filepath.Abs(cmd.configPath)
+	if err != nil {
+		return err
+	}
+	cmd.configPath = path
+
 	return nil
 }
 
 // ExecuteCommand performs the actual work of the 'service' subcommand.
 func (cmd *ServiceCommand) ExecuteCommand() error {
+
+	if cmd.runSubcommand.Used {
+		return cmd.runService()
+	} else if cmd.systemdUnitSubcommand.Used {
+		return cmd.writeSystemdUnit()
+	}
+
+	flaggy.ShowHelpAndExit("No command specified.")
+	return nil
+}
+
+// runService runs the service and blocks until it is terminated by SIGTERM or SIGINT.
+// SIGHUP lets the service reload its configuration.
+func (cmd *ServiceCommand) runService() error {
+
+	// systemd adds timestamps to log messages and does not handle colors
+	// => disable both, if running as a systemd service
+	if len(os.Getenv("INVOCATION_ID")) > 0 {
+		log.SetFormatter(&log.TextFormatter{DisableTimestamp: true, DisableLevelTruncation: true, DisableColors: true})
+	}
+
+	// register for signals controlling the service
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	// load the service configuration file and check the configured files
+	err := cmd.initService(true)
+	if err != nil {
+		return err
+	}
+
+	// start watching the hot folder
+	log.Infof("Starting %s service", cmd.serviceName)
+	monitor, err := newHotFolderMonitor(cmd)
+	if err != nil {
+		log.Errorf("%s service failed: %v", cmd.serviceName, err)
+		return err
+	}
+	defer func() { monitor.Close() }()
+
+loop:
+	for {
+		select {
+
+		// handle signals
+		case sig := <-signals:
+			switch sig {
+			case syscall.SIGTERM, syscall.SIGINT:
+				log.Infof("Received %s, stopping %s service", sig, cmd.serviceName)
+				break loop
+			case syscall.SIGHUP:
+				log.Infof("Received %s, reloading configuration", sig)
+				previousSettings := cmd.serviceSettings
+				err := cmd.initService(false)
+				if err != nil {
+					log.Errorf("Reloading configuration failed, keeping previous configuration: %v", err)
+					continue
+				}
+
+				// the hot folder may have changed
+				// => restart watching it (keep watching the old one with the previous configuration on error)
+				newMonitor, err := newHotFolderMonitor(cmd)
+				if err != nil {
+					log.Errorf("Watching hot folder (%s) failed, keeping previous configuration: %v", cmd.hotFolderPath, err)
+					cmd.serviceSettings = previousSettings
+					err = cmd.applyGlobalSettings()
+					if err != nil {
+						log.Errorf("Restoring previous configuration failed: %v", err)
+					}
+					continue
+				}
+				monitor.Close()
+				monitor = newMonitor
+				log.Info("Reloading configuration succeeded.")
+			}
+
+		// handle file system watcher events
+		case event, ok := <-monitor.Events():
+			if ok {
+				monitor.HandleEvent(event)
+			}
+
+		// handle file system watcher errors
+		case err, ok := <-monitor.Errors():
+			if ok {
+				log.Errorf("watcher error: %v", err)
+			}
+
+		// process files in the hot folder that did not change within the specified time
+		case <-monitor.Timer():
+			monitor.ProcessFiles()
+		}
+	}
+
+	// the service has stopped
+	log.Infof("%s service stopped", cmd.serviceName)
+	return nil
+}
+
+// writeSystemdUnit writes a systemd unit file that runs the service with the specified service configuration.
+func (cmd *ServiceCommand) writeSystemdUnit() error {
+
+	exePath, err := exePath()
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		Description      string
+		ExecutablePath   string
+		ConfigPath       string
+		WorkingDirectory string
+		User             string
+	}{
+		cmd.serviceDescription,
+		exePath,
+		cmd.configPath,
+		filepath.Dir(cmd.configPath),
+		cmd.unitUser,
+	}
+
+	// render the unit file
+	tmpl := template.Must(template.New("unit").Parse(systemdUnitTemplate))
+	buffer := bytes.Buffer{}
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		return err
+	}
+
+	// write the unit file to stdout, if no output file was specified
+	if len(cmd.unitFilePath) == 0 {
+		log.Info("Writing systemd unit to stdout...")
+		os.Stdout.Write(buffer.Bytes())
+		return nil
+	}
+
+	log.Infof("Writing systemd unit (%s)...", cmd.unitFilePath)
+	err = ioutil.WriteFile(cmd.unitFilePath, buffer.Bytes(), 0644)
+	if err != nil {
+		log.Errorf("Writing systemd unit (%s) failed: %s", cmd.unitFilePath, err)
+		return err
+	}
+
+	log.Infof("Install the service using 'systemctl enable --now %s'.", filepath.Base(cmd.unitFilePath))
 	return nil
 }
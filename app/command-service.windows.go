@@ -5,9 +5,6 @@ package main
 import (
 	"path/filepath"
 
-	"github.com/griffinplus/mguard-config-tool/mguard/atv"
-	"github.com/griffinplus/mguard-config-tool/mguard/certmgr"
-
 	"github.com/integrii/flaggy"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows/svc"
@@ -17,39 +14,18 @@ import (
 
 // ServiceCommand represents the 'service' subcommand.
 type ServiceCommand struct {
-	serviceName                             string                      // name of the service
-	serviceConfig                           mgr.Config                  // configuration of the service
-	cacheDirectory                          string                      // path of the directory where the service caches files
-	certificateManager                      *certmgr.CertificateManager // certificate manager that takes care of caching and downloading device certificates
-	configPath                              string                      // path of the base configuration file
-	sdcardTemplateDirectory                 string                      // path of the directory containing the basic structure of an sdcard (incl. firmware files)
-	baseConfigurationPath                   string                      // path of the mguard configuration file to use as base configuration
-	mergeConfigurationPath                  string                      // path of the merge configuration file that defines which settings to merge into the base configuration
-	hotFolderPath                           string                      // path of the directory to watch for atv/ecs files with configurations to merge with the base configuration
-	passwordsRoot                           string                      // password of user 'root'
-	passwordsAdmin                          string                      // password of user 'admin'
-	mergedConfigurationDirectory            string                      // path of the directory where to store merged mguard configurations
-	mergedConfigurationsWriteAtv            bool                        // true to write an ATV file with the merged configuration, otherwise false
-	mergedConfigurationsWriteUnencryptedEcs bool                        // true to write an unencrypted ECS file with the merged configuration, otherwise false
-	mergedConfigurationsWriteEncryptedEcs   bool                        // true to write an encrypted ECS file with the merged configuration, otherwise false
-	updatePackageDirectory                  string                      // path of the directory where to store update packages (for use on an sdcard)
-	updatePackageConfiguration              ConfigurationType           // Configuration to put into the update package (for use on an sdcard)
-	subcommand                              *flaggy.Subcommand          // flaggy's subcommand representing the 'service' subcommand
-	installSubcommand                       *flaggy.Subcommand          // flaggy's subcommand representing the 'service install' subcommand
-	uninstallSubcommand                     *flaggy.Subcommand          // flaggy's subcommand representing the 'service uninstall' subcommand
-	startSubcommand                         *flaggy.Subcommand          // flaggy's subcommand representing the 'service start' subcommand
-	stopSubcommand                          *flaggy.Subcommand          // flaggy's subcommand representing the 'service stop' subcommand
-	debugSubcommand                         *flaggy.Subcommand          // flaggy's subcommand representing the 'service debug' subcommand
+	serviceSettings                        // settings loaded from the service configuration file
+	serviceName         string             // name of the service
+	serviceConfig       mgr.Config         // configuration of the service
+	configPath          string             // path of the service configuration file
+	subcommand          *flaggy.Subcommand // flaggy's subcommand representing the 'service' subcommand
+	installSubcommand   *flaggy.Subcommand // flaggy's subcommand representing the 'service install' subcommand
+	uninstallSubcommand *flaggy.Subcommand // flaggy's subcommand representing the 'service uninstall' subcommand
+	startSubcommand     *flaggy.Subcommand // flaggy's subcommand representing the 'service start' subcommand
+	stopSubcommand      *flaggy.Subcommand // flaggy's subcommand representing the 'service stop' subcommand
+	debugSubcommand     *flaggy.Subcommand // flaggy's subcommand representing the 'service debug' subcommand
 }
 
-type ConfigurationType string
-
-const (
-	config_atv             ConfigurationType = "ATV"
-	config_unencrypted_ecs                   = "ECS (unencrypted)"
-	config_encrypted_ecs                     = "ECS (encrypted)"
-)
-
 // NewServiceCommand creates a new command handling the 'service' subcommand.
 func NewServiceCommand() *ServiceCommand {
 	exePath, _ := exePath()
@@ -167,34 +143,12 @@ func (cmd *ServiceCommand) runService(isDebug bool) error {
 		defer logAdapter.Close()
 	}
 
-	// load the service configuration file
-	err = cmd.loadServiceConfiguration(cmd.configPath, true)
+	// load the service configuration file and check the configured files
+	err = cmd.initService(true)
 	if err != nil {
 		return err
 	}
 
-	// create involved directories as configured in the service configuration
-	err = cmd.setupFilesystem()
-	if err != nil {
-		return err
-	}
-
-	// ensure that the specified base configuration file is valid
-	_, err = loadConfigurationFile(cmd.baseConfigurationPath)
-	if err != nil {
-		log.Errorf("Loading base configuration file failed: %v", err)
-		return err
-	}
-
-	// ensure that the specified merge configuration file is valid
-	if len(cmd.mergeConfigurationPath) > 0 {
-		_, err = atv.LoadMergeConfiguration(cmd.mergeConfigurationPath)
-		if err != nil {
-			log.Errorf("Loading merge configuration file failed: %v", err)
-			return err
-		}
-	}
-
 	// start the service
 	log.Infof("Starting %s service", cmd.serviceName)
 	run := svc.Run
@@ -4,11 +4,8 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows/svc"
 	"golang.org/x/sys/windows/svc/eventlog"
@@ -21,22 +18,14 @@ func (cmd *ServiceCommand) Execute(args []string, r <-chan svc.ChangeRequest, ch
 	// signal that the service is starting
 	changes <- svc.Status{State: svc.StartPending}
 
-	// initialize file system watcher for input directory (hot folder)
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		log.Errorf("%v", err)
-		changes <- svc.Status{State: svc.StopPending}
-		return
-	}
-	defer watcher.Close()
-
-	// start watching hot folder
-	err = watcher.Add(cmd.hotFolderPath)
+	// start watching the hot folder
+	monitor, err := newHotFolderMonitor(cmd)
 	if err != nil {
 		log.Errorf("%v", err)
 		changes <- svc.Status{State: svc.StopPending}
 		return
 	}
+	defer monitor.Close()
 
 	// signal that the service is running now
 	changes <- svc.Status{
@@ -44,43 +33,6 @@ func (cmd *ServiceCommand) Execute(args []string, r <-chan svc.ChangeRequest, ch
 		Accepts: svc.AcceptStop | svc.AcceptShutdown,
 	}
 
-	// populate list of files in hot folder to start with
-	filesInHotFolder := make(map[string]time.Time)
-	err = filepath.Walk(cmd.hotFolderPath, func(path string, info os.FileInfo, err error) error {
-		path, err = filepath.Abs(path)
-		if err == nil {
-			if cmd.mergedConfigurationsWriteEncryptedEcs {
-				// encrypted ECS containers should be generated
-				// => the files must bring along the serial number with the file name
-				serial, err := getSerialNumberFrommGuardConfigurationFileName(path)
-				if err == nil && serial != nil {
-					log.Debugf("Found possible configuration file: %s", path)
-					filesInHotFolder[path] = time.Now()
-				}
-			} else {
-				// encrypted ECS containers are not needed
-				// => any file name is ok
-				isConfFile, err := isPossiblemGuardConfigurationFile(path)
-				if err == nil && isConfFile {
-					log.Debugf("Found possible configuration file: %s", path)
-					filesInHotFolder[path] = time.Now()
-				}
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		log.Errorf("%v", err)
-		changes <- svc.Status{State: svc.StopPending}
-		return
-	}
-
-	// set up timer that triggers processing files in the hot folder
-	hotFolderDetectedFileCooldown := 5 * time.Second          // time after which a file is considered "stable"
-	hotFolderProcessingCycle := 1 * time.Second               // time after which the files in the hot folder are checked
-	hotFolderTimer := time.NewTimer(hotFolderProcessingCycle) // timer triggering processing files in the hot folder
-	hotFolderTimerRunning := true
-
 loop:
 	for {
 		select {
@@ -100,129 +52,20 @@ loop:
 			}
 
 		// handle file system watcher events
-		case event, ok := <-watcher.Events:
-
-			if !ok {
-				continue
-			}
-
-			log.Debugf("fs event: %v", event)
-
-			if (event.Op & fsnotify.Create) == fsnotify.Create {
-				log.Debugf("Created file: %s", event.Name)
-				path, err := filepath.Abs(event.Name)
-				if err == nil {
-					if cmd.mergedConfigurationsWriteEncryptedEcs {
-						// encrypted ECS containers should be generated
-						// => the files must bring along the serial number with the file name
-						serial, err := getSerialNumberFrommGuardConfigurationFileName(path)
-						if err == nil && serial != nil {
-							filesInHotFolder[path] = time.Now()
-							if !hotFolderTimerRunning {
-								hotFolderTimer.Reset(hotFolderProcessingCycle)
-								hotFolderTimerRunning = true
-							}
-						}
-					} else {
-						// encrypted ECS containers are not needed
-						// => any file name is ok
-						isConfFile, err := isPossiblemGuardConfigurationFile(path)
-						if err == nil && isConfFile {
-							filesInHotFolder[path] = time.Now()
-							if !hotFolderTimerRunning {
-								hotFolderTimer.Reset(hotFolderProcessingCycle)
-								hotFolderTimerRunning = true
-							}
-						}
-					}
-				}
-			}
-
-			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
-
-				log.Debugf("Removed/Renamed file: %s", event.Name)
-				path, err := filepath.Abs(event.Name)
-				if err == nil {
-					delete(filesInHotFolder, path)
-				}
-				if len(filesInHotFolder) == 0 {
-					if hotFolderTimerRunning {
-						if !hotFolderTimer.Stop() {
-							<-hotFolderTimer.C
-						}
-						hotFolderTimerRunning = false
-					}
-				}
-			}
-
-			if (event.Op & fsnotify.Write) == fsnotify.Write {
-				log.Debugf("Modified file: %s", event.Name)
-				path, err := filepath.Abs(event.Name)
-				if err == nil {
-					if cmd.mergedConfigurationsWriteEncryptedEcs {
-						// encrypted ECS containers should be generated
-						// => the files must bring along the serial number with the file name
-						serial, err := getSerialNumberFrommGuardConfigurationFileName(path)
-						if err == nil && serial != nil {
-							filesInHotFolder[path] = time.Now()
-							if !hotFolderTimerRunning {
-								hotFolderTimer.Reset(hotFolderProcessingCycle)
-								hotFolderTimerRunning = true
-							}
-						}
-					} else {
-						// encrypted ECS containers are not needed
-						// => any file name is ok
-						isConfFile, err := isPossiblemGuardConfigurationFile(path)
-						if err == nil && isConfFile {
-							filesInHotFolder[path] = time.Now()
-							if !hotFolderTimerRunning {
-								hotFolderTimer.Reset(hotFolderProcessingCycle)
-								hotFolderTimerRunning = true
-							}
-						}
-					}
-				}
+		case event, ok := <-monitor.Events():
+			if ok {
+				monitor.HandleEvent(event)
 			}
 
 		// handle file system watcher errors
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				continue
+		case err, ok := <-monitor.Errors():
+			if ok {
+				log.Errorf("watcher error: %v", err)
 			}
-			log.Errorf("watcher error: %v", err)
 
 		// process files in the hot folder that did not change within the specified time
-		case <-hotFolderTimer.C:
-			for file, lastwritten := range filesInHotFolder {
-				if time.Since(lastwritten) > hotFolderDetectedFileCooldown {
-					path, _ := filepath.Abs(file)
-					err = cmd.processFileInHotfolder(path)
-					if err == nil {
-						log.Infof("Processing file '%s' succeeded.", path)
-						err = os.Remove(path)
-						if err != nil {
-							log.Errorf("%v", err)
-						} else {
-							log.Debugf("Removing file '%s' succeeded.", path)
-						}
-					} else {
-						newPath := path + ".err"
-						log.Errorf("Processing file '%s' failed, renaming it to '%s'.", path, newPath)
-						err = os.Rename(path, newPath)
-						if err != nil {
-							log.Errorf("Renaming file '%s' to '%s' failed: %v", path, newPath, err)
-						}
-					}
-					delete(filesInHotFolder, path)
-				}
-			}
-			if len(filesInHotFolder) > 0 {
-				hotFolderTimer.Reset(hotFolderProcessingCycle)
-				hotFolderTimerRunning = true
-			} else {
-				hotFolderTimerRunning = false
-			}
+		case <-monitor.Timer():
+			monitor.ProcessFiles()
 		}
 	}
 
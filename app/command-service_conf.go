@@ -0,0 +1,648 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/certmgr"
+	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
+	"github.com/griffinplus/mguard-config-tool/shadow"
+	"github.com/griffinplus/mguard-config-tool/snmpd"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// serviceSettings contains the settings of the service that are loaded from the service configuration file.
+type serviceSettings struct {
+	cacheDirectory                          string                      // path of the directory where the service caches files
+	certificateManager                      *certmgr.CertificateManager // certificate manager that takes care of caching and downloading device certificates
+	sdcardTemplateDirectory                 string                      // path of the directory containing the basic structure of an sdcard (incl. firmware files)
+	baseConfigurationPath                   string                      // path of the mguard configuration file to use as base configuration
+	mergeConfigurationPath                  string                      // path of the merge configuration file that defines which settings to merge into the base configuration
+	mergeRemapRowIDs                        bool                        // true to rename row ids of dropped configurations that are used in the base configuration as well
+	hotFolderPath                           string                      // path of the directory to watch for atv/ecs files with configurations to merge with the base configuration
+	passwordsRoot                           string                      // password of user 'root'
+	passwordsAdmin                          string                      // password of user 'admin'
+	passwordHashRoot                        string                      // hashed password of user 'root' (instead of the password)
+	passwordHashAdmin                       string                      // hashed password of user 'admin' (instead of the password)
+	passwordsAlgorithm                      shadow.Algorithm            // algorithm to hash passwords with
+	passwordsRounds                         int                         // number of rounds to hash passwords with (0 => default)
+	snmpUsername                            string                      // name of the SNMPv3 user to set the credentials of
+	snmpAuthProtocol                        snmpd.AuthProtocol          // authentication protocol of the SNMPv3 user
+	snmpAuthPassphrase                      string                      // authentication passphrase of the SNMPv3 user (empty => do not touch the user)
+	snmpPrivProtocol                        snmpd.PrivProtocol          // privacy protocol of the SNMPv3 user
+	snmpPrivPassphrase                      string                      // privacy passphrase of the SNMPv3 user (empty => authentication passphrase)
+	mergedConfigurationDirectory            string                      // path of the directory where to store merged mguard configurations
+	mergedConfigurationsWriteAtv            bool                        // true to write an ATV file with the merged configuration, otherwise false
+	mergedConfigurationsWriteUnencryptedEcs bool                        // true to write an unencrypted ECS file with the merged configuration, otherwise false
+	mergedConfigurationsWriteEncryptedEcs   bool                        // true to write an encrypted ECS file with the merged configuration, otherwise false
+	updatePackageDirectory                  string                      // path of the directory where to store update packages (for use on an sdcard)
+	updatePackageConfiguration              ConfigurationType           // Configuration to put into the update package (for use on an sdcard)
+	deterministicOutput                     bool                        // true to write ECS containers and update packages deterministically
+	deterministicTimestamp                  *time.Time                  // timestamp to use when writing deterministically (nil to use SOURCE_DATE_EPOCH or the default timestamp)
+	opensslEnabled                          bool                        // true to encrypt ECS containers using openssl, false to use the built-in implementation
+	opensslBinaryPath                       string                      // absolute path of the openssl executable (if opensslEnabled is true)
+}
+
+type ConfigurationType string
+
+const (
+	config_atv             ConfigurationType = "ATV"
+	config_unencrypted_ecs                   = "ECS (unencrypted)"
+	config_encrypted_ecs                     = "ECS (encrypted)"
+)
+
+type setting struct {
+	path         string
+	defaultValue interface{}
+}
+
+var settingCachePath = setting{
+	"cache.path",
+	"./data/cache",
+}
+
+var settingInputSdCardTemplatePath = setting{
+	"input.sdcard_template.path",
+	"./data/sdcard-template",
+}
+
+var settingInputBaseConfigurationPath = setting{
+	"input.base_configuration.path",
+	"./data/configs/default.atv",
+}
+
+var settingInputMergeConfigurationPath = setting{
+	"input.merge_configuration.path",
+	"",
+}
+
+var settingInputMergeConfigurationRemapRowIDs = setting{
+	"input.merge_configuration.remap_rids",
+	false,
+}
+
+var settingInputHotfolderPath = setting{
+	"input.hotfolder.path",
+	"./data/input",
+}
+
+var settingInputPasswordsRoot = setting{
+	"input.passwords.root",
+	"",
+}
+
+var settingInputPasswordsAdmin = setting{
+	"input.passwords.admin",
+	"",
+}
+
+var settingInputPasswordsRootHash = setting{
+	"input.passwords.root_hash",
+	"",
+}
+
+var settingInputPasswordsAdminHash = setting{
+	"input.passwords.admin_hash",
+	"",
+}
+
+var settingInputPasswordsAlgorithm = setting{
+	"input.passwords.algorithm",
+	"sha512",
+}
+
+var settingInputPasswordsRounds = setting{
+	"input.passwords.rounds",
+	0,
+}
+
+var settingInputSnmpUsername = setting{
+	"input.snmp.username",
+	"admin",
+}
+
+var settingInputSnmpAuthProtocol = setting{
+	"input.snmp.auth_protocol",
+	"SHA",
+}
+
+var settingInputSnmpAuthPassphrase = setting{
+	"input.snmp.auth_passphrase",
+	"",
+}
+
+var settingInputSnmpPrivProtocol = setting{
+	"input.snmp.priv_protocol",
+	"AES",
+}
+
+var settingInputSnmpPrivPassphrase = setting{
+	"input.snmp.priv_passphrase",
+	"",
+}
+
+var settingOutputMergedConfigurationsPath = setting{
+	"output.merged_configurations.path",
+	"./data/output-merged-configs",
+}
+
+var settingOutputMergedConfigurationsWriteAtv = setting{
+	"output.merged_configurations.write_atv",
+	true,
+}
+
+var settingOutputMergedConfigurationsWriteUnencryptedEcs = setting{
+	"output.merged_configurations.write_unencrypted_ecs",
+	true,
+}
+
+var settingOutputMergedConfigurationsWriteEncryptedEcs = setting{
+	"output.merged_configurations.write_encrypted_ecs",
+	false,
+}
+
+var settingOutputUpdatePackagesPath = setting{
+	"output.update_packages.path",
+	"./data/output-update-packages",
+}
+
+var settingOutputUpdatePackagesConfiguration = setting{
+	"output.update_packages.configuration",
+	"encrypted_ecs",
+}
+
+var settingOutputDeterministicEnabled = setting{
+	"output.deterministic.enabled",
+	false,
+}
+
+var settingOutputDeterministicTimestamp = setting{
+	"output.deterministic.timestamp",
+	"",
+}
+
+var settingOpenSslEnabled = setting{
+	"tools.openssl.enabled",
+	false,
+}
+
+var settingOpenSslBinaryPath = setting{
+	"tools.openssl.path",
+	"",
+}
+
+var allSettings = []setting{
+	settingCachePath,
+	settingInputSdCardTemplatePath,
+	settingInputBaseConfigurationPath,
+	settingInputMergeConfigurationPath,
+	settingInputMergeConfigurationRemapRowIDs,
+	settingInputHotfolderPath,
+	settingInputPasswordsRoot,
+	settingInputPasswordsAdmin,
+	settingInputPasswordsRootHash,
+	settingInputPasswordsAdminHash,
+	settingInputPasswordsAlgorithm,
+	settingInputPasswordsRounds,
+	settingInputSnmpUsername,
+	settingInputSnmpAuthProtocol,
+	settingInputSnmpAuthPassphrase,
+	settingInputSnmpPrivProtocol,
+	settingInputSnmpPrivPassphrase,
+	settingOutputMergedConfigurationsPath,
+	settingOutputMergedConfigurationsWriteAtv,
+	settingOutputMergedConfigurationsWriteUnencryptedEcs,
+	settingOutputMergedConfigurationsWriteEncryptedEcs,
+	settingOutputUpdatePackagesPath,
+	settingOutputUpdatePackagesConfiguration,
+	settingOutputDeterministicEnabled,
+	settingOutputDeterministicTimestamp,
+	settingOpenSslEnabled,
+	settingOpenSslBinaryPath,
+}
+
+// loadServiceConfiguration loads the service configuration from the specified file.
+// The settings of the service are only replaced, if the configuration was loaded successfully.
+func (cmd *ServiceCommand) loadServiceConfiguration(path string, createIfNotExist bool) error {
+
+	settings := serviceSettings{}
+
+	// set up new viper configuration with default settings
+	conf := viper.New()
+	for _, setting := range allSettings {
+		conf.SetDefault(setting.path, setting.defaultValue)
+	}
+
+	// read configuration from file
+	log.Debugf("Loading configuration file '%s'...", path)
+	basename := filepath.Base(path)
+	configName := strings.TrimSuffix(basename, filepath.Ext(basename))
+	configDir := filepath.Dir(path) + string(os.PathSeparator)
+	conf.SetConfigName(configName)
+	conf.SetConfigType("yaml")
+	conf.AddConfigPath(configDir)
+	err := conf.ReadInConfig()
+	if err != nil {
+		switch err := err.(type) {
+		case viper.ConfigFileNotFoundError:
+			log.Debugf("Configuration file '%s' does not exist.", path)
+			if createIfNotExist {
+				log.Debugf("Creating new configuration file '%s'...", path)
+				err := conf.WriteConfigAs(path)
+				if err != nil {
+					log.Errorf("Saving configuration file '%s' failed: %v", path, err)
+					// proceed with default settings...
+				}
+			}
+		default:
+			// some severe error, cannot proceed...
+			return err
+		}
+	}
+
+	// configuration is available now
+	// => validate settings
+
+	// cache: base path (must be a directory)
+	log.Debugf("Setting '%s': '%s'", settingCachePath.path, conf.GetString(settingCachePath.path))
+	settings.cacheDirectory = conf.GetString(settingCachePath.path)
+	if len(settings.cacheDirectory) > 0 {
+		if filepath.IsAbs(settings.cacheDirectory) {
+			settings.cacheDirectory = filepath.Clean(settings.cacheDirectory)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.cacheDirectory))
+			if err != nil {
+				return err
+			}
+			settings.cacheDirectory = path
+		}
+	} else {
+		return fmt.Errorf("setting '%s' is not set.", settingCachePath.path)
+	}
+
+	// input: sdcard template path (must be a directory)
+	log.Debugf("Setting '%s': '%s'", settingInputSdCardTemplatePath.path, conf.GetString(settingInputSdCardTemplatePath.path))
+	settings.sdcardTemplateDirectory = conf.GetString(settingInputSdCardTemplatePath.path)
+	if len(settings.sdcardTemplateDirectory) > 0 {
+		if filepath.IsAbs(settings.sdcardTemplateDirectory) {
+			settings.sdcardTemplateDirectory = filepath.Clean(settings.sdcardTemplateDirectory)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.sdcardTemplateDirectory))
+			if err != nil {
+				return err
+			}
+			settings.sdcardTemplateDirectory = path
+		}
+	} else {
+		return fmt.Errorf("setting '%s' is not set.", settingInputSdCardTemplatePath.path)
+	}
+
+	// input: base configuration file
+	log.Debugf("Setting '%s': '%s'", settingInputBaseConfigurationPath.path, conf.GetString(settingInputBaseConfigurationPath.path))
+	settings.baseConfigurationPath = conf.GetString(settingInputBaseConfigurationPath.path)
+	if len(settings.baseConfigurationPath) > 0 {
+		if filepath.IsAbs(settings.baseConfigurationPath) {
+			settings.baseConfigurationPath = filepath.Clean(settings.baseConfigurationPath)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.baseConfigurationPath))
+			if err != nil {
+				return err
+			}
+			settings.baseConfigurationPath = path
+		}
+	} else {
+		return fmt.Errorf("setting '%s' is not set.", settingInputBaseConfigurationPath.path)
+	}
+
+	// input: merge configuration file
+	log.Debugf("Setting '%s': '%s'", settingInputMergeConfigurationPath.path, conf.GetString(settingInputMergeConfigurationPath.path))
+	settings.mergeConfigurationPath = conf.GetString(settingInputMergeConfigurationPath.path)
+	if len(settings.mergeConfigurationPath) > 0 { // setting is optional
+		if filepath.IsAbs(settings.mergeConfigurationPath) {
+			settings.mergeConfigurationPath = filepath.Clean(settings.mergeConfigurationPath)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.mergeConfigurationPath))
+			if err != nil {
+				return err
+			}
+			settings.mergeConfigurationPath = path
+		}
+	}
+
+	// input: rename colliding row ids
+	log.Debugf("Setting '%s': '%s'", settingInputMergeConfigurationRemapRowIDs.path, conf.GetString(settingInputMergeConfigurationRemapRowIDs.path))
+	settings.mergeRemapRowIDs = conf.GetBool(settingInputMergeConfigurationRemapRowIDs.path)
+
+	// input: hot folder path
+	log.Debugf("Setting '%s': '%s'", settingInputHotfolderPath.path, conf.GetString(settingInputHotfolderPath.path))
+	settings.hotFolderPath = conf.GetString(settingInputHotfolderPath.path)
+	if len(settings.hotFolderPath) > 0 {
+		if filepath.IsAbs(settings.hotFolderPath) {
+			settings.hotFolderPath = filepath.Clean(settings.hotFolderPath)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.hotFolderPath))
+			if err != nil {
+				return err
+			}
+			settings.hotFolderPath = path
+		}
+	} else {
+		return fmt.Errorf("setting '%s' is not set", settingInputHotfolderPath.path)
+	}
+
+	// input: password for user 'root'
+	// (passwords are not logged)
+	settings.passwordsRoot = conf.GetString(settingInputPasswordsRoot.path)
+
+	// input: password for user 'admin'
+	settings.passwordsAdmin = conf.GetString(settingInputPasswordsAdmin.path)
+
+	// input: hashed password for user 'root' and 'admin'
+	// (instead of the password, hashes are not logged either)
+	passwordHashSettings := []struct {
+		setting  setting
+		password string
+		hash     *string
+	}{
+		{settingInputPasswordsRootHash, settings.passwordsRoot, &settings.passwordHashRoot},
+		{settingInputPasswordsAdminHash, settings.passwordsAdmin, &settings.passwordHashAdmin},
+	}
+	for _, item := range passwordHashSettings {
+		*item.hash = conf.GetString(item.setting.path)
+		if len(*item.hash) > 0 {
+			if len(item.password) > 0 {
+				return fmt.Errorf("setting '%s' cannot be used along with the password", item.setting.path)
+			}
+			err := shadow.ValidatePasswordHash(*item.hash)
+			if err != nil {
+				return fmt.Errorf("setting '%s' is invalid: %v", item.setting.path, err)
+			}
+		}
+	}
+
+	// input: algorithm to hash passwords with
+	log.Debugf("Setting '%s': '%s'", settingInputPasswordsAlgorithm.path, conf.GetString(settingInputPasswordsAlgorithm.path))
+	settings.passwordsAlgorithm, err = shadow.ParseAlgorithm(conf.GetString(settingInputPasswordsAlgorithm.path))
+	if err != nil {
+		return fmt.Errorf("setting '%s' is invalid: %v", settingInputPasswordsAlgorithm.path, err)
+	}
+
+	// input: number of rounds to hash passwords with
+	// (0 => default)
+	log.Debugf("Setting '%s': '%s'", settingInputPasswordsRounds.path, conf.GetString(settingInputPasswordsRounds.path))
+	settings.passwordsRounds = conf.GetInt(settingInputPasswordsRounds.path)
+	if settings.passwordsRounds != 0 && (settings.passwordsRounds < shadow.MinRounds || settings.passwordsRounds > shadow.MaxRounds) {
+		return fmt.Errorf("setting '%s' is invalid (please specify 0 or a number in the range [%d,%d])", settingInputPasswordsRounds.path, shadow.MinRounds, shadow.MaxRounds)
+	}
+
+	// input: credentials of a SNMPv3 user
+	// (the passphrases are not logged, the protocols and passphrases are checked only, if the user is configured)
+	log.Debugf("Setting '%s': '%s'", settingInputSnmpUsername.path, conf.GetString(settingInputSnmpUsername.path))
+	log.Debugf("Setting '%s': '%s'", settingInputSnmpAuthProtocol.path, conf.GetString(settingInputSnmpAuthProtocol.path))
+	log.Debugf("Setting '%s': '%s'", settingInputSnmpPrivProtocol.path, conf.GetString(settingInputSnmpPrivProtocol.path))
+	settings.snmpUsername = conf.GetString(settingInputSnmpUsername.path)
+	settings.snmpAuthPassphrase = conf.GetString(settingInputSnmpAuthPassphrase.path)
+	settings.snmpPrivPassphrase = conf.GetString(settingInputSnmpPrivPassphrase.path)
+	if len(settings.snmpUsername) > 0 && len(settings.snmpAuthPassphrase) > 0 {
+		settings.snmpAuthProtocol, err = snmpd.ParseAuthProtocol(conf.GetString(settingInputSnmpAuthProtocol.path))
+		if err != nil {
+			return fmt.Errorf("setting '%s' is invalid: %v", settingInputSnmpAuthProtocol.path, err)
+		}
+		settings.snmpPrivProtocol, err = snmpd.ParsePrivProtocol(conf.GetString(settingInputSnmpPrivProtocol.path))
+		if err != nil {
+			return fmt.Errorf("setting '%s' is invalid: %v", settingInputSnmpPrivProtocol.path, err)
+		}
+		// check username and passphrases by adding the user to an empty file
+		err = snmpd.NewFile().AddUser(
+			settings.snmpUsername,
+			settings.snmpAuthProtocol, settings.snmpAuthPassphrase,
+			settings.snmpPrivProtocol, settings.snmpPrivPassphrase)
+		if err != nil {
+			return fmt.Errorf("settings 'input.snmp.*' are invalid: %v", err)
+		}
+	}
+
+	// output: merged configuration directory
+	log.Debugf("Setting '%s': '%s'", settingOutputMergedConfigurationsPath.path, conf.GetString(settingOutputMergedConfigurationsPath.path))
+	settings.mergedConfigurationDirectory = conf.GetString(settingOutputMergedConfigurationsPath.path)
+	if len(settings.mergedConfigurationDirectory) > 0 { // setting is optional
+		if filepath.IsAbs(settings.mergedConfigurationDirectory) {
+			settings.mergedConfigurationDirectory = filepath.Clean(settings.mergedConfigurationDirectory)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.mergedConfigurationDirectory))
+			if err != nil {
+				return err
+			}
+			settings.mergedConfigurationDirectory = path
+		}
+	}
+
+	// output: merged configuration directory - write atv
+	// Valid: true, false
+	log.Debugf("Setting '%s': '%s'", settingOutputMergedConfigurationsWriteAtv.path, conf.GetString(settingOutputMergedConfigurationsWriteAtv.path))
+	settings.mergedConfigurationsWriteAtv = conf.GetBool(settingOutputMergedConfigurationsWriteAtv.path)
+
+	// output: merged configuration directory - write unencrypted ecs
+	// Valid: true, false
+	log.Debugf("Setting '%s': '%s'", settingOutputMergedConfigurationsWriteUnencryptedEcs.path, conf.GetString(settingOutputMergedConfigurationsWriteUnencryptedEcs.path))
+	settings.mergedConfigurationsWriteUnencryptedEcs = conf.GetBool(settingOutputMergedConfigurationsWriteUnencryptedEcs.path)
+
+	// output: merged configuration directory - write encrypted ecs
+	// Valid: true, false
+	log.Debugf("Setting '%s': '%s'", settingOutputMergedConfigurationsWriteEncryptedEcs.path, conf.GetString(settingOutputMergedConfigurationsWriteEncryptedEcs.path))
+	settings.mergedConfigurationsWriteEncryptedEcs = conf.GetBool(settingOutputMergedConfigurationsWriteEncryptedEcs.path)
+
+	// output: update package directory
+	log.Debugf("Setting '%s': '%s'", settingOutputUpdatePackagesPath.path, conf.GetString(settingOutputUpdatePackagesPath.path))
+	settings.updatePackageDirectory = conf.GetString(settingOutputUpdatePackagesPath.path)
+	if len(settings.updatePackageDirectory) > 0 { // setting is optional
+		if filepath.IsAbs(settings.updatePackageDirectory) {
+			settings.updatePackageDirectory = filepath.Clean(settings.updatePackageDirectory)
+		} else {
+			path, err := filepath.Abs(filepath.Join(configDir, settings.updatePackageDirectory))
+			if err != nil {
+				return err
+			}
+			settings.updatePackageDirectory = path
+		}
+	}
+
+	// output: update package configuration
+	log.Debugf("Setting '%s': '%s'", settingOutputUpdatePackagesConfiguration.path, conf.GetString(settingOutputUpdatePackagesConfiguration.path))
+	updatePackageConfiguration := conf.GetString(settingOutputUpdatePackagesConfiguration.path)
+	switch updatePackageConfiguration {
+	case "atv":
+		settings.updatePackageConfiguration = config_atv
+	case "unencrypted_ecs":
+		settings.updatePackageConfiguration = config_unencrypted_ecs
+	case "encrypted_ecs":
+		settings.updatePackageConfiguration = config_encrypted_ecs
+	default:
+		return fmt.Errorf("setting '%s' is invalid (please choose one of the following: 'atv', 'unencrypted_ecs', 'encrypted_ecs')", settingOutputUpdatePackagesConfiguration.path)
+	}
+
+	// update packages with ATV files set passwords on the device, this needs the password itself
+	if len(settings.updatePackageDirectory) > 0 && settings.updatePackageConfiguration == config_atv {
+		if len(settings.passwordHashRoot) > 0 || len(settings.passwordHashAdmin) > 0 {
+			return fmt.Errorf("Update packages with ATV files cannot set hashed passwords, please configure the passwords or choose an ECS container (setting '%s')", settingOutputUpdatePackagesConfiguration.path)
+		}
+	}
+
+	// output: deterministic output
+	// Valid: true, false
+	log.Debugf("Setting '%s': '%s'", settingOutputDeterministicEnabled.path, conf.GetString(settingOutputDeterministicEnabled.path))
+	settings.deterministicOutput = conf.GetBool(settingOutputDeterministicEnabled.path)
+
+	// output: timestamp to use when writing deterministically
+	// (optional, RFC 3339, e.g. 2020-01-01T00:00:00Z)
+	log.Debugf("Setting '%s': '%s'", settingOutputDeterministicTimestamp.path, conf.GetString(settingOutputDeterministicTimestamp.path))
+	deterministicTimestamp := conf.GetString(settingOutputDeterministicTimestamp.path)
+	if len(deterministicTimestamp) > 0 {
+		timestamp, err := time.Parse(time.RFC3339, deterministicTimestamp)
+		if err != nil {
+			return fmt.Errorf("setting '%s' is invalid (please specify a timestamp like '2020-01-01T00:00:00Z')", settingOutputDeterministicTimestamp.path)
+		}
+		settings.deterministicTimestamp = &timestamp
+	}
+
+	// tools: openssl enabled
+	// Valid: true, false
+	log.Debugf("Setting '%s': '%s'", settingOpenSslEnabled.path, conf.GetString(settingOpenSslEnabled.path))
+	settings.opensslEnabled = conf.GetBool(settingOpenSslEnabled.path)
+
+	// tools: openssl binary path
+	// (only needed, if encrypting ECS containers using openssl is enabled)
+	// (the path is passed to the ecs module after the entire configuration is validated)
+	if settings.opensslEnabled {
+
+		log.Debugf("Setting '%s': '%s'", settingOpenSslBinaryPath.path, conf.GetString(settingOpenSslBinaryPath.path))
+		opensslBinaryPath := conf.GetString(settingOpenSslBinaryPath.path)
+		if len(opensslBinaryPath) > 0 {
+
+			// the openssl binary path was specified explicitly
+			// => check whether the executable exists
+
+			if filepath.IsAbs(opensslBinaryPath) {
+				opensslBinaryPath = filepath.Clean(opensslBinaryPath)
+			} else {
+				path, err := filepath.Abs(filepath.Join(configDir, opensslBinaryPath))
+				if err != nil {
+					return err
+				}
+				opensslBinaryPath = path
+			}
+
+			info, err := os.Stat(opensslBinaryPath)
+			if err != nil || info.IsDir() {
+				return fmt.Errorf("setting '%s' is invalid (the openssl executable was not found at the specified location: %s)", settingOpenSslBinaryPath.path, opensslBinaryPath)
+			}
+
+		} else {
+
+			// search the openssl executable using the PATH variable
+			opensslBinaryPath, err = ecs.FindOpensslExecutablePath()
+			if err != nil {
+				return fmt.Errorf("Encrypting ECS containers using OpenSSL is enabled, but the OpenSSL executable was not found: %v", err)
+			}
+		}
+
+		settings.opensslBinaryPath = opensslBinaryPath
+	}
+
+	// log configuration
+	logtext := strings.Builder{}
+	logtext.WriteString(fmt.Sprintf("--- Configuration ---\n"))
+	logtext.WriteString(fmt.Sprintf("Cache Directory:                  %s\n", settings.cacheDirectory))
+	logtext.WriteString(fmt.Sprintf("SD Card Template Directory:       %s\n", settings.sdcardTemplateDirectory))
+	logtext.WriteString(fmt.Sprintf("Base Configuration File:          %s\n", settings.baseConfigurationPath))
+	logtext.WriteString(fmt.Sprintf("Merge Configuration File:         %s\n", settings.mergeConfigurationPath))
+	logtext.WriteString(fmt.Sprintf("  - Remap Row IDs:                %v\n", settings.mergeRemapRowIDs))
+	logtext.WriteString(fmt.Sprintf("Hot folder:                       %s\n", settings.hotFolderPath))
+	logtext.WriteString(fmt.Sprintf("Passwords:\n"))
+	logtext.WriteString(fmt.Sprintf("  - root:                         %s\n", describePassword(settings.passwordsRoot, settings.passwordHashRoot)))
+	logtext.WriteString(fmt.Sprintf("  - admin:                        %s\n", describePassword(settings.passwordsAdmin, settings.passwordHashAdmin)))
+	if settings.passwordsRounds != 0 {
+		logtext.WriteString(fmt.Sprintf("  - Hashing:                      %s (rounds: %d)\n", settings.passwordsAlgorithm, settings.passwordsRounds))
+	} else {
+		logtext.WriteString(fmt.Sprintf("  - Hashing:                      %s (rounds: %d)\n", settings.passwordsAlgorithm, shadow.DefaultRounds))
+	}
+	if len(settings.snmpUsername) > 0 && len(settings.snmpAuthPassphrase) > 0 {
+		privacy := "none"
+		if settings.snmpPrivProtocol != snmpd.NoPriv {
+			privacy = string(settings.snmpPrivProtocol)
+		}
+		logtext.WriteString(fmt.Sprintf("SNMPv3 User:                      %s (authentication: %s, privacy: %s)\n", settings.snmpUsername, settings.snmpAuthProtocol, privacy))
+	} else {
+		logtext.WriteString(fmt.Sprintf("SNMPv3 User:                      <not configured>\n"))
+	}
+	logtext.WriteString(fmt.Sprintf("Merged Configuration Directory:   %s\n", settings.mergedConfigurationDirectory))
+	logtext.WriteString(fmt.Sprintf("  - Write ATV:                    %v\n", settings.mergedConfigurationsWriteAtv))
+	logtext.WriteString(fmt.Sprintf("  - Write ECS (unencrypted):      %v\n", settings.mergedConfigurationsWriteUnencryptedEcs))
+	logtext.WriteString(fmt.Sprintf("  - Write ECS (encrypted):        %v\n", settings.mergedConfigurationsWriteEncryptedEcs))
+	logtext.WriteString(fmt.Sprintf("Update Package Directory:         %s\n", settings.updatePackageDirectory))
+	logtext.WriteString(fmt.Sprintf("  - Configuration:                %s\n", settings.updatePackageConfiguration))
+	logtext.WriteString(fmt.Sprintf("Deterministic Output:             %v\n", settings.deterministicOutput))
+	if settings.deterministicTimestamp != nil {
+		logtext.WriteString(fmt.Sprintf("  - Timestamp:                    %s\n", settings.deterministicTimestamp.UTC().Format(time.RFC3339)))
+	} else {
+		logtext.WriteString(fmt.Sprintf("  - Timestamp:                    <SOURCE_DATE_EPOCH or %s>\n", ecs.DefaultDeterministicTimestamp.Format(time.RFC3339)))
+	}
+	logtext.WriteString(fmt.Sprintf("External Tools:\n"))
+	if settings.opensslEnabled {
+		logtext.WriteString(fmt.Sprintf("  - OpenSSL:                      %s\n", settings.opensslBinaryPath))
+	} else {
+		logtext.WriteString(fmt.Sprintf("  - OpenSSL:                      <disabled, using built-in encryption>\n"))
+	}
+	logtext.WriteString(fmt.Sprintf("--- Configuration End ---"))
+	log.Info(logtext.String())
+
+	// initialize the certificate manager
+	// (load credentials from mguard-device-database.yaml)
+	certificateCacheDirectory := filepath.Join(settings.cacheDirectory, "certificates")
+	settings.certificateManager, err = certmgr.NewCertificateManager(certificateCacheDirectory, "", "")
+	if err != nil {
+		return fmt.Errorf("Initializing the certificate manager failed: %v", err)
+	}
+
+	// the configuration is valid
+	// => replace the settings of the service
+	// (settings affecting the ecs package are applied by applyGlobalSettings() when the service is set up completely)
+	cmd.serviceSettings = settings
+	return nil
+}
+
+// applyGlobalSettings applies the settings of the service that affect the ecs package as a whole (encryption using
+// openssl and deterministic output). Nothing is changed, if the openssl executable cannot be found.
+func (cmd *ServiceCommand) applyGlobalSettings() error {
+
+	// apply the openssl executable path first (the only step that can fail, the executable may have vanished)
+	if cmd.opensslEnabled {
+		err := ecs.SetOpensslExecutablePath(cmd.opensslBinaryPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	ecs.EnableOpensslEncryption(cmd.opensslEnabled)
+	ecs.EnableDeterministicOutput(cmd.deterministicOutput)
+	ecs.SetDeterministicTimestamp(cmd.deterministicTimestamp)
+	return nil
+}
+
+// describePassword returns a description of a configured password that can be logged (without the password itself).
+func describePassword(password string, hash string) string {
+
+	if len(password) > 0 {
+		return "<plaintext>"
+	}
+
+	if len(hash) > 0 {
+		return "<hash>"
+	}
+
+	return "<not set>"
+}
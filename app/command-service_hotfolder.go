@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// time after which a file in the hot folder is considered "stable"
+const hotFolderDetectedFileCooldown = 5 * time.Second
+
+// time after which the files in the hot folder are checked
+const hotFolderProcessingCycle = 1 * time.Second
+
+// hotFolderMonitor watches the hot folder of the service and keeps track of configuration files that arrive there.
+type hotFolderMonitor struct {
+	cmd          *ServiceCommand      // the service command the monitor works for
+	watcher      *fsnotify.Watcher    // file system watcher monitoring the hot folder
+	files        map[string]time.Time // files in the hot folder (value: time of the last modification)
+	timer        *time.Timer          // timer triggering processing files in the hot folder
+	timerRunning bool                 // true, if the timer is running, otherwise false
+}
+
+// newHotFolderMonitor creates a new monitor that watches the hot folder configured in the service settings.
+// The monitor picks up files that are already in the hot folder as well.
+func newHotFolderMonitor(cmd *ServiceCommand) (*hotFolderMonitor, error) {
+
+	// initialize file system watcher for input directory (hot folder)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// start watching hot folder
+	err = watcher.Add(cmd.hotFolderPath)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	monitor := &hotFolderMonitor{
+		cmd:          cmd,
+		watcher:      watcher,
+		files:        make(map[string]time.Time),
+		timer:        time.NewTimer(hotFolderProcessingCycle),
+		timerRunning: true,
+	}
+
+	// populate list of files in hot folder to start with
+	err = filepath.Walk(cmd.hotFolderPath, func(path string, info os.FileInfo, err error) error {
+		path, err = filepath.Abs(path)
+		if err == nil && monitor.isCandidate(path) {
+			log.Debugf("Found possible configuration file: %s", path)
+			monitor.files[path] = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		monitor.Close()
+		return nil, err
+	}
+
+	return monitor, nil
+}
+
+// Close stops watching the hot folder.
+func (monitor *hotFolderMonitor) Close() {
+	if monitor != nil {
+		monitor.timer.Stop()
+		monitor.watcher.Close()
+	}
+}
+
+// Events returns the channel delivering file system events.
+func (monitor *hotFolderMonitor) Events() <-chan fsnotify.Event {
+	return monitor.watcher.Events
+}
+
+// Errors returns the channel delivering file system watcher errors.
+func (monitor *hotFolderMonitor) Errors() <-chan error {
+	return monitor.watcher.Errors
+}
+
+// Timer returns the channel signalling that files in the hot folder should be checked.
+func (monitor *hotFolderMonitor) Timer() <-chan time.Time {
+	return monitor.timer.C
+}
+
+// isCandidate checks whether the file at the specified path should be processed by the service.
+func (monitor *hotFolderMonitor) isCandidate(path string) bool {
+
+	if monitor.cmd.mergedConfigurationsWriteEncryptedEcs {
+		// encrypted ECS containers should be generated
+		// => the files must bring along the serial number with the file name
+		serial, err := getSerialNumberFrommGuardConfigurationFileName(path)
+		return err == nil && serial != nil
+	}
+
+	// encrypted ECS containers are not needed
+	// => any file name is ok
+	isConfFile, err := isPossiblemGuardConfigurationFile(path)
+	return err == nil && isConfFile
+}
+
+// HandleEvent updates the list of files in the hot folder according to the specified file system event.
+func (monitor *hotFolderMonitor) HandleEvent(event fsnotify.Event) {
+
+	log.Debugf("fs event: %v", event)
+
+	if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+		log.Debugf("Created/Modified file: %s", event.Name)
+		path, err := filepath.Abs(event.Name)
+		if err == nil && monitor.isCandidate(path) {
+			monitor.files[path] = time.Now()
+			if !monitor.timerRunning {
+				monitor.timer.Reset(hotFolderProcessingCycle)
+				monitor.timerRunning = true
+			}
+		}
+	}
+
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		log.Debugf("Removed/Renamed file: %s", event.Name)
+		path, err := filepath.Abs(event.Name)
+		if err == nil {
+			delete(monitor.files, path)
+		}
+		if len(monitor.files) == 0 {
+			if monitor.timerRunning {
+				if !monitor.timer.Stop() {
+					<-monitor.timer.C
+				}
+				monitor.timerRunning = false
+			}
+		}
+	}
+}
+
+// ProcessFiles processes files in the hot folder that did not change within the cooldown time.
+// It must be called when the timer has elapsed.
+func (monitor *hotFolderMonitor) ProcessFiles() {
+
+	for file, lastwritten := range monitor.files {
+		if time.Since(lastwritten) > hotFolderDetectedFileCooldown {
+			path, _ := filepath.Abs(file)
+			err := monitor.cmd.processFileInHotfolder(path)
+			if err == nil {
+				log.Infof("Processing file '%s' succeeded.", path)
+				err = os.Remove(path)
+				if err != nil {
+					log.Errorf("%v", err)
+				} else {
+					log.Debugf("Removing file '%s' succeeded.", path)
+				}
+			} else {
+				newPath := path + ".err"
+				log.Errorf("Processing file '%s' failed, renaming it to '%s'.", path, newPath)
+				err = os.Rename(path, newPath)
+				if err != nil {
+					log.Errorf("Renaming file '%s' to '%s' failed: %v", path, newPath, err)
+				}
+			}
+			delete(monitor.files, path)
+		}
+	}
+
+	if len(monitor.files) > 0 {
+		monitor.timer.Reset(hotFolderProcessingCycle)
+		monitor.timerRunning = true
+	} else {
+		monitor.timerRunning = false
+	}
+}
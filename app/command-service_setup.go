@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+	log "github.com/sirupsen/logrus"
+)
+
+// setupFilesystem creates the configured directories, if necessary and checks for the existence of configured files.
+func (cmd *ServiceCommand) setupFilesystem() error {
+
+	// create all configured directories recursively, if necessary
+	dirs := []string{
+		cmd.cacheDirectory,
+		cmd.sdcardTemplateDirectory,
+		cmd.hotFolderPath,
+		cmd.mergedConfigurationDirectory,
+		cmd.updatePackageDirectory,
+	}
+
+	if len(cmd.baseConfigurationPath) > 0 {
+		dirs = append(dirs, filepath.Dir(cmd.baseConfigurationPath))
+	}
+
+	if len(cmd.mergeConfigurationPath) > 0 {
+		dirs = append(dirs, filepath.Dir(cmd.mergeConfigurationPath))
+	}
+
+	for _, dir := range dirs {
+		if len(dir) > 0 {
+			err := os.MkdirAll(dir, 0777)
+			if err != nil {
+				log.Errorf("Creating directory (%s) failed: %v", dir, err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// initService loads the service configuration, prepares the file system and checks whether the configured
+// base configuration and merge configuration files are valid. The settings affecting the ecs package are applied
+// only, if everything else succeeded. If anything goes wrong, the settings that were in effect before are kept.
+func (cmd *ServiceCommand) initService(createIfNotExist bool) (err error) {
+
+	// restore the previous settings on error
+	previousSettings := cmd.serviceSettings
+	defer func() {
+		if err != nil {
+			cmd.serviceSettings = previousSettings
+		}
+	}()
+
+	// load the service configuration file
+	err = cmd.loadServiceConfiguration(cmd.configPath, createIfNotExist)
+	if err != nil {
+		return err
+	}
+
+	// create involved directories as configured in the service configuration
+	err = cmd.setupFilesystem()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the specified base configuration file is valid
+	_, err = loadConfigurationFile(cmd.baseConfigurationPath)
+	if err != nil {
+		log.Errorf("Loading base configuration file failed: %v", err)
+		return err
+	}
+
+	// ensure that the specified merge configuration file is valid
+	if len(cmd.mergeConfigurationPath) > 0 {
+		_, err = atv.LoadMergeConfiguration(cmd.mergeConfigurationPath)
+		if err != nil {
+			log.Errorf("Loading merge configuration file failed: %v", err)
+			return err
+		}
+	}
+
+	// apply the settings affecting the ecs package
+	err = cmd.applyGlobalSettings()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
+	"github.com/griffinplus/mguard-config-tool/shadow"
+
+	"github.com/otiai10/copy"
+	log "github.com/sirupsen/logrus"
+)
+
+// processFileInHotfolder is called when a new file arrives in the input directory for configuration files.
+// It processes .atv/.ecs files, merges them with the common configuration and writes them to the output directory.
+func (cmd *ServiceCommand) processFileInHotfolder(path string) error {
+
+	filename := filepath.Base(path)
+	filenameWithoutExtension := strings.TrimSuffix(filename, filepath.Ext(filename))
+	timestamp := time.Now().Format("20060102150405")
+	var err error
+
+	// determine whether to write files deterministically
+	// (files that are the same as the most recent ones are not written again)
+	skipUnchanged := ecs.IsDeterministicOutputEnabled()
+	var zipModTime *time.Time
+	if skipUnchanged {
+		deterministicTimestamp, err := ecs.GetDeterministicTimestamp()
+		if err != nil {
+			return err
+		}
+		zipModTime = &deterministicTimestamp
+	}
+
+	// extract the serial number of the mGuard, if ECS containers should be encrypted
+	var deviceCertificate *x509.Certificate
+	if cmd.mergedConfigurationsWriteEncryptedEcs {
+
+		// encrypted ECS containers should be generated
+		// => the files must bring along the serial number with the file name
+		serial, err := getSerialNumberFrommGuardConfigurationFileName(path)
+		if err != nil {
+			return err
+		}
+		if serial == nil {
+			return fmt.Errorf("The name of the configuration file (%s) does not match the required pattern (<serial>.(atv|ecs|tgz)", path)
+		}
+
+		// query the certificate manager for the appropriate device certificate
+		deviceCertificate, err = cmd.certificateManager.GetCertificate(*serial)
+		if err != nil {
+			return err
+		}
+	}
+
+	// load the merge configuration file
+	var mergeConfig *atv.MergeConfiguration
+	if len(cmd.mergeConfigurationPath) > 0 {
+		mergeConfig, err = atv.LoadMergeConfiguration(cmd.mergeConfigurationPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// load the configuration file (in the hot folder)
+	ecs, err := loadConfigurationFile(path)
+	if err != nil {
+		return err
+	}
+
+	// determine the version of the configuration file in the hot folder
+	ecsVersion, err := ecs.Atv.GetVersion()
+	if err != nil {
+		return err
+	}
+
+	// load the base configuration file
+	baseEcs, err := loadConfigurationFile(cmd.baseConfigurationPath)
+	if err != nil {
+		return err
+	}
+
+	// determine the version of the base configuration file
+	baseEcsVersion, err := baseEcs.Atv.GetVersion()
+	if err != nil {
+		return err
+	}
+
+	// ensure that the configuration file in the hot folder is made for the same family of devices as the base configuration file
+	if baseEcsVersion.DeviceFamily() != ecsVersion.DeviceFamily() {
+		return fmt.Errorf(
+			"The configuration file (%s, version: %s) is made for %s, but the base configuration file (%s, version: %s) is made for %s",
+			path, ecsVersion, ecsVersion.DeviceFamily(),
+			cmd.baseConfigurationPath, baseEcsVersion, baseEcsVersion.DeviceFamily())
+	}
+
+	// ensure that the version of the base configuration file has the same or a higher version than the configuration file in the hot folder
+	if baseEcsVersion.CompareRelease(ecsVersion) < 0 {
+		return fmt.Errorf(
+			"The configuration file (%s, version: %s) must have the same or a higher version than the base configuration file (%s, version: %s)",
+			path, ecsVersion,
+			cmd.baseConfigurationPath, baseEcsVersion)
+	}
+
+	// migrate configuration file in the hot folder to the version of the base configuration file, if necessary
+	migration, err := ecs.Atv.MigrateWithResult(baseEcsVersion)
+	if err != nil {
+		return err
+	}
+	ecs.Atv = migration.File
+
+	// rename row ids of the loaded configuration that collide with row ids of the base configuration, if configured
+	if cmd.mergeRemapRowIDs {
+		ecs.Atv, _, err = ecs.Atv.RemapCollidingRowIDs(baseEcs.Atv)
+		if err != nil {
+			return err
+		}
+	}
+
+	// merge the base configuration with the loaded configuration
+	mergedAtv, _, err := baseEcs.Atv.MergeSelectively(ecs.Atv, mergeConfig)
+	if err != nil {
+		return err
+	}
+
+	// ensure that the merged configuration is consistent
+	err = validateMergedConfiguration(mergedAtv)
+	if err != nil {
+		return err
+	}
+
+	// keep the base ECS container, but update the configuration
+	mergedEcs := baseEcs.Dupe()
+	mergedEcs.Atv = mergedAtv
+	if err != nil {
+		return err
+	}
+
+	// set the passwords for users 'root' and 'admin', if configured
+	err = cmd.setPassword(mergedEcs, "root", cmd.passwordsRoot, cmd.passwordHashRoot)
+	if err != nil {
+		return err
+	}
+	err = cmd.setPassword(mergedEcs, "admin", cmd.passwordsAdmin, cmd.passwordHashAdmin)
+	if err != nil {
+		return err
+	}
+
+	// set the credentials of the SNMPv3 user, if configured
+	// (the user is added, if necessary, the file is only modified, if the credentials differ)
+	if len(cmd.snmpUsername) > 0 && len(cmd.snmpAuthPassphrase) > 0 {
+		if mergedEcs.SnmpUsers.HasUser(cmd.snmpUsername) {
+			err = mergedEcs.SnmpUsers.SetAuthentication(cmd.snmpUsername, cmd.snmpAuthProtocol, cmd.snmpAuthPassphrase)
+			if err == nil {
+				err = mergedEcs.SnmpUsers.SetPrivacy(cmd.snmpUsername, cmd.snmpPrivProtocol, cmd.snmpPrivPassphrase)
+			}
+		} else {
+			err = mergedEcs.SnmpUsers.AddUser(
+				cmd.snmpUsername,
+				cmd.snmpAuthProtocol, cmd.snmpAuthPassphrase,
+				cmd.snmpPrivProtocol, cmd.snmpPrivPassphrase)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// flag SNMPv3 users that still use the passphrase of the factory settings
+	warnAboutFactorySnmpUsers(mergedEcs)
+
+	// write ATV/ECS files containing the merged result
+	if len(cmd.mergedConfigurationDirectory) > 0 {
+
+		// write ATV file, if requested
+		if cmd.mergedConfigurationsWriteAtv {
+
+			log.Infof("Writing ATV file (%s) to directory (%s)...", filenameWithoutExtension+".atv", cmd.mergedConfigurationDirectory)
+			buffer := bytes.Buffer{}
+			err = mergedEcs.Atv.ToWriter(&buffer)
+			if err == nil {
+				_, err = writeOutputFile(cmd.mergedConfigurationDirectory, filenameWithoutExtension, timestamp, ".atv", buffer.Bytes(), skipUnchanged)
+			}
+			if err != nil {
+				log.Errorf("Writing ATV file (%s) failed: %s", filenameWithoutExtension+".atv", err)
+				return err
+			}
+		}
+
+		// write unencrypted ECS file, if requested
+		if cmd.mergedConfigurationsWriteUnencryptedEcs {
+
+			log.Infof("Writing unencrypted ECS file (%s) to directory (%s)...", filenameWithoutExtension+".ecs", cmd.mergedConfigurationDirectory)
+			buffer := bytes.Buffer{}
+			err = mergedEcs.ToWriter(&buffer)
+			if err == nil {
+				_, err = writeOutputFile(cmd.mergedConfigurationDirectory, filenameWithoutExtension, timestamp, ".ecs", buffer.Bytes(), skipUnchanged)
+			}
+			if err != nil {
+				log.Errorf("Writing unencrypted ECS file (%s) failed: %s", filenameWithoutExtension+".ecs", err)
+				return err
+			}
+		}
+
+		// write encrypted ECS file, if requested
+		// (the encryption uses random keys, so the file is different each time)
+		if cmd.mergedConfigurationsWriteEncryptedEcs {
+
+			ecsFileName := filenameWithoutExtension + " (" + timestamp + ")" + ".ecs.p7e"
+			ecsFilePath := filepath.Join(cmd.mergedConfigurationDirectory, ecsFileName)
+
+			log.Infof("Writing encrypted ECS file (%s)...", ecsFilePath)
+			err := mergedEcs.ToEncryptedFile(ecsFilePath, deviceCertificate)
+			if err != nil {
+				log.Errorf("Writing encrypted ECS file (%s) failed: %s", ecsFilePath, err)
+				return err
+			}
+		}
+
+	} else {
+
+		log.Info("Output directory is not specified. Skipping generation of ATV/ECS files with the merged configuration.")
+
+	}
+
+	// build archive containing the contents of an sdcard that can be used to flash an mGuard with a
+	// the defined firmware and load the merged configuration
+	if len(cmd.updatePackageDirectory) > 0 {
+
+		// create temporary directory to prepare the package in
+		scratchDir, err := ioutil.TempDir("", "sdcard")
+		if err != nil {
+			log.Errorf("%v", err)
+			return err
+		}
+		defer os.RemoveAll(scratchDir)
+
+		// copy files from sdcard template (always configured)
+		src := cmd.sdcardTemplateDirectory + string(filepath.Separator)
+		dest := scratchDir + string(filepath.Separator)
+		err = copy.Copy(src, dest)
+		if err != nil {
+			log.Errorf("Copying sdcard template files into scratch directory failed: %s", err)
+			return err
+		}
+
+		// process template 'preconfig.sh.tmpl'
+		// ------------------------------------------------------------------------------------------------------------
+
+		// parse the template
+		templatePath := filepath.Join(scratchDir, "Rescue Config", "preconfig.sh.tmpl")
+		renderedTemplatePath := filepath.Join(scratchDir, "Rescue Config", "preconfig.sh")
+		template, err := template.ParseFiles(templatePath)
+		if err != nil {
+			log.Errorf("Parsing preconfig.sh.tmpl failed: %s", err)
+			return err
+		}
+
+		// initialize dummy template data
+		data := struct {
+			Atv           bool
+			RootPassword  string
+			AdminPassword string
+		}{
+			false,
+			"XXX",
+			"XXX",
+		}
+
+		// replace passwords with real passwords, if an ATV file is used
+		// (ECS containers encorporate hashed passwords and don't need them)
+		if cmd.updatePackageConfiguration == config_atv {
+			data.Atv = true
+			data.RootPassword = cmd.passwordsRoot
+			data.AdminPassword = cmd.passwordsAdmin
+		}
+
+		// render the template
+		f, err := os.Create(renderedTemplatePath)
+		if err != nil {
+			log.Errorf("Opening preconfig.sh failed: %s", err)
+			return err
+		}
+		defer f.Close()
+		err = template.Execute(f, data)
+		if err != nil {
+			log.Errorf("Executing template preconfig.sh.tmpl and writing to file failed: %s", err)
+			return err
+		}
+		f.Close()
+
+		// remove template file from the temporary directory
+		err = os.Remove(templatePath)
+		if err != nil {
+			log.Errorf("Removing preconfig.sh.tmpl failed: %s", err)
+			return err
+		}
+
+		// write configuration
+		// ------------------------------------------------------------------------------------------------------------
+		switch cmd.updatePackageConfiguration {
+
+		case config_atv:
+			atvFilePath := filepath.Join(scratchDir, "Rescue Config", "preconfig.atv")
+			err = mergedEcs.Atv.ToFile(atvFilePath)
+			if err != nil {
+				log.Errorf("Writing ATV file (%s) failed: %s", atvFilePath, err)
+				return err
+			}
+
+		case config_unencrypted_ecs:
+			ecsFileName := "ECS.tgz"
+			ecsFilePath := filepath.Join(scratchDir, ecsFileName)
+			err = mergedEcs.ToFile(ecsFilePath)
+			if err != nil {
+				log.Errorf("Writing unencrypted ECS file (%s) failed: %s", ecsFilePath, err)
+				return err
+			}
+
+		case config_encrypted_ecs:
+			ecsFileName := filenameWithoutExtension + ".ecs.p7e"
+			ecsFilePath := filepath.Join(scratchDir, ecsFileName)
+			err := mergedEcs.ToEncryptedFile(ecsFilePath, deviceCertificate)
+			if err != nil {
+				log.Errorf("Writing encrypted ECS file (%s) failed: %s", ecsFilePath, err)
+				return err
+			}
+		}
+
+		// create a package wrapping everything up using zip
+		// (the package is the same each time when writing deterministically, unless it contains an encrypted ECS container)
+		log.Infof("Writing update package (%s) to directory (%s)...", filenameWithoutExtension+".zip", cmd.updatePackageDirectory)
+		buffer := bytes.Buffer{}
+		err = zipFiles(scratchDir, &buffer, zipModTime)
+		if err == nil {
+			skipUnchangedPackage := skipUnchanged && cmd.updatePackageConfiguration != config_encrypted_ecs
+			_, err = writeOutputFile(cmd.updatePackageDirectory, filenameWithoutExtension, timestamp, ".zip", buffer.Bytes(), skipUnchangedPackage)
+		}
+		if err != nil {
+			log.Errorf("Creating update package (%s) failed: %s", filenameWithoutExtension+".zip", err)
+			return err
+		}
+
+	} else {
+		log.Info("Output directory is not specified. Skipping generation of update package.")
+	}
+
+	// write the migration report next to the generated files, if the configuration file was migrated
+	// (in the directory receiving ATV/ECS files, if configured, otherwise in the directory receiving update packages)
+	if len(migration.Report.Steps) > 0 {
+		reportDirectory := cmd.mergedConfigurationDirectory
+		if len(reportDirectory) == 0 {
+			reportDirectory = cmd.updatePackageDirectory
+		}
+		if len(reportDirectory) > 0 {
+			log.Infof("Writing migration report (%s) to directory (%s)...", filenameWithoutExtension+".migration.json", reportDirectory)
+			data, err := json.MarshalIndent(migration.Report, "", "  ")
+			if err == nil {
+				_, err = writeOutputFile(reportDirectory, filenameWithoutExtension, timestamp, ".migration.json", append(data, '\n'), skipUnchanged)
+			}
+			if err != nil {
+				log.Errorf("Writing migration report (%s) failed: %s", filenameWithoutExtension+".migration.json", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setPassword sets the password of the specified user in the specified ECS container, if a password or a hashed
+// password is configured. Passwords are hashed with a random salt, so they are only set, if they differ to keep the
+// output the same. When writing deterministically, the salt is derived from the configuration and the day of the last
+// password change is taken from the deterministic timestamp, so the same input always results in the same users.
+func (cmd *ServiceCommand) setPassword(container *ecs.Container, username string, password string, hash string) error {
+
+	deterministic := ecs.IsDeterministicOutputEnabled()
+
+	if len(password) > 0 {
+		if ok, _ := container.Users.VerifyPassword(username, password); ok {
+			return nil
+		}
+		var err error
+		if deterministic {
+			hash, err = shadow.HashPasswordWithSalt(password, cmd.passwordsAlgorithm, cmd.passwordsRounds, deterministicSalt(container, username))
+		} else {
+			hash, err = shadow.HashPassword(password, cmd.passwordsAlgorithm, cmd.passwordsRounds)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(hash) == 0 {
+		return nil
+	}
+
+	err := container.Users.SetPasswordHash(username, hash)
+	if err != nil || !deterministic {
+		return err
+	}
+
+	timestamp, err := ecs.GetDeterministicTimestamp()
+	if err != nil {
+		return err
+	}
+	user, err := container.Users.User(username)
+	if err != nil {
+		return err
+	}
+	day := shadow.Day(timestamp)
+	user.Aging.LastChanged = &day
+	return container.Users.SetAging(username, user.Aging)
+}
+
+// deterministicSalt returns a salt for hashing the password of the specified user in the specified ECS container
+// deterministically. The salt is derived from the user name and the configuration in the container, so it differs
+// between configurations, but it is the same each time the same configuration is processed.
+func deterministicSalt(container *ecs.Container, username string) string {
+
+	const saltCharacters = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	digest := sha256.Sum256([]byte(username + "\n" + container.Atv.String()))
+	salt := make([]byte, 16)
+	for i := range salt {
+		salt[i] = saltCharacters[int(digest[i])%len(saltCharacters)]
+	}
+
+	return string(salt)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
+	"github.com/griffinplus/mguard-config-tool/snmpd"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/integrii/flaggy"
+)
+
+// SnmpCommand represents the 'snmp' subcommand.
+type SnmpCommand struct {
+	inFilePath       string             // the file to process
+	outFilePath      string             // the file receiving the updated ECS container
+	username         string             // name of the SNMPv3 user the operation applys to
+	authProtocolName string             // authentication protocol to set (as specified)
+	authPassphrase   string             // authentication passphrase to set
+	privProtocolName string             // privacy protocol to set (as specified)
+	privPassphrase   string             // privacy passphrase to set
+	authProtocol     snmpd.AuthProtocol // authentication protocol to set
+	privProtocol     snmpd.PrivProtocol // privacy protocol to set
+	subcommand       *flaggy.Subcommand // flaggy's subcommand representing the 'snmp' subcommand
+	addSubcommand    *flaggy.Subcommand // flaggy's subcommand representing the 'snmp add' subcommand
+	removeSubcommand *flaggy.Subcommand // flaggy's subcommand representing the 'snmp remove' subcommand
+	setSubcommand    *flaggy.Subcommand // flaggy's subcommand representing the 'snmp set' subcommand
+}
+
+// NewSnmpCommand creates a new command handling the 'snmp' subcommand.
+func NewSnmpCommand() *SnmpCommand {
+	return &SnmpCommand{}
+}
+
+// AddFlaggySubcommand adds the 'snmp' subcommand to flaggy.
+func (cmd *SnmpCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("snmp")
+	cmd.subcommand.Description = "Add/remove SNMPv3 users and set their credentials (ECS containers only)"
+
+	cmd.addSubcommand = flaggy.NewSubcommand("add")
+	cmd.addSubcommand.Description = "Add a SNMPv3 user"
+	cmd.addSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Name of the SNMPv3 user")
+	cmd.addSubcommand.AddPositionalValue(&cmd.authPassphrase, "auth-passphrase", 2, true, "Authentication passphrase (at least 8 characters)")
+	cmd.addSubcommand.String(&cmd.authProtocolName, "", "auth-protocol", "Authentication protocol (MD5, SHA, default: SHA)")
+	cmd.addSubcommand.String(&cmd.privProtocolName, "", "priv-protocol", "Privacy protocol (DES, AES, none, default: AES)")
+	cmd.addSubcommand.String(&cmd.privPassphrase, "", "priv-passphrase", "Privacy passphrase (at least 8 characters, default: authentication passphrase)")
+	cmd.addSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.addSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.removeSubcommand = flaggy.NewSubcommand("remove")
+	cmd.removeSubcommand.Description = "Remove a SNMPv3 user"
+	cmd.removeSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Name of the SNMPv3 user")
+	cmd.removeSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.removeSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.setSubcommand = flaggy.NewSubcommand("set")
+	cmd.setSubcommand.Description = "Set the protocols and passphrases of a SNMPv3 user"
+	cmd.setSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Name of the SNMPv3 user")
+	cmd.setSubcommand.String(&cmd.authProtocolName, "", "auth-protocol", "Authentication protocol (MD5, SHA, default: current protocol)")
+	cmd.setSubcommand.String(&cmd.authPassphrase, "", "auth-passphrase", "Authentication passphrase (at least 8 characters)")
+	cmd.setSubcommand.String(&cmd.privProtocolName, "", "priv-protocol", "Privacy protocol (DES, AES, none, default: current protocol)")
+	cmd.setSubcommand.String(&cmd.privPassphrase, "", "priv-passphrase", "Privacy passphrase (at least 8 characters, default: authentication passphrase)")
+	cmd.setSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.setSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	// attach subcommands to flaggy
+	cmd.subcommand.AttachSubcommand(cmd.addSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.removeSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.setSubcommand, 1)
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'snmp' subcommand was used in the command line.
+func (cmd *SnmpCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'snmp' subcommand are valid.
+func (cmd *SnmpCommand) ValidateArguments() error {
+
+	// ensure that one of the subcommands is specified
+	if !cmd.addSubcommand.Used && !cmd.removeSubcommand.Used && !cmd.setSubcommand.Used {
+		flaggy.ShowHelpAndExit("")
+	}
+
+	// ensure that the username is specified (needed for all operations)
+	if len(cmd.username) == 0 {
+		return fmt.Errorf("The username was not specified")
+	}
+
+	// ensure that the specified protocols are supported
+	var err error
+	if len(cmd.authProtocolName) > 0 {
+		cmd.authProtocol, err = snmpd.ParseAuthProtocol(cmd.authProtocolName)
+		if err != nil {
+			return err
+		}
+	}
+	if len(cmd.privProtocolName) > 0 {
+		cmd.privProtocol, err = snmpd.ParsePrivProtocol(cmd.privProtocolName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ensure that the authentication protocol is not changed without specifying the passphrase
+	if cmd.setSubcommand.Used {
+		if len(cmd.authProtocolName) > 0 && len(cmd.authPassphrase) == 0 {
+			return fmt.Errorf("The authentication protocol can only be changed along with the passphrase (--auth-passphrase)")
+		}
+		if len(cmd.authProtocolName) == 0 && len(cmd.authPassphrase) == 0 &&
+			len(cmd.privProtocolName) == 0 && len(cmd.privPassphrase) == 0 {
+			return fmt.Errorf("Please specify the protocols and/or passphrases to set")
+		}
+	}
+
+	// ensure that the specified files exist and are readable
+	files := []string{cmd.inFilePath}
+	for _, path := range files {
+		if len(path) > 0 {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			file.Close()
+		}
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'snmp' subcommand.
+func (cmd *SnmpCommand) ExecuteCommand() error {
+
+	// load configuration file (can be ATV or ECS)
+	// (the configuration is always loaded into an ECS container, missing parts are filled with defaults)
+	ecs, err := loadConfigurationFile(cmd.inFilePath)
+	if err != nil {
+		return err
+	}
+
+	if cmd.addSubcommand.Used {
+		err = cmd.executeAdd(ecs)
+	} else if cmd.removeSubcommand.Used {
+		err = cmd.executeRemove(ecs)
+	} else if cmd.setSubcommand.Used {
+		err = cmd.executeSet(ecs)
+	} else {
+		panic("Unhandled subcommand")
+	}
+
+	// abort, if the operation failed
+	if err != nil {
+		return err
+	}
+
+	// check whether a different ECS file was specified as output and fall back to the
+	// input file, if it was not specified
+	effectiveOutFilePath := cmd.outFilePath
+	if len(effectiveOutFilePath) == 0 {
+		effectiveOutFilePath = cmd.inFilePath
+	}
+
+	// operation succeeded, write ECS container
+	if len(effectiveOutFilePath) > 0 {
+		log.Infof("Writing ECS file (%s)...", effectiveOutFilePath)
+		err = ecs.ToFile(effectiveOutFilePath)
+		if err != nil {
+			log.Errorf("Writing ECS file (%s) failed: %s", effectiveOutFilePath, err)
+			return err
+		}
+	} else {
+		log.Info("Writing ECS file to stdout...")
+		buffer := bytes.Buffer{}
+		err := ecs.ToWriter(&buffer)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(buffer.Bytes())
+	}
+
+	return nil
+}
+
+// executeAdd performs the actual work of the 'snmp add' subcommand.
+func (cmd *SnmpCommand) executeAdd(ecs *ecs.Container) error {
+
+	authProtocol := snmpd.SHA
+	if len(cmd.authProtocolName) > 0 {
+		authProtocol = cmd.authProtocol
+	}
+
+	privProtocol := snmpd.AES
+	if len(cmd.privProtocolName) > 0 {
+		privProtocol = cmd.privProtocol
+	}
+
+	return ecs.SnmpUsers.AddUser(cmd.username, authProtocol, cmd.authPassphrase, privProtocol, cmd.privPassphrase)
+}
+
+// executeRemove performs the actual work of the 'snmp remove' subcommand.
+func (cmd *SnmpCommand) executeRemove(ecs *ecs.Container) error {
+	return ecs.SnmpUsers.RemoveUser(cmd.username)
+}
+
+// executeSet performs the actual work of the 'snmp set' subcommand.
+func (cmd *SnmpCommand) executeSet(ecs *ecs.Container) error {
+
+	// determine the current protocols of the user
+	var user *snmpd.User
+	for _, u := range ecs.SnmpUsers.Users() {
+		if u.Name == cmd.username {
+			user = &u
+			break
+		}
+	}
+	if user == nil {
+		return fmt.Errorf("The specified user (%s) does not exist", cmd.username)
+	}
+
+	// set authentication protocol and passphrase
+	// (keep the current protocol, if the protocol is not specified)
+	if len(cmd.authPassphrase) > 0 {
+		protocol := user.AuthProtocol
+		if len(cmd.authProtocolName) > 0 {
+			protocol = cmd.authProtocol
+		} else if protocol == snmpd.NoAuth {
+			protocol = snmpd.SHA
+		}
+		err := ecs.SnmpUsers.SetAuthentication(cmd.username, protocol, cmd.authPassphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	// set privacy protocol and passphrase
+	// (keep the current protocol, if the protocol is not specified)
+	if len(cmd.privProtocolName) > 0 || len(cmd.privPassphrase) > 0 {
+		protocol := user.PrivProtocol
+		if len(cmd.privProtocolName) > 0 {
+			protocol = cmd.privProtocol
+		} else if protocol == snmpd.NoPriv {
+			protocol = snmpd.AES
+		}
+		err := ecs.SnmpUsers.SetPrivacy(cmd.username, protocol, cmd.privPassphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
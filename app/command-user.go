@@ -2,10 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
+	"github.com/griffinplus/mguard-config-tool/shadow"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/integrii/flaggy"
@@ -14,11 +20,28 @@ import (
 // UserCommand represents the 'user' subcommand.
 type UserCommand struct {
 	inFilePath               string             // the file to process
-	outFilePath              string             // the file receiving the updated ECS container
+	outFilePath              string             // the file receiving the updated ECS container (or the user list)
 	username                 string             // login name of the user the operation applys to
 	password                 string             // the password to set/verify
+	passwordHash             string             // the hashed password to set (instead of the password)
+	algorithmName            string             // the algorithm to hash the password with (as specified)
+	algorithm                shadow.Algorithm   // the algorithm to hash the password with
+	rounds                   int                // the number of rounds to hash the password with (0 => default)
+	json                     bool               // true to list users in JSON format, false to list them as plain text
+	lastChanged              string             // aging: day of the last password change (as specified)
+	minimum                  string             // aging: days until a change is allowed (as specified)
+	maximum                  string             // aging: days before a change is required (as specified)
+	warn                     string             // aging: days to warn before the password expires (as specified)
+	inactive                 string             // aging: days after the password expired before the account is locked (as specified)
+	expire                   string             // aging: day the account expires (as specified)
 	subcommand               *flaggy.Subcommand // flaggy's subcommand representing the 'user' subcommand
+	listSubcommand           *flaggy.Subcommand // flaggy's subcommand representing the 'user list' subcommand
 	addSubcommand            *flaggy.Subcommand // flaggy's subcommand representing the 'user add' subcommand
+	removeSubcommand         *flaggy.Subcommand // flaggy's subcommand representing the 'user remove' subcommand
+	lockSubcommand           *flaggy.Subcommand // flaggy's subcommand representing the 'user lock' subcommand
+	unlockSubcommand         *flaggy.Subcommand // flaggy's subcommand representing the 'user unlock' subcommand
+	expireSubcommand         *flaggy.Subcommand // flaggy's subcommand representing the 'user expire' subcommand
+	agingSubcommand          *flaggy.Subcommand // flaggy's subcommand representing the 'user aging' subcommand
 	passwordSubcommand       *flaggy.Subcommand // flaggy's subcommand representing the 'user password' subcommand
 	passwordSetSubcommand    *flaggy.Subcommand // flaggy's subcommand representing the 'user password set' subcommand
 	passwordVerifySubcommand *flaggy.Subcommand // flaggy's subcommand representing the 'user password verify' subcommand
@@ -33,22 +56,70 @@ func NewUserCommand() *UserCommand {
 func (cmd *UserCommand) AddFlaggySubcommand() *flaggy.Subcommand {
 
 	cmd.subcommand = flaggy.NewSubcommand("user")
-	cmd.subcommand.Description = "Add user and set/verify user passwords (ECS containers only)"
+	cmd.subcommand.Description = "Manage user accounts and set/verify user passwords (ECS containers only)"
+
+	cmd.listSubcommand = flaggy.NewSubcommand("list")
+	cmd.listSubcommand.Description = "List users with the state of their passwords and password aging"
+	cmd.listSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.listSubcommand.String(&cmd.outFilePath, "", "out", "File receiving the list of users (instead of stdout)")
+	cmd.listSubcommand.Bool(&cmd.json, "", "json", "Write the list of users in JSON format (instead of plain text)")
 
 	cmd.addSubcommand = flaggy.NewSubcommand("add")
 	cmd.addSubcommand.Description = "Add a user."
 	cmd.addSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
-	cmd.addSubcommand.AddPositionalValue(&cmd.password, "password", 2, true, "Password of the user")
+	cmd.addSubcommand.AddPositionalValue(&cmd.password, "password", 2, false, "Password of the user (required, if --hash is not specified)")
+	cmd.addSubcommand.String(&cmd.passwordHash, "", "hash", "Hashed password of the user (sha256-crypt: $5$..., sha512-crypt: $6$..., instead of the password)")
+	cmd.addSubcommand.String(&cmd.algorithmName, "", "algorithm", "Algorithm to hash the password with (sha256, sha512, default: sha512)")
+	cmd.addSubcommand.Int(&cmd.rounds, "", "rounds", "Number of rounds to hash the password with (0 => 5000 rounds)")
 	cmd.addSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
 	cmd.addSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
 
+	cmd.removeSubcommand = flaggy.NewSubcommand("remove")
+	cmd.removeSubcommand.Description = "Remove a user"
+	cmd.removeSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
+	cmd.removeSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.removeSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.lockSubcommand = flaggy.NewSubcommand("lock")
+	cmd.lockSubcommand.Description = "Lock the password of a user (the user cannot log in with the password)"
+	cmd.lockSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
+	cmd.lockSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.lockSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.unlockSubcommand = flaggy.NewSubcommand("unlock")
+	cmd.unlockSubcommand.Description = "Unlock the password of a user"
+	cmd.unlockSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
+	cmd.unlockSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.unlockSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.expireSubcommand = flaggy.NewSubcommand("expire")
+	cmd.expireSubcommand.Description = "Expire the password of a user (the user must change the password at the next login)"
+	cmd.expireSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
+	cmd.expireSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.expireSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
+	cmd.agingSubcommand = flaggy.NewSubcommand("aging")
+	cmd.agingSubcommand.Description = "Set the password aging fields of a user ('none' clears a field)"
+	cmd.agingSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
+	cmd.agingSubcommand.String(&cmd.lastChanged, "", "last-changed", "Day of the last password change (YYYY-MM-DD or days since 1970-01-01)")
+	cmd.agingSubcommand.String(&cmd.minimum, "", "minimum", "Days until a password change is allowed")
+	cmd.agingSubcommand.String(&cmd.maximum, "", "maximum", "Days before a password change is required")
+	cmd.agingSubcommand.String(&cmd.warn, "", "warn", "Days to warn the user before the password expires")
+	cmd.agingSubcommand.String(&cmd.inactive, "", "inactive", "Days after the password expired before the account is locked")
+	cmd.agingSubcommand.String(&cmd.expire, "", "expire", "Day the account expires (YYYY-MM-DD or days since 1970-01-01)")
+	cmd.agingSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
+	cmd.agingSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
+
 	cmd.passwordSubcommand = flaggy.NewSubcommand("password")
 	cmd.passwordSubcommand.Description = "Set or verify the password of a user"
 
 	cmd.passwordSetSubcommand = flaggy.NewSubcommand("set")
-	cmd.passwordSetSubcommand.Description = "Set the password a user (ECS containers only)"
+	cmd.passwordSetSubcommand.Description = "Set the password of a user (ECS containers only)"
 	cmd.passwordSetSubcommand.AddPositionalValue(&cmd.username, "username", 1, true, "Login name of the user")
-	cmd.passwordSetSubcommand.AddPositionalValue(&cmd.password, "password", 2, true, "Password of the user")
+	cmd.passwordSetSubcommand.AddPositionalValue(&cmd.password, "password", 2, false, "Password of the user (required, if --hash is not specified)")
+	cmd.passwordSetSubcommand.String(&cmd.passwordHash, "", "hash", "Hashed password of the user (sha256-crypt: $5$..., sha512-crypt: $6$..., instead of the password)")
+	cmd.passwordSetSubcommand.String(&cmd.algorithmName, "", "algorithm", "Algorithm to hash the password with (sha256, sha512, default: sha512)")
+	cmd.passwordSetSubcommand.Int(&cmd.rounds, "", "rounds", "Number of rounds to hash the password with (0 => 5000 rounds)")
 	cmd.passwordSetSubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
 	cmd.passwordSetSubcommand.String(&cmd.outFilePath, "", "ecs-out", "File receiving the updated ECS container (unencrypted, instead of stdout)")
 
@@ -59,7 +130,13 @@ func (cmd *UserCommand) AddFlaggySubcommand() *flaggy.Subcommand {
 	cmd.passwordVerifySubcommand.String(&cmd.inFilePath, "", "ecs-in", "The ECS container (unencrypted, instead of stdin)")
 
 	// attach subcommands to flaggy
+	cmd.subcommand.AttachSubcommand(cmd.listSubcommand, 1)
 	cmd.subcommand.AttachSubcommand(cmd.addSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.removeSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.lockSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.unlockSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.expireSubcommand, 1)
+	cmd.subcommand.AttachSubcommand(cmd.agingSubcommand, 1)
 	cmd.subcommand.AttachSubcommand(cmd.passwordSubcommand, 1)
 	cmd.passwordSubcommand.AttachSubcommand(cmd.passwordSetSubcommand, 1)
 	cmd.passwordSubcommand.AttachSubcommand(cmd.passwordVerifySubcommand, 1)
@@ -77,18 +154,57 @@ func (cmd *UserCommand) IsSubcommandUsed() bool {
 func (cmd *UserCommand) ValidateArguments() error {
 
 	// ensure that one of the subcommands is specified
-	if !cmd.addSubcommand.Used && !cmd.passwordSubcommand.Used {
+	if !cmd.listSubcommand.Used && !cmd.addSubcommand.Used && !cmd.removeSubcommand.Used &&
+		!cmd.lockSubcommand.Used && !cmd.unlockSubcommand.Used && !cmd.expireSubcommand.Used &&
+		!cmd.agingSubcommand.Used && !cmd.passwordSubcommand.Used {
 		flaggy.ShowHelpAndExit("")
 	}
 
-	// ensure that the username is specified (needed for all operations)
-	if len(cmd.username) == 0 {
+	// ensure that the username is specified (needed for all operations except listing users)
+	if !cmd.listSubcommand.Used && len(cmd.username) == 0 {
 		return fmt.Errorf("The username was not specified, please add '--username <user>' to the command line")
 	}
 
-	// ensure that the password is specified (needed for all operations)
-	if len(cmd.password) == 0 {
-		return fmt.Errorf("The password was not specified, please add '--pass <new-password>' to the command line")
+	// ensure that either the password or its hash is specified (needed for adding users and setting/verifying passwords)
+	if cmd.addSubcommand.Used || cmd.passwordSetSubcommand.Used {
+		if len(cmd.password) == 0 && len(cmd.passwordHash) == 0 {
+			return fmt.Errorf("The password was not specified, please specify the password or its hash (--hash)")
+		}
+		if len(cmd.password) > 0 && len(cmd.passwordHash) > 0 {
+			return fmt.Errorf("Please specify either the password or its hash (--hash)")
+		}
+		if len(cmd.passwordHash) > 0 && (len(cmd.algorithmName) > 0 || cmd.rounds != 0) {
+			return fmt.Errorf("--algorithm and --rounds can only be used along with the password, not with its hash")
+		}
+	} else if cmd.passwordVerifySubcommand.Used && len(cmd.password) == 0 {
+		return fmt.Errorf("The password was not specified")
+	}
+
+	// ensure that the specified hash can be set and the specified algorithm is supported
+	if len(cmd.passwordHash) > 0 {
+		err := shadow.ValidatePasswordHash(cmd.passwordHash)
+		if err != nil {
+			return err
+		}
+	}
+	cmd.algorithm = shadow.SHA512Crypt
+	if len(cmd.algorithmName) > 0 {
+		var err error
+		cmd.algorithm, err = shadow.ParseAlgorithm(cmd.algorithmName)
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.rounds != 0 && (cmd.rounds < shadow.MinRounds || cmd.rounds > shadow.MaxRounds) {
+		return fmt.Errorf("The number of rounds (%d) must be in the range [%d,%d]", cmd.rounds, shadow.MinRounds, shadow.MaxRounds)
+	}
+
+	// ensure that at least one aging field is specified
+	if cmd.agingSubcommand.Used {
+		fields := []string{cmd.lastChanged, cmd.minimum, cmd.maximum, cmd.warn, cmd.inactive, cmd.expire}
+		if len(strings.Join(fields, "")) == 0 {
+			return fmt.Errorf("Please specify the aging fields to set")
+		}
 	}
 
 	// ensure that the specified files exist and are readable
@@ -116,8 +232,20 @@ func (cmd *UserCommand) ExecuteCommand() error {
 		return err
 	}
 
-	if cmd.addSubcommand.Used {
+	if cmd.listSubcommand.Used {
+		return cmd.executeList(ecs)
+	} else if cmd.addSubcommand.Used {
 		err = cmd.executeAdd(ecs)
+	} else if cmd.removeSubcommand.Used {
+		err = cmd.executeRemove(ecs)
+	} else if cmd.lockSubcommand.Used {
+		err = cmd.executeLock(ecs)
+	} else if cmd.unlockSubcommand.Used {
+		err = cmd.executeUnlock(ecs)
+	} else if cmd.expireSubcommand.Used {
+		err = cmd.executeExpire(ecs)
+	} else if cmd.agingSubcommand.Used {
+		err = cmd.executeAging(ecs)
 	} else if cmd.passwordSetSubcommand.Used {
 		err = cmd.executeSet(ecs)
 	} else if cmd.passwordVerifySubcommand.Used {
@@ -159,14 +287,143 @@ func (cmd *UserCommand) ExecuteCommand() error {
 	return nil
 }
 
+// executeList performs the actual work of the 'user list' subcommand.
+func (cmd *UserCommand) executeList(ecs *ecs.Container) error {
+
+	users, err := ecs.Users.Users()
+	if err != nil {
+		return err
+	}
+
+	// format the list of users
+	// (plain text: one user per line with whitespace separated fields, similar to 'passwd --status')
+	buffer := bytes.Buffer{}
+	if cmd.json {
+		data, err := json.MarshalIndent(users, "", "  ")
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	} else {
+		for _, user := range users {
+			buffer.WriteString(fmt.Sprintf("%s %s %s %s %s %s %s %s\n",
+				user.Name,
+				user.PasswordStatus,
+				formatDay(user.LastChanged),
+				formatDays(user.Minimum),
+				formatDays(user.Maximum),
+				formatDays(user.Warn),
+				formatDays(user.Inactive),
+				formatDay(user.Expire)))
+		}
+	}
+
+	// write the list of users
+	if len(cmd.outFilePath) > 0 {
+		log.Infof("Writing list of users (%s)...", cmd.outFilePath)
+		err := ioutil.WriteFile(cmd.outFilePath, buffer.Bytes(), 0644)
+		if err != nil {
+			log.Errorf("Writing list of users (%s) failed: %s", cmd.outFilePath, err)
+			return err
+		}
+	} else {
+		log.Info("Writing list of users to stdout...")
+		os.Stdout.Write(buffer.Bytes())
+	}
+
+	return nil
+}
+
 // executeAdd performs the actual work of the 'user add' subcommand.
 func (cmd *UserCommand) executeAdd(ecs *ecs.Container) error {
-	return ecs.Users.AddUser(cmd.username, cmd.password)
+
+	// add the user without a password first, then set the password
+	// (the password is hashed with the specified algorithm and number of rounds)
+	err := ecs.Users.AddUser(cmd.username, "")
+	if err != nil {
+		return err
+	}
+
+	return cmd.executeSet(ecs)
+}
+
+// executeRemove performs the actual work of the 'user remove' subcommand.
+func (cmd *UserCommand) executeRemove(ecs *ecs.Container) error {
+
+	// the mGuard needs its built-in users
+	if cmd.username == "root" || cmd.username == "admin" {
+		return fmt.Errorf("The specified user (%s) is built into the mGuard and cannot be removed", cmd.username)
+	}
+
+	return ecs.Users.RemoveUser(cmd.username)
+}
+
+// executeLock performs the actual work of the 'user lock' subcommand.
+func (cmd *UserCommand) executeLock(ecs *ecs.Container) error {
+	return ecs.Users.LockUser(cmd.username)
+}
+
+// executeUnlock performs the actual work of the 'user unlock' subcommand.
+func (cmd *UserCommand) executeUnlock(ecs *ecs.Container) error {
+	return ecs.Users.UnlockUser(cmd.username)
+}
+
+// executeExpire performs the actual work of the 'user expire' subcommand.
+func (cmd *UserCommand) executeExpire(ecs *ecs.Container) error {
+	return ecs.Users.ExpirePassword(cmd.username)
+}
+
+// executeAging performs the actual work of the 'user aging' subcommand.
+func (cmd *UserCommand) executeAging(ecs *ecs.Container) error {
+
+	user, err := ecs.Users.User(cmd.username)
+	if err != nil {
+		return err
+	}
+
+	// replace the specified fields, keep the others
+	fields := []struct {
+		name    string
+		value   string
+		isDay   bool
+		current **int
+	}{
+		{"last-changed", cmd.lastChanged, true, &user.LastChanged},
+		{"minimum", cmd.minimum, false, &user.Minimum},
+		{"maximum", cmd.maximum, false, &user.Maximum},
+		{"warn", cmd.warn, false, &user.Warn},
+		{"inactive", cmd.inactive, false, &user.Inactive},
+		{"expire", cmd.expire, true, &user.Expire},
+	}
+
+	for _, field := range fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		days, err := parseDays(field.value, field.isDay)
+		if err != nil {
+			return fmt.Errorf("The value of --%s is invalid: %s", field.name, err)
+		}
+		*field.current = days
+	}
+
+	return ecs.Users.SetAging(cmd.username, user.Aging)
 }
 
 // executeSet performs the actual work of the 'user password set' subcommand.
 func (cmd *UserCommand) executeSet(ecs *ecs.Container) error {
-	return ecs.Users.SetPassword(cmd.username, cmd.password)
+
+	hash := cmd.passwordHash
+	if len(hash) == 0 {
+		var err error
+		hash, err = shadow.HashPassword(cmd.password, cmd.algorithm, cmd.rounds)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ecs.Users.SetPasswordHash(cmd.username, hash)
 }
 
 // executeVerify performs the actual work of the 'user password verify' subcommand.
@@ -188,3 +445,49 @@ func (cmd *UserCommand) executeVerify(ecs *ecs.Container) error {
 
 	return nil
 }
+
+// parseDays parses the value of a password aging field.
+// 'none' clears the field, a number specifies days, a date (YYYY-MM-DD) can be specified for fields containing a day.
+func parseDays(value string, isDay bool) (*int, error) {
+
+	if value == "none" {
+		return nil, nil
+	}
+
+	if isDay {
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			days := shadow.Day(date)
+			return &days, nil
+		}
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		if isDay {
+			return nil, fmt.Errorf("'%s' is neither 'none', a date (YYYY-MM-DD) nor a number of days", value)
+		}
+		return nil, fmt.Errorf("'%s' is neither 'none' nor a number of days", value)
+	}
+
+	return &days, nil
+}
+
+// formatDay formats the value of a password aging field containing a day as a date (YYYY-MM-DD, '-' if not set).
+func formatDay(day *int) string {
+
+	if day == nil {
+		return "-"
+	}
+
+	return shadow.DayToTime(*day).Format("2006-01-02")
+}
+
+// formatDays formats the value of a password aging field containing a number of days ('-' if not set).
+func formatDays(days *int) string {
+
+	if days == nil {
+		return "-"
+	}
+
+	return strconv.Itoa(*days)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+
+	"github.com/integrii/flaggy"
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateCommand represents the 'validate' subcommand.
+type ValidateCommand struct {
+	inFilePath    string             // the file to validate
+	outFilePath   string             // the file receiving the findings
+	targetVersion string             // the firmware version to validate the configuration for (optional)
+	json          bool               // true to write the findings in JSON format, otherwise false (plain text)
+	subcommand    *flaggy.Subcommand // flaggy's subcommand representing the 'validate' subcommand
+}
+
+// validateReport is the structure of the report written by the 'validate' subcommand in JSON format.
+type validateReport struct {
+	File     string                 `json:"file"`
+	Version  string                 `json:"version"`
+	Scope    validateScope          `json:"scope"`
+	Findings atv.ValidationFindings `json:"findings"`
+}
+
+// validateScope describes which settings the 'validate' subcommand checked against the schema.
+type validateScope struct {
+	SchemaSettings    int    `json:"schemaSettings"`    // number of settings known to the schema (0, if there is no schema)
+	UncheckedSettings int    `json:"uncheckedSettings"` // number of settings in the configuration that were not checked
+	Description       string `json:"description"`       // human-readable description of the scope
+}
+
+// newValidateScope describes the scope of the validation of a configuration for the specified version.
+func newValidateScope(version atv.Version, findings atv.ValidationFindings) validateScope {
+
+	scope := validateScope{UncheckedSettings: len(findings.OfKind(atv.UncheckedSetting))}
+
+	schema, err := atv.SchemaForVersion(version)
+	if err != nil {
+		scope.Description = fmt.Sprintf(
+			"There is no schema for version %s, only the referential integrity was checked.",
+			version)
+		return scope
+	}
+
+	scope.SchemaSettings = schema.NumSettings()
+	scope.Description = fmt.Sprintf(
+		"The referential integrity was checked for all settings, but the schema of version %s knows only %d settings "+
+			"(mainly the ones migrations and merge configurations deal with). %d settings are not known to the schema, "+
+			"their names and values were NOT checked (misspelt setting names end up here as well).",
+		version, scope.SchemaSettings, scope.UncheckedSettings)
+	return scope
+}
+
+// NewValidateCommand creates a new command handling the 'validate' subcommand.
+func NewValidateCommand() *ValidateCommand {
+	return &ValidateCommand{}
+}
+
+// AddFlaggySubcommand adds the 'validate' subcommand to flaggy.
+func (cmd *ValidateCommand) AddFlaggySubcommand() *flaggy.Subcommand {
+
+	cmd.subcommand = flaggy.NewSubcommand("validate")
+	cmd.subcommand.Description = "Check the referential integrity and the known settings of a mGuard configuration file"
+	cmd.subcommand.AddPositionalValue(&cmd.inFilePath, "file", 1, true, "Configuration file to validate")
+	cmd.subcommand.String(&cmd.targetVersion, "", "target-version", "Firmware version to validate the configuration for (default: version of the file)")
+	cmd.subcommand.Bool(&cmd.json, "", "json", "Write the findings in JSON format (instead of plain text)")
+	cmd.subcommand.String(&cmd.outFilePath, "", "out", "File receiving the findings (instead of stdout)")
+
+	flaggy.AttachSubcommand(cmd.subcommand, 1)
+
+	return cmd.subcommand
+}
+
+// IsSubcommandUsed checks whether the 'validate' subcommand was used in the command line.
+func (cmd *ValidateCommand) IsSubcommandUsed() bool {
+	return cmd.subcommand.Used
+}
+
+// ValidateArguments checks whether the specified arguments for the 'validate' subcommand are valid.
+func (cmd *ValidateCommand) ValidateArguments() error {
+
+	// ensure that the target version is valid
+	if len(cmd.targetVersion) > 0 {
+		_, err := atv.ParseVersion(cmd.targetVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ensure that the specified file exists and is readable
+	if len(cmd.inFilePath) > 0 {
+		file, err := os.Open(cmd.inFilePath)
+		if err != nil {
+			return err
+		}
+		file.Close()
+	}
+
+	return nil
+}
+
+// ExecuteCommand performs the actual work of the 'validate' subcommand.
+func (cmd *ValidateCommand) ExecuteCommand() error {
+
+	// load the file (can be ATV or ECS)
+	ecs, err := loadConfigurationFile(cmd.inFilePath)
+	if err != nil {
+		return err
+	}
+
+	// determine the version to validate the configuration for
+	// (defaults to the version of the file)
+	version, err := ecs.Atv.GetVersion()
+	if err != nil {
+		return err
+	}
+	if len(cmd.targetVersion) > 0 {
+		version, _ = atv.ParseVersion(cmd.targetVersion)
+	}
+
+	// validate the configuration
+	log.Infof("Validating configuration for version %s...", version)
+	findings, err := ecs.Atv.Validate(version)
+	if err != nil {
+		return err
+	}
+
+	// format the findings
+	// (along with the scope of the validation, the schema does not know most settings)
+	scope := newValidateScope(version, findings)
+	buffer := bytes.Buffer{}
+	if cmd.json {
+		report := validateReport{
+			File:     cmd.inFilePath,
+			Version:  version.String(),
+			Scope:    scope,
+			Findings: findings,
+		}
+		if report.Findings == nil {
+			report.Findings = atv.ValidationFindings{}
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	} else {
+		for _, finding := range findings {
+			buffer.WriteString(fmt.Sprintf("%s\n", finding))
+		}
+		buffer.WriteString(fmt.Sprintf("Scope: %s\n", scope.Description))
+	}
+
+	// write the findings
+	if len(cmd.outFilePath) > 0 {
+		log.Infof("Writing findings (%s)...", cmd.outFilePath)
+		err := ioutil.WriteFile(cmd.outFilePath, buffer.Bytes(), 0644)
+		if err != nil {
+			log.Errorf("Writing findings (%s) failed: %s", cmd.outFilePath, err)
+			return err
+		}
+	} else {
+		log.Info("Writing findings to stdout...")
+		os.Stdout.Write(buffer.Bytes())
+	}
+
+	// warn about settings that could not be checked
+	// (the bundled schemas do not know most settings, so misspelt settings are reported this way as well)
+	if scope.UncheckedSettings > 0 {
+		log.Warnf("%d settings are not known to the schema of version %s and were not checked.", scope.UncheckedSettings, version)
+	}
+
+	// set the exit code to signal whether the configuration contains errors
+	errors := findings.Errors()
+	if len(errors) > 0 {
+		log.Infof("The configuration contains %d errors and %d warnings.", len(errors), len(findings)-len(errors))
+		ExitCode = 1
+	} else {
+		log.Infof("No errors found in the checked settings (%d warnings, %d settings not checked).", len(findings), scope.UncheckedSettings)
+		ExitCode = 0
+	}
+
+	return nil
+}
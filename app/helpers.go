@@ -3,12 +3,19 @@ package main
 import (
 	"archive/zip"
 	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/griffinplus/mguard-config-tool/mguard/atv"
 	"github.com/griffinplus/mguard-config-tool/mguard/ecs"
@@ -20,10 +27,50 @@ import (
 // have to be generated.
 var filePatternRegex, _ = regexp.Compile(`^([0-9]{10})\.(?:atv|ecs|tgz)$`)
 
+// configurationFormat specifies the format of a loaded configuration file.
+type configurationFormat int
+
+const (
+	atvFormat  configurationFormat = iota // ATV document
+	ecsFormat                             // ECS container (unencrypted or encrypted)
+	jsonFormat                            // ATV document in JSON representation
+	yamlFormat                            // ATV document in YAML representation
+)
+
+// String returns the name of the configuration format.
+func (format configurationFormat) String() string {
+	switch format {
+	case atvFormat:
+		return "ATV"
+	case ecsFormat:
+		return "ECS"
+	case jsonFormat:
+		return "JSON"
+	case yamlFormat:
+		return "YAML"
+	default:
+		return "unknown"
+	}
+}
+
 // loadConfigurationFile loads the specified ATV/ECS file and returns an ECS container with the mGuard configuration.
 // If the file is an ATV file, the missing parts in the ECS container are filled with defaults. If the path is an
-// empty string and stdin is not a console, it trys to read the ATV/ECS file from stdin.
+// empty string and stdin is not a console, it trys to read the ATV/ECS file from stdin. Encrypted ECS containers
+// are detected, but cannot be loaded (see loadEncryptedConfigurationFile).
 func loadConfigurationFile(path string) (*ecs.Container, error) {
+	return loadEncryptedConfigurationFile(path, nil, nil)
+}
+
+// loadEncryptedConfigurationFile works like loadConfigurationFile, but it can load encrypted ECS containers as well,
+// if the private key of the device is specified. The device certificate is optional and may be nil.
+func loadEncryptedConfigurationFile(path string, deviceCertificate *x509.Certificate, privateKey crypto.PrivateKey) (*ecs.Container, error) {
+	container, _, err := loadConfigurationFileWithFormat(path, deviceCertificate, privateKey)
+	return container, err
+}
+
+// loadConfigurationFileWithFormat works like loadEncryptedConfigurationFile, but it returns the format of the
+// loaded file as well.
+func loadConfigurationFileWithFormat(path string, deviceCertificate *x509.Certificate, privateKey crypto.PrivateKey) (*ecs.Container, configurationFormat, error) {
 
 	if len(path) == 0 {
 
@@ -35,7 +82,22 @@ func loadConfigurationFile(path string) (*ecs.Container, error) {
 			data, err := ioutil.ReadAll(os.Stdin)
 			log.Infof("Read %d bytes from stdin.", len(data))
 			if err != nil {
-				return nil, err
+				return nil, ecsFormat, err
+			}
+
+			// check whether the data is an encrypted ECS container
+			if ecs.IsEncryptedContainer(data) {
+				log.Info("Data piped in via stdin is an encrypted ECS container.")
+				if privateKey == nil {
+					return nil, ecsFormat, fmt.Errorf("Data piped in via stdin is an encrypted ECS container, the private key of the device is needed to decrypt it")
+				}
+				log.Info("Trying to decrypt ECS container...")
+				container, err := ecs.ContainerFromEncryptedReader(bytes.NewBuffer(data), deviceCertificate, privateKey)
+				if err != nil {
+					return nil, ecsFormat, fmt.Errorf("Decrypting ECS container failed: %s", err)
+				}
+				log.Info("Reading encrypted ECS container succeeded.")
+				return container, ecsFormat, nil
 			}
 
 			// try to read ECS container from stdin
@@ -43,7 +105,7 @@ func loadConfigurationFile(path string) (*ecs.Container, error) {
 			container, err := ecs.ContainerFromReader(bytes.NewBuffer(data))
 			if err == nil {
 				log.Info("Reading ECS container succeeded.")
-				return container, nil
+				return container, ecsFormat, nil
 			}
 			log.Infof("Reading ECS container failed: %s", err)
 
@@ -53,38 +115,80 @@ func loadConfigurationFile(path string) (*ecs.Container, error) {
 			if err == nil {
 				log.Info("Reading ATV file succeeded.")
 				container := ecs.ContainerFromATV(atv)
-				return container, nil
+				return container, atvFormat, nil
 			}
 			log.Infof("Reading ATV file failed: %s", err)
 
-			return nil, fmt.Errorf("Data piped in via stdin does not seem to be an ECS/ATV file")
+			// try to read ATV document in JSON/YAML representation from stdin
+			// (JSON is a subset of YAML, so the YAML parser would accept JSON documents as well)
+			format := yamlFormat
+			if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+				format = jsonFormat
+			}
+			log.Infof("Trying to interpret data as ATV document in %s representation...", format)
+			atv, err = atvFromData(data, format)
+			if err == nil {
+				log.Infof("Reading %s document succeeded.", format)
+				container := ecs.ContainerFromATV(atv)
+				return container, format, nil
+			}
+			log.Infof("Reading %s document failed: %s", format, err)
+
+			return nil, ecsFormat, fmt.Errorf("Data piped in via stdin does not seem to be an ECS/ATV/JSON/YAML file")
 		}
 
-		return nil, fmt.Errorf("Configuration file was not specified and stdin is no pipe")
+		return nil, ecsFormat, fmt.Errorf("Configuration file was not specified and stdin is no pipe")
 	}
 
 	log.Infof("Trying to load file (%s)...", path)
 
+	// check whether the file is an encrypted ECS container
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, ecsFormat, err
+	}
+	if ecs.IsEncryptedContainer(data) {
+		log.Infof("File (%s) is an encrypted ECS container.", path)
+		if privateKey == nil {
+			return nil, ecsFormat, fmt.Errorf("File (%s) is an encrypted ECS container, the private key of the device is needed to decrypt it", path)
+		}
+		log.Infof("Trying to decrypt file (%s)...", path)
+		container, err := ecs.ContainerFromEncryptedReader(bytes.NewBuffer(data), deviceCertificate, privateKey)
+		if err != nil {
+			return nil, ecsFormat, fmt.Errorf("Decrypting file (%s) failed: %s", path, err)
+		}
+		log.Infof("Reading file (%s) succeeded.", path)
+		return container, ecsFormat, nil
+	}
+
 	ext := strings.ToLower(filepath.Ext(path))
 	var tryOrder []string
 	if ext == ".atv" {
 		// this is probably an ATV file
 		log.Infof("File (%s) has the extension '%s'. This could be an ATV file.", path, ext)
-		tryOrder = []string{"atv", "ecs"}
+		tryOrder = []string{"atv", "ecs", "json", "yaml"}
 	} else if ext == ".tgz" {
 		// this could be an ECS container
 		log.Infof("File (%s) has the extension '%s'. This could be an ECS file.", path, ext)
-		tryOrder = []string{"ecs", "atv"}
+		tryOrder = []string{"ecs", "atv", "json", "yaml"}
+	} else if ext == ".json" {
+		// this could be an ATV document in JSON representation
+		log.Infof("File (%s) has the extension '%s'. This could be a JSON file.", path, ext)
+		tryOrder = []string{"json", "yaml", "ecs", "atv"}
+	} else if ext == ".yaml" || ext == ".yml" {
+		// this could be an ATV document in YAML representation
+		log.Infof("File (%s) has the extension '%s'. This could be a YAML file.", path, ext)
+		tryOrder = []string{"yaml", "ecs", "atv"}
 	} else {
 		// cannot give an educated guess
-		// => try both and check whether one works...
+		// => try all formats and check whether one works...
 		log.Infof("File (%s) has the extension '%s'. Cannot guess the configuration file type from the file extension.", path, ext)
-		tryOrder = []string{"ecs", "atv"}
+		tryOrder = []string{"ecs", "atv", "json", "yaml"}
 	}
 
 loop:
-	for _, format := range tryOrder {
-		switch format {
+	for _, tryFormat := range tryOrder {
+		switch tryFormat {
 
 		case "atv":
 			log.Infof("Trying to interpret file (%s) as an ATV file...", path)
@@ -98,7 +202,7 @@ loop:
 			}
 			log.Infof("Reading file (%s) succeeded.", path)
 			container := ecs.ContainerFromATV(atv)
-			return container, nil
+			return container, atvFormat, nil
 
 		case "ecs":
 			log.Infof("Trying to interpret file (%s) as an ECS file...", path)
@@ -111,16 +215,357 @@ loop:
 				continue
 			}
 			log.Infof("Reading file (%s) succeeded.", path)
-			return container, nil
+			return container, ecsFormat, nil
+
+		case "json", "yaml":
+			format := map[string]configurationFormat{"json": jsonFormat, "yaml": yamlFormat}[tryFormat]
+			log.Infof("Trying to interpret file (%s) as a %s file...", path, format)
+			atv, err := atvFromData(data, format)
+			if err != nil {
+				log.Infof("Reading file (%s) failed: %s", path, err)
+				continue
+			}
+			log.Infof("Reading file (%s) succeeded.", path)
+			container := ecs.ContainerFromATV(atv)
+			return container, format, nil
 
 		default:
 			log.Panic("Unhandled document format")
 		}
 	}
 
-	// the file could neither be read as an ECS container nor as an ATV file
-	return nil, fmt.Errorf("Loading file (%s) failed", path)
+	// the file could neither be read as an ECS container nor as an ATV file (or its JSON/YAML representation)
+	return nil, ecsFormat, fmt.Errorf("Loading file (%s) failed", path)
+
+}
+
+// atvFromData reads an ATV document in JSON or YAML representation from the specified data.
+func atvFromData(data []byte, format configurationFormat) (*atv.File, error) {
+
+	switch format {
+	case jsonFormat:
+		return atv.FromJSON(data)
+	case yamlFormat:
+		return atv.FromYAML(data)
+	default:
+		return atv.FromReader(bytes.NewBuffer(data))
+	}
+}
+
+// atvToData returns the ATV document in the specified representation (ATV, JSON or YAML).
+func atvToData(file *atv.File, format configurationFormat) ([]byte, error) {
+
+	switch format {
+	case jsonFormat:
+		return file.ToJSON()
+	case yamlFormat:
+		return file.ToYAML()
+	default:
+		buffer := bytes.Buffer{}
+		err := file.ToWriter(&buffer)
+		return buffer.Bytes(), err
+	}
+}
+
+// writeAtvFile writes the ATV document to the specified file using the specified representation (ATV, JSON or YAML).
+func writeAtvFile(file *atv.File, format configurationFormat, path string) error {
+
+	if format == atvFormat || format == ecsFormat {
+		return file.ToFile(path)
+	}
+
+	data, err := atvToData(file, format)
+	if err != nil {
+		return err
+	}
+
+	// create directories on the way, if necessary
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// loadPrivateKeyFile loads a PEM encoded private key (PKCS#1 or PKCS#8) from the specified file.
+// If the file contains a certificate as well, the certificate is returned, too (otherwise nil).
+func loadPrivateKeyFile(path string) (crypto.PrivateKey, *x509.Certificate, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var privateKey crypto.PrivateKey
+	var certificate *x509.Certificate
+	for rest := data; ; {
+
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+
+		case "RSA PRIVATE KEY": // PKCS#1 (RSA only)
+			privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("File (%s) contains a PEM encoded '%s' block, but parsing it failed: %s", path, block.Type, err)
+			}
+
+		case "PRIVATE KEY": // PKCS#8
+			privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("File (%s) contains a PEM encoded '%s' block, but parsing it failed: %s", path, block.Type, err)
+			}
+
+		case "ENCRYPTED PRIVATE KEY": // PKCS#8
+			return nil, nil, fmt.Errorf("File (%s) contains an encrypted private key, please decrypt it first", path)
+
+		case "CERTIFICATE":
+			certificate, err = x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("File (%s) contains a PEM encoded '%s' block, but parsing it failed: %s", path, block.Type, err)
+			}
+
+		default:
+			log.Debugf("File (%s) contains an unknown PEM encoded block (%s), skipping block...", path, block.Type)
+		}
+	}
+
+	if privateKey == nil {
+		return nil, nil, fmt.Errorf("File (%s) does not contain a PEM encoded private key", path)
+	}
+
+	return privateKey, certificate, nil
+}
+
+// loadCertificateFile loads a PEM or DER encoded X.509 certificate from the specified file.
+func loadCertificateFile(path string) (*x509.Certificate, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// try to parse PEM encoded certificate
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+
+	// try to parse DER encoded certificate
+	certificate, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("File (%s) does not contain a PEM or DER encoded certificate", path)
+	}
+
+	return certificate, nil
+}
+
+// validateMergedConfiguration checks the referential integrity of the specified merged configuration and its settings
+// (against the schema of its version) and logs the findings. It returns an error, if the configuration contains errors
+// (warnings are logged only, settings the schema does not know are summarized to keep the log readable).
+func validateMergedConfiguration(file *atv.File) error {
+
+	log.Info("Validating merged configuration...")
+	version, err := file.GetVersion()
+	if err != nil {
+		return err
+	}
+
+	findings, err := file.Validate(version)
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range findings {
+		if finding.Severity == atv.ValidationError {
+			log.Errorf("Validation: %s", finding)
+		} else if finding.Kind != atv.UncheckedSetting {
+			log.Warnf("Validation: %s", finding)
+		}
+	}
+
+	unchecked := findings.OfKind(atv.UncheckedSetting)
+	if len(unchecked) > 0 {
+		log.Warnf(
+			"Validation: %d settings are not known to the schema of version %s and were not checked (first one: %s)",
+			len(unchecked), version, unchecked[0].Path)
+	}
+
+	errors := findings.Errors()
+	if len(errors) > 0 {
+		return fmt.Errorf("The merged configuration is invalid (%d errors, first error: %s)", len(errors), errors[0])
+	}
+
+	return nil
+}
+
+// warnAboutFactorySnmpUsers logs a warning for each SNMPv3 user in the specified container that still uses the
+// well-known passphrase of the factory settings.
+func warnAboutFactorySnmpUsers(container *ecs.Container) {
+
+	if container.SnmpUsers == nil {
+		return
+	}
+
+	for _, username := range container.SnmpUsers.UsersWithFactoryPassphrase() {
+		log.Warnf(
+			"The SNMPv3 user '%s' uses the well-known passphrase of the factory settings, change its passphrases or remove it (see subcommand 'snmp')",
+			username)
+	}
+}
+
+// writeMigrationReport writes the specified migration report(s) to the specified file (JSON format).
+func writeMigrationReport(path string, report interface{}) error {
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Writing migration report (%s)...", path)
+	err = ioutil.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		log.Errorf("Writing migration report (%s) failed: %s", path, err)
+		return err
+	}
+
+	return nil
+}
+
+// printMigrationReport prints the specified migration report in a human-readable form to stderr
+// (stdout may receive the resulting configuration).
+func printMigrationReport(report *atv.MigrationReport) {
+	fmt.Fprint(os.Stderr, report.String())
+}
+
+// applyEditBatchFile applies the edits in the specified batch file to the specified ATV file.
+// Each line of the batch file contains one edit: 'set <path> <value>' or 'remove <path>'. The value is the rest
+// of the line, it may be enclosed in double quotes to keep leading/trailing whitespace or to use escape sequences.
+// Empty lines and lines starting with '#' are ignored.
+func applyEditBatchFile(file *atv.File, path string) error {
+
+	log.Infof("Applying edits in batch file (%s)...", path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitEditLine(line)
+		switch {
+
+		case fields[0] == "set" && len(fields) >= 2:
+			value := ""
+			if len(fields) == 3 {
+				value = strings.TrimSpace(fields[2])
+				if strings.HasPrefix(value, "\"") {
+					value, err = strconv.Unquote(value)
+					if err != nil {
+						return fmt.Errorf("Line %d of batch file (%s) contains an invalid quoted value: %s", i+1, path, err)
+					}
+				}
+			}
+			log.Debugf("Setting '%s' to '%s'...", fields[1], value)
+			err = file.SetSimpleValueSetting(fields[1], value)
+
+		case fields[0] == "remove" && len(fields) == 2:
+			log.Debugf("Removing '%s'...", fields[1])
+			err = file.RemoveSetting(fields[1])
+
+		default:
+			return fmt.Errorf("Line %d of batch file (%s) is not a valid edit (expecting 'set <path> <value>' or 'remove <path>')", i+1, path)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Applying line %d of batch file (%s) failed: %s", i+1, path, err)
+		}
+	}
+
+	return nil
+}
+
+// writeEditedConfigurationFile writes the specified edited configuration to the specified ATV and/or ECS file.
+// If no output file is specified, the configuration is written back to the input file using the format of the
+// input file (ATV, ECS, JSON or YAML). If the input was read from stdin, the configuration is written to stdout
+// using the same format.
+func writeEditedConfigurationFile(container *ecs.Container, format configurationFormat, inFilePath, outAtvFilePath, outEcsFilePath string) error {
+
+	// write back to the input file, if no output file was specified
+	// (ATV documents in JSON/YAML representation are written in the same representation)
+	if len(outAtvFilePath) == 0 && len(outEcsFilePath) == 0 && len(inFilePath) > 0 {
+		switch format {
+		case atvFormat:
+			outAtvFilePath = inFilePath
+		case ecsFormat:
+			outEcsFilePath = inFilePath
+		default:
+			log.Infof("Writing %s file (%s)...", format, inFilePath)
+			err := writeAtvFile(container.Atv, format, inFilePath)
+			if err != nil {
+				log.Errorf("Writing %s file (%s) failed: %s", format, inFilePath, err)
+			}
+			return err
+		}
+	}
+
+	// write ATV file, if requested
+	if len(outAtvFilePath) > 0 {
+		log.Infof("Writing ATV file (%s)...", outAtvFilePath)
+		err := container.Atv.ToFile(outAtvFilePath)
+		if err != nil {
+			log.Errorf("Writing ATV file (%s) failed: %s", outAtvFilePath, err)
+			return err
+		}
+	}
+
+	// write ECS file, if requested
+	if len(outEcsFilePath) > 0 {
+		log.Infof("Writing ECS file (%s)...", outEcsFilePath)
+		err := container.ToFile(outEcsFilePath)
+		if err != nil {
+			log.Errorf("Writing ECS file (%s) failed: %s", outEcsFilePath, err)
+			return err
+		}
+	}
+
+	// write the configuration to stdout, if no file was written
+	if len(outAtvFilePath) == 0 && len(outEcsFilePath) == 0 {
+		buffer := bytes.Buffer{}
+		if format == ecsFormat {
+			log.Info("Writing ECS file to stdout...")
+			err := container.ToWriter(&buffer)
+			if err != nil {
+				return err
+			}
+		} else {
+			log.Infof("Writing %s file to stdout...", format)
+			data, err := atvToData(container.Atv, format)
+			if err != nil {
+				return err
+			}
+			buffer.Write(data)
+		}
+		os.Stdout.Write(buffer.Bytes())
+	}
 
+	return nil
 }
 
 // exePath gets the full path of the executable.
@@ -194,32 +639,28 @@ func getSerialNumberFrommGuardConfigurationFileName(path string) (*string, error
 	return &match[1], nil
 }
 
-// zipFiles puts the specified files into a zip archive at the specified location.
-func zipFiles(src string, zipPath string) error {
-
-	// open zip file for writing
-	zipFile, err := os.OpenFile(zipPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer zipFile.Close()
+// zipFiles puts the specified files into a zip archive and writes it to the specified io.Writer.
+// The files are added in lexical order. If a modification time is specified, all files get this modification time,
+// so the same files always result in the same zip archive.
+func zipFiles(src string, writer io.Writer, modTime *time.Time) error {
 
-	// create archiver on top of the file
-	zipWriter := zip.NewWriter(zipFile)
+	// create archiver on top of the writer
+	zipWriter := zip.NewWriter(writer)
 
 	// add files to the zip file
-	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-
-		// skip directories
-		if info.IsDir() {
-			return nil
-		}
+	// (filepath.Walk walks the files in lexical order)
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 
 		// abort on error
 		if err != nil {
 			return err
 		}
 
+		// skip directories
+		if info.IsDir() {
+			return nil
+		}
+
 		// load file to add into memory
 		buf, err := ioutil.ReadFile(path)
 		if err != nil {
@@ -228,7 +669,18 @@ func zipFiles(src string, zipPath string) error {
 
 		// add file to the zip archive
 		relpath, err := filepath.Rel(src, path)
-		f, err := zipWriter.Create(relpath)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(relpath),
+			Method:   zip.Deflate,
+			Modified: info.ModTime(),
+		}
+		if modTime != nil {
+			header.Modified = *modTime
+		}
+		f, err := zipWriter.CreateHeader(header)
 		if err != nil {
 			return err
 		}
@@ -247,10 +699,106 @@ func zipFiles(src string, zipPath string) error {
 	}
 
 	// close the archive
-	err = zipWriter.Close()
+	return zipWriter.Close()
+}
+
+// writeOutputFile writes the specified data to the file '<name> (<timestamp>)<extension>' in the specified directory.
+// If skipUnchanged is true and the most recent file '<name> (<any timestamp>)<extension>' in the directory contains
+// the same data, the file is not written. Returns the path of the file containing the data.
+func writeOutputFile(directory, name, timestamp, extension string, data []byte, skipUnchanged bool) (string, error) {
+
+	if skipUnchanged {
+		previousPath, err := latestOutputFile(directory, name, extension)
+		if err != nil {
+			return "", err
+		}
+		if len(previousPath) > 0 {
+			previousData, err := ioutil.ReadFile(previousPath)
+			if err == nil && bytes.Equal(previousData, data) {
+				log.Infof("File (%s) is up-to-date, skipping writing a new file.", previousPath)
+				return previousPath, nil
+			}
+		}
+	}
+
+	err := os.MkdirAll(directory, os.ModePerm)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	path := filepath.Join(directory, name+" ("+timestamp+")"+extension)
+	err = ioutil.WriteFile(path, data, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// latestOutputFile returns the path of the most recent file '<name> (<timestamp>)<extension>' in the specified
+// directory (empty string, if there is no such file).
+func latestOutputFile(directory, name, extension string) (string, error) {
+
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + ` \((\d{14})\)` + regexp.QuoteMeta(extension) + `$`)
+	infos, err := ioutil.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// the timestamps in the file names sort chronologically
+	latest := ""
+	for _, info := range infos {
+		if !info.IsDir() && pattern.MatchString(info.Name()) && info.Name() > latest {
+			latest = info.Name()
+		}
+	}
+
+	if len(latest) == 0 {
+		return "", nil
+	}
+
+	return filepath.Join(directory, latest), nil
+}
+
+// splitEditLine splits a line of an edit batch file into the command, the path and the value (if any).
+// The path may contain query expressions with quoted values containing whitespace, e.g. VPN_CONNECTION[NAME="a b"].
+func splitEditLine(line string) []string {
+
+	// the command
+	end := strings.IndexAny(line, " \t")
+	if end < 0 {
+		return []string{line}
+	}
+	fields := []string{line[:end]}
+	rest := strings.TrimSpace(line[end:])
+
+	// the path (whitespace in brackets does not terminate the path)
+	depth, quoted := 0, false
+	end = len(rest)
+	for i := 0; i < len(rest) && end == len(rest); i++ {
+		switch c := rest[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t'):
+			end = i
+		}
+	}
+	fields = append(fields, rest[:end])
+
+	// the value
+	if end < len(rest) {
+		fields = append(fields, strings.TrimSpace(rest[end:]))
+	}
+
+	return fields
 }
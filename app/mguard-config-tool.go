@@ -91,8 +91,16 @@ func parseArgs() arguments {
 	subcommands := []command{
 		NewUserCommand(),
 		NewConditionCommand(),
+		NewGetCommand(),
+		NewSetCommand(),
+		NewRemoveCommand(),
 		NewMergeCommand(),
+		NewDiffCommand(),
+		NewValidateCommand(),
 		NewEncryptCommand(),
+		NewDecryptCommand(),
+		NewEcsCommand(),
+		NewSnmpCommand(),
 		NewServiceCommand(),
 	}
 	for _, cmd := range subcommands {
@@ -0,0 +1,23 @@
+package atv
+
+// DeviceFamily identifies the family of mGuard devices a firmware version runs on.
+// Configurations of different device families cannot be combined, because the devices provide different features.
+type DeviceFamily string
+
+const (
+	// ClassicDevices are the mGuard devices running firmware 7.x and 8.x (e.g. mGuard RS4000, mGuard SMART2).
+	ClassicDevices DeviceFamily = "classic mGuard devices (firmware 7.x/8.x)"
+
+	// FLMguardDevices are the FL MGUARD 2000/4000 devices running firmware 10.x.
+	FLMguardDevices DeviceFamily = "FL MGUARD 2000/4000 devices (firmware 10.x)"
+)
+
+// DeviceFamily returns the family of mGuard devices the firmware version runs on.
+func (version Version) DeviceFamily() DeviceFamily {
+
+	if version.Major >= 10 {
+		return FLMguardDevices
+	}
+
+	return ClassicDevices
+}
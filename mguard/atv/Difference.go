@@ -0,0 +1,23 @@
+package atv
+
+// DifferenceKind specifies in which way a setting differs between two ATV documents.
+type DifferenceKind string
+
+const (
+	// SettingAdded indicates that a setting exists in the second document only.
+	SettingAdded DifferenceKind = "added"
+
+	// SettingRemoved indicates that a setting exists in the first document only.
+	SettingRemoved DifferenceKind = "removed"
+
+	// SettingChanged indicates that a setting exists in both documents, but its value differs.
+	SettingChanged DifferenceKind = "changed"
+)
+
+// Difference describes a setting that differs between two ATV documents.
+type Difference struct {
+	Kind     DifferenceKind `json:"kind"`          // kind of the difference
+	Path     string         `json:"path"`          // full path of the setting (pragmas are prefixed with '#')
+	OldValue string         `json:"old,omitempty"` // value in the first document (empty, if the setting was added)
+	NewValue string         `json:"new,omitempty"` // value in the second document (empty, if the setting was removed)
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // File represents a mGuard configuration file.
@@ -70,16 +72,7 @@ func FromReader(reader io.Reader) (*File, error) {
 		return nil, err
 	}
 
-	// create atv file object
-	file := File{doc: doc}
-
-	// ensure that the version pragma exists and is properly formatted
-	_, err = file.GetVersion()
-	if err != nil {
-		return nil, err
-	}
-
-	return &file, nil
+	return newFileFromDocument(doc)
 }
 
 // ToFile saves the ATV document to the specified file.
@@ -122,6 +115,68 @@ func (file *File) ToWriter(writer io.Writer) error {
 	return err
 }
 
+// FromJSON reads an ATV document from its JSON representation (see ToJSON).
+func FromJSON(data []byte) (*File, error) {
+
+	doc, err := documentFromJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileFromDocument(doc)
+}
+
+// ToJSON returns the JSON representation of the ATV document.
+// The JSON document contains a list of nodes (pragmas and settings) in the order they appear in the ATV document.
+// Settings have a name and a simple value, attributes (e.g. 'value', 'rowref', 'uuid' and 'access') and/or table
+// rows with optional row ids. Comments are kept along with the elements they are attached to. Converting the JSON
+// document back to ATV results in the same document (except for non-standard formatting).
+func (file *File) ToJSON() ([]byte, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	return file.doc.toJSON()
+}
+
+// FromYAML reads an ATV document from its YAML representation (see ToYAML).
+func FromYAML(data []byte) (*File, error) {
+
+	doc, err := documentFromYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileFromDocument(doc)
+}
+
+// ToYAML returns the YAML representation of the ATV document.
+// The YAML document is structured the same way as the JSON document (see ToJSON).
+func (file *File) ToYAML() ([]byte, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	return file.doc.toYAML()
+}
+
+// newFileFromDocument creates an ATV file object wrapping the specified document.
+// The document must contain a properly formatted version pragma.
+func newFileFromDocument(doc *document) (*File, error) {
+
+	file := File{doc: doc}
+
+	// ensure that the version pragma exists and is properly formatted
+	_, err := file.GetVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
 // GetVersion gets the version of the document.
 func (file *File) GetVersion() (Version, error) {
 
@@ -287,27 +342,240 @@ func (file *File) GetSetting(settingName string) (string, error) {
 	return setting.String(), nil
 }
 
-// Merge merges all settings from the specified ATV document into the current one.
-func (file *File) Merge(other *File) (*File, error) {
-	merged, err := file.doc.Merge(other.doc)
+// GetSettingValue gets the value of the setting with the specified name.
+// Simple values (with or without metadata) are returned as they are, table values are returned in ATV notation.
+// If the setting does not exist, nil is returned.
+func (file *File) GetSettingValue(settingName string) (*string, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	setting, err := file.doc.GetSetting(settingName)
+	if err != nil || setting == nil {
+		return nil, err
+	}
+
+	value, err := setting.GetValue()
+	if err != nil {
+		value = setting.TableValue.String()
+	}
+
+	return &value, nil
+}
+
+// GetSettingValues gets the values of all settings selected by the specified path (may contain query expressions).
+// Simple values (with or without metadata) are returned as they are, table values are returned in ATV notation.
+// If the path ends with a row selector, the selected table rows are returned in ATV notation.
+func (file *File) GetSettingValues(settingPath string) ([]string, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	path, err := parseDocumentSettingPath(settingPath)
 	if err != nil {
 		return nil, err
 	}
-	return &File{doc: merged}, nil
+
+	if path[len(path)-1].isRowSelector() {
+		rows, err := file.doc.findRows(path)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(rows))
+		for _, row := range rows {
+			values = append(values, row.String())
+		}
+		return values, nil
+	}
+
+	settings, err := file.FindSettings(settingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, len(settings))
+	for _, setting := range settings {
+		value, err := setting.setting.GetValue()
+		if err != nil {
+			value = setting.setting.TableValue.String()
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
 }
 
-// MergeSelectively merges the specified settings from the specified ATV document into the current one.
-func (file *File) MergeSelectively(other *File, config *MergeConfiguration) (*File, error) {
-	merged, err := file.doc.MergeSelectively(other.doc, config)
+// Settings returns all top-level settings in the order they appear in the document.
+func (file *File) Settings() []*Setting {
+
+	if file == nil {
+		return nil
+	}
+
+	var settings []*Setting
+	for _, node := range file.doc.Nodes {
+		if node.Setting != nil {
+			settings = append(settings, &Setting{setting: node.Setting})
+		}
+	}
+
+	return settings
+}
+
+// Setting gets the setting with the specified name (a top-level setting or a path to a nested setting).
+// If the path selects multiple settings, the first one is returned (see FindSettings).
+// If the setting does not exist, nil is returned.
+func (file *File) Setting(settingName string) (*Setting, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	setting, err := file.doc.GetSetting(settingName)
 	if err != nil {
 		return nil, err
 	}
-	return &File{doc: merged}, nil
+
+	return newSetting(setting), nil
 }
 
-// Migrate migrates the ATV file to the specified version (upwards only).
+// FindSettings returns all settings selected by the specified path.
+// Table rows can be selected by their index, by the wildcard '*' or by a query expression selecting rows by their
+// content, e.g. VPN_CONNECTION[NAME="plant-a"].VPN_START or FW_INCOMING[rid="abc"].COMMENT.
+func (file *File) FindSettings(settingPath string) ([]*Setting, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	path, err := parseDocumentSettingPath(settingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := file.doc.findSettings(path)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]*Setting, 0, len(found))
+	for _, setting := range found {
+		settings = append(settings, &Setting{setting: setting})
+	}
+
+	return settings, nil
+}
+
+// FindRows returns all table rows selected by the specified path.
+// The path must end with a row selector, e.g. VPN_CONNECTION[NAME="plant-*"], FW_INCOMING[rid="abc"] or FW_INCOMING.*.
+func (file *File) FindRows(rowPath string) ([]*Row, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	path, err := parseDocumentSettingPath(rowPath)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := file.doc.findRows(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*Row, 0, len(found))
+	for _, row := range found {
+		rows = append(rows, &Row{row: row})
+	}
+
+	return rows, nil
+}
+
+// SetTableSetting sets the setting with the specified name to an empty table and returns the table.
+// Settings and table rows along the path are created, if necessary. Attributes of an existing setting are kept.
+func (file *File) SetTableSetting(settingName string) (*Table, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	path, err := parseDocumentSettingPath(settingName)
+	if err != nil {
+		return nil, err
+	}
+
+	setting, err := file.doc.createSettingPlaceholder(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSetting(setting).SetTable(), nil
+}
+
+// SetSimpleValueSetting sets the setting with the specified name to a simple string value.
+// Settings and table rows along the path are created, if necessary. Attributes of an existing setting are kept.
+// If the path contains query expressions, all selected settings are set (nothing is created).
+func (file *File) SetSimpleValueSetting(settingName string, value string) error {
+
+	if file == nil {
+		return ErrNilReceiver
+	}
+
+	return file.doc.SetSimpleValueSetting(settingName, value)
+}
+
+// RemoveSetting removes the setting with the specified name.
+// If the path ends with a row selector, the table rows are removed. If the path contains query expressions,
+// all selected settings and rows are removed.
+// If the setting does not exist, no error is signalled.
+func (file *File) RemoveSetting(settingName string) error {
+
+	if file == nil {
+		return ErrNilReceiver
+	}
+
+	return file.doc.RemoveSetting(settingName)
+}
+
+// Merge merges all settings from the specified ATV document into the current one.
+// The returned report lists settings that conflicted with their access modifiers (also returned on error).
+func (file *File) Merge(other *File) (*File, *MergeReport, error) {
+	merged, report, err := file.doc.Merge(other.doc)
+	if err != nil {
+		return nil, report, err
+	}
+	return &File{doc: merged}, report, nil
+}
+
+// MergeSelectively merges the specified settings from the specified ATV document into the current one.
+// The returned report lists settings that conflicted with their access modifiers (also returned on error).
+func (file *File) MergeSelectively(other *File, config *MergeConfiguration) (*File, *MergeReport, error) {
+	merged, report, err := file.doc.MergeSelectively(other.doc, config)
+	if err != nil {
+		return nil, report, err
+	}
+	return &File{doc: merged}, report, nil
+}
+
+// Migrate migrates the ATV file to the specified version (upwards and downwards).
+// Settings that do not exist in the target version are removed and logged (see MigrateWithResult).
 func (file *File) Migrate(targetVersion Version) (*File, error) {
 
+	result, err := file.MigrateWithResult(targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.File, nil
+}
+
+// MigrateWithResult migrates the ATV file to the specified version (upwards and downwards) and returns the migrated
+// file along with the settings that were lost and a report describing every change the migration steps made.
+func (file *File) MigrateWithResult(targetVersion Version) (*MigrationResult, error) {
+
 	if file == nil {
 		return nil, ErrNilReceiver
 	}
@@ -317,67 +585,57 @@ func (file *File) Migrate(targetVersion Version) (*File, error) {
 		return nil, err
 	}
 
-	if currentVersion.Compare(targetVersion) > 0 {
-		return nil, fmt.Errorf("Current atv document version (%s) must not be greater than the target version (%s)", currentVersion, targetVersion)
-	}
-
-	// migrations in ascending order
-	migrations := []migrationProvider{
-		migration_7_5_0_to_7_6_0{},
-		migration_7_6_0_to_7_6_1{},
-		migration_7_6_1_to_7_6_2{},
-		migration_7_6_2_to_8_0_1{},
-		migration_8_0_1_to_8_0_2{},
-		migration_8_0_2_to_8_1_0{},
-		migration_8_1_0_to_8_1_1{},
-		migration_8_1_1_to_8_1_2{},
-		migration_8_1_2_to_8_1_3{},
-		migration_8_1_3_to_8_1_4{},
-		migration_8_1_4_to_8_1_5{},
-		migration_8_1_5_to_8_1_6{},
-		migration_8_1_6_to_8_1_7{},
-		migration_8_1_7_to_8_1_8{},
-		migration_8_1_8_to_8_3_0{},
-		migration_8_3_0_to_8_3_1{},
-		migration_8_3_1_to_8_4_0{},
-		migration_8_4_0_to_8_4_1{},
-		migration_8_4_1_to_8_4_2{},
-		migration_8_4_2_to_8_5_0{},
-		migration_8_5_0_to_8_5_1{},
-		migration_8_5_1_to_8_5_2{},
-		migration_8_5_2_to_8_5_3{},
-		migration_8_5_3_to_8_6_0{},
-		migration_8_6_0_to_8_6_1{},
-		migration_8_6_1_to_8_7_0{},
-		migration_8_7_0_to_8_7_1{},
-		migration_8_7_1_to_8_8_0{},
-		migration_8_8_0_to_8_8_1{},
-	}
-
-	// run migrations
-	current := file.Dupe()
-	for _, migration := range migrations {
-
-		// abort, if the migration would lead to a higher version number than specified
-		if migration.ToVersion().Compare(targetVersion) > 0 {
-			break
-		}
+	result := &MigrationResult{
+		File:   file.Dupe(),
+		Report: &MigrationReport{FromVersion: currentVersion.String(), ToVersion: targetVersion.String(), Steps: []*MigrationStep{}},
+	}
 
-		// get version of the current document
-		currentVersion, err = current.GetVersion()
+	// work out the migration steps leading to the target version
+	path, err := migrations.path(currentVersion, targetVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pathStep := range path {
+
+		// migrate
+		log.Debugf("Migrating from version %s to version %s...", pathStep.from(), pathStep.to())
+		step := &MigrationStep{FromVersion: pathStep.from().String(), ToVersion: pathStep.to().String(), Changes: []MigrationChange{}}
+		if pathStep.down {
+			result.File, err = pathStep.migration.(reverseMigrationProvider).MigrateDown(result.File, step)
+		} else {
+			result.File, err = pathStep.migration.Migrate(result.File, step)
+		}
 		if err != nil {
 			return nil, err
 		}
+		result.Report.Steps = append(result.Report.Steps, step)
+	}
 
-		// migrate
-		current, err = migration.Migrate(current)
+	// the migrations apply to all variants of a version
+	// => the document gets the suffix of the target version
+	if currentVersion.Compare(targetVersion) != 0 {
+		if len(path) == 0 {
+			log.Debugf("Changing the variant of the document from version %s to version %s...", currentVersion, targetVersion)
+		}
+		err = result.File.SetVersion(targetVersion)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	// log settings that were lost
+	for _, step := range result.Report.Steps {
+		for _, change := range step.Changes {
+			if change.Lost {
+				log.Warnf("Setting '%s' does not exist in version %s, removed it.", change.Path, step.ToVersion)
+			}
+		}
+	}
+	result.LostSettings = result.Report.LostSettings()
+
 	// check whether the target version was reached
-	currentVersion, err = current.GetVersion()
+	currentVersion, err = result.File.GetVersion()
 	if err != nil {
 		return nil, err
 	}
@@ -385,5 +643,73 @@ func (file *File) Migrate(targetVersion Version) (*File, error) {
 		return nil, fmt.Errorf("Migration failed, could not reach target version (%s)", targetVersion)
 	}
 
-	return current, nil
+	return result, nil
+}
+
+// Diff compares the ATV document with the specified one and returns the settings that differ.
+// Both documents must have the same version, so the older document should be migrated first.
+func (file *File) Diff(other *File) ([]Difference, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	version, err := file.GetVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	otherVersion, err := other.GetVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if version.Compare(otherVersion) != 0 {
+		return nil, fmt.Errorf("The documents to compare have different versions (%s, %s)", version, otherVersion)
+	}
+
+	return file.doc.Diff(other.doc)
+}
+
+// Validate checks the ATV document for use with the specified firmware version and returns the findings.
+// It checks the referential integrity of the document and reports row references that do not refer to an existing
+// row and row ids that are used multiple times (errors) as well as rows without a row id in tables that use row ids
+// (warnings). Furthermore it checks the settings against the schema of the firmware version and reports settings
+// that do not exist in the version and values that are not valid (errors) as well as settings that are not known to
+// the schema and could not be checked (warnings). If there is no schema for the version, a warning is reported
+// instead. Positions of findings refer to the document as it was parsed.
+func (file *File) Validate(version Version) (ValidationFindings, error) {
+
+	if file == nil {
+		return nil, ErrNilReceiver
+	}
+
+	findings := file.doc.Validate()
+
+	schema, err := SchemaForVersion(version)
+	if err != nil {
+		findings = append(findings, ValidationFinding{
+			Severity: ValidationWarning,
+			Kind:     MissingSchema,
+			Path:     "",
+			Message:  fmt.Sprintf("There is no schema for version %s, settings and values were not checked", version),
+		})
+		return findings, nil
+	}
+
+	return append(findings, file.doc.ValidateSchema(schema)...), nil
+}
+
+// RemapCollidingRowIDs returns a copy of the ATV document with all row ids that are used in the specified document as
+// well renamed to fresh unique row ids. Row references referring to renamed rows are rewritten accordingly, so the
+// copy can be merged into the specified document without replacing unrelated rows. The returned map maps the old
+// row ids to the new ones.
+func (file *File) RemapCollidingRowIDs(other *File) (*File, map[RowID]RowID, error) {
+
+	if file == nil {
+		return nil, nil, ErrNilReceiver
+	}
+
+	remapped, mapping := file.doc.remapCollidingRowIDs(other.doc)
+	return &File{doc: remapped}, mapping, nil
 }
@@ -3,21 +3,62 @@ package atv
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
-// MergeConfiguration defines which settings should be merged from one ATV document into another.
+// MergeConfiguration defines which settings should be merged from one ATV document into another and how they
+// should be merged.
 type MergeConfiguration struct {
-	paths documentSettingPaths
+	includeAll bool                 // true to merge all top-level settings (except excluded ones)
+	rules      []mergeRule          // settings to merge (along with the merge strategy to use)
+	excludes   documentSettingPaths // settings not to merge
+}
+
+// mergeRule defines how the settings at a specific path are merged.
+type mergeRule struct {
+	path     documentSettingPath // path of the setting (may contain wildcards)
+	strategy MergeStrategy       // strategy to use when merging the setting
+	column   string              // name of the column identifying rows (for MergeUnionByColumn only)
 }
 
-var commentRegex = regexp.MustCompile(`^\s*([^#]*)\s*(#.*)?$`)
+// mergeConfigurationFile represents a merge configuration file in YAML format.
+type mergeConfigurationFile struct {
+	Settings []mergeConfigurationEntry `yaml:"settings"`
+}
+
+// mergeConfigurationEntry represents an entry in the 'settings' list of a merge configuration file in YAML format.
+// An entry is either a simple string (path) or a map with the path, the merge strategy and the column.
+type mergeConfigurationEntry struct {
+	Path     string `yaml:"path"`
+	Strategy string `yaml:"strategy"`
+	Column   string `yaml:"column"`
+}
+
+// commentRegex splits a line into the setting path and a trailing comment
+// (hash characters in quoted values of query expressions do not start a comment).
+var commentRegex = regexp.MustCompile(`^\s*((?:[^#"]|"(?:[^"\\]|\\.)*")*)\s*(#.*)?$`)
 
 // LoadMergeConfiguration loads a merge configuration file.
+// Files with the extension '.yaml' or '.yml' are read as YAML files, all other files are read line by line.
 func LoadMergeConfiguration(path string) (*MergeConfiguration, error) {
 
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		return loadYamlMergeConfiguration(path)
+	}
+
+	return loadLineBasedMergeConfiguration(path)
+}
+
+// loadLineBasedMergeConfiguration loads a merge configuration file that contains one path per line.
+func loadLineBasedMergeConfiguration(path string) (*MergeConfiguration, error) {
+
 	// open file for reading
 	file, err := os.Open(path)
 	if err != nil {
@@ -35,16 +76,18 @@ func LoadMergeConfiguration(path string) (*MergeConfiguration, error) {
 
 		// remove comments
 		matches := commentRegex.FindStringSubmatch(line)
+		if matches == nil {
+			matches = []string{line, line} // unbalanced quotes, let the path parser report the error
+		}
 		if len(matches[0]) == 0 || len(matches[1]) == 0 {
 			continue // empty line or a line with just a comment
 		}
 
-		lineWithoutComment := matches[1]
-		settingPath, err := parseDocumentSettingPath(lineWithoutComment)
+		lineWithoutComment := strings.TrimSpace(matches[1])
+		err := config.add(lineWithoutComment, DefaultMergeStrategy, "")
 		if err != nil {
 			return nil, fmt.Errorf("Reading merge configuration file failed (line: %d). Error: %s", lineNo, err)
 		}
-		config.paths = append(config.paths, settingPath)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -54,18 +97,163 @@ func LoadMergeConfiguration(path string) (*MergeConfiguration, error) {
 	return &config, nil
 }
 
-// ShouldMergeSetting indicates whether the specified setting should be merged.
+// loadYamlMergeConfiguration loads a merge configuration file in YAML format.
+func loadYamlMergeConfiguration(path string) (*MergeConfiguration, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file mergeConfigurationFile
+	err = yaml.UnmarshalStrict(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("Reading merge configuration file failed. Error: %s", err)
+	}
+
+	config := MergeConfiguration{}
+	for i, entry := range file.Settings {
+
+		strategy := DefaultMergeStrategy
+		if len(entry.Strategy) > 0 {
+			strategy, err = ParseMergeStrategy(entry.Strategy)
+			if err != nil {
+				return nil, fmt.Errorf("Reading merge configuration file failed (entry: %d). Error: %s", i+1, err)
+			}
+		}
+
+		err = config.add(strings.TrimSpace(entry.Path), strategy, entry.Column)
+		if err != nil {
+			return nil, fmt.Errorf("Reading merge configuration file failed (entry: %d). Error: %s", i+1, err)
+		}
+	}
+
+	return &config, nil
+}
+
+// UnmarshalYAML unmarshals an entry of the 'settings' list in a merge configuration file.
+// The entry can be a simple string (path) or a map with the path, the merge strategy and the column.
+func (entry *mergeConfigurationEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+
+	var path string
+	if err := unmarshal(&path); err == nil {
+		entry.Path = path
+		return nil
+	}
+
+	type plainEntry mergeConfigurationEntry
+	return unmarshal((*plainEntry)(entry))
+}
+
+// add adds a rule to the merge configuration.
+// '*' includes all top-level settings, a path with a preceding '!' excludes the setting at the path.
+func (cfg *MergeConfiguration) add(s string, strategy MergeStrategy, column string) error {
+
+	// include all settings
+	if s == "*" {
+		if strategy != DefaultMergeStrategy {
+			return fmt.Errorf("A merge strategy cannot be specified for '*'")
+		}
+		cfg.includeAll = true
+		return nil
+	}
+
+	// exclude setting
+	if strings.HasPrefix(s, "!") {
+		if strategy != DefaultMergeStrategy {
+			return fmt.Errorf("A merge strategy cannot be specified for an excluded setting")
+		}
+		settingPath, err := parseDocumentSettingPath(strings.TrimSpace(s[1:]))
+		if err != nil {
+			return err
+		}
+		cfg.excludes = append(cfg.excludes, settingPath)
+		return nil
+	}
+
+	// include setting
+	settingPath, err := parseDocumentSettingPath(s)
+	if err != nil {
+		return err
+	}
+	if strategy == MergeUnionByColumn && len(column) == 0 {
+		return fmt.Errorf("The merge strategy '%s' requires a column", strategy)
+	}
+	if strategy != MergeUnionByColumn && len(column) > 0 {
+		return fmt.Errorf("A column can only be specified along with the merge strategy '%s'", MergeUnionByColumn)
+	}
+	cfg.rules = append(cfg.rules, mergeRule{path: settingPath, strategy: strategy, column: column})
+	return nil
+}
+
+// ShouldMergeSetting indicates whether the specified setting should be merged as a whole.
 func (cfg *MergeConfiguration) ShouldMergeSetting(path documentSettingPath) bool {
 
 	if cfg == nil {
 		return false
 	}
 
-	for _, p := range cfg.paths {
-		if p.String() == path.String() {
+	if cfg.isExcluded(path) {
+		return false
+	}
+
+	if cfg.includeAll && len(path) == 1 {
+		return true
+	}
+
+	for _, rule := range cfg.rules {
+		if rule.path.Matches(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExcluded checks whether the specified setting (or a setting it is nested in) is excluded from merging.
+func (cfg *MergeConfiguration) isExcluded(path documentSettingPath) bool {
+
+	if cfg == nil {
+		return false
+	}
+
+	for _, exclude := range cfg.excludes {
+		if len(exclude) <= len(path) && exclude.Matches(path[:len(exclude)]) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// getStrategy returns the merge strategy to use when merging the specified setting and the column identifying
+// rows (for MergeUnionByColumn only).
+func (cfg *MergeConfiguration) getStrategy(path documentSettingPath) (MergeStrategy, string) {
+
+	if cfg != nil {
+		for _, rule := range cfg.rules {
+			if rule.path.Matches(path) {
+				return rule.strategy, rule.column
+			}
+		}
+	}
+
+	return DefaultMergeStrategy, ""
+}
+
+// nestedPaths returns the configured paths that select settings or rows nested in the specified setting.
+func (cfg *MergeConfiguration) nestedPaths(path documentSettingPath) documentSettingPaths {
+
+	if cfg == nil {
+		return nil
+	}
+
+	var paths documentSettingPaths
+	for _, rule := range cfg.rules {
+		if len(rule.path) > len(path) && rule.path[:len(path)].Matches(path) {
+			paths = append(paths, rule.path)
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,86 @@
+package atv
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MergeReport collects information about a merge operation, in particular settings that were not merged as
+// requested due to their access modifiers in the base document.
+type MergeReport struct {
+	Violations []AccessViolation `json:"violations"` // settings that conflicted with their access modifier
+}
+
+// AccessViolation describes a setting in the base document whose access modifier conflicts with the merge operation.
+type AccessViolation struct {
+	Path    string `json:"path"`    // full path of the setting (or row)
+	Access  string `json:"access"`  // access modifier of the setting in the base document
+	Message string `json:"message"` // description of the violation
+	Fatal   bool   `json:"fatal"`   // true, if the violation let the merge operation fail
+}
+
+// String returns the violation as a single line string.
+func (violation AccessViolation) String() string {
+	return fmt.Sprintf("%s (%s): %s", violation.Path, violation.Access, violation.Message)
+}
+
+// HasViolations checks whether the report contains any access violations.
+func (report *MergeReport) HasViolations() bool {
+	return report != nil && len(report.Violations) > 0
+}
+
+// FatalViolations returns the access violations that let the merge operation fail.
+func (report *MergeReport) FatalViolations() []AccessViolation {
+
+	if report == nil {
+		return nil
+	}
+
+	var violations []AccessViolation
+	for _, violation := range report.Violations {
+		if violation.Fatal {
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+// addViolation adds an access violation to the report and logs it.
+func (report *MergeReport) addViolation(path string, access AccessModifier, fatal bool, format string, args ...interface{}) {
+
+	violation := AccessViolation{
+		Path:    path,
+		Access:  access.String(),
+		Message: fmt.Sprintf(format, args...),
+		Fatal:   fatal,
+	}
+
+	if fatal {
+		log.Errorf("Access violation: %s", violation)
+	} else {
+		log.Warnf("Access violation: %s", violation)
+	}
+
+	if report != nil {
+		report.Violations = append(report.Violations, violation)
+	}
+}
+
+// fatalError returns an error summarizing the fatal access violations (nil, if there are no fatal violations).
+func (report *MergeReport) fatalError() error {
+
+	violations := report.FatalViolations()
+	if len(violations) == 0 {
+		return nil
+	}
+
+	var paths []string
+	for _, violation := range violations {
+		paths = append(paths, violation.Path)
+	}
+
+	return fmt.Errorf("Merging failed, the following settings must be overwritten, but were not supplied: %s", strings.Join(paths, ", "))
+}
@@ -0,0 +1,58 @@
+package atv
+
+import "fmt"
+
+// MergeStrategy determines how a setting is merged into a document that contains the setting already.
+type MergeStrategy int
+
+const (
+	// MergeReplace indicates that the setting in the document is replaced entirely.
+	MergeReplace MergeStrategy = iota
+
+	// MergeAppendRows indicates that all rows are appended to the table in the document (table values only).
+	MergeAppendRows
+
+	// MergeReplaceRowsByRid indicates that rows with the same row id replace the rows in the document and other rows
+	// are appended to the table in the document (table values only).
+	MergeReplaceRowsByRid
+
+	// MergeKeepBase indicates that the setting in the document is kept, if it exists.
+	MergeKeepBase
+
+	// MergeUnionByColumn indicates that rows are appended to the table in the document, if the table does not contain
+	// a row with the same value in a specific column, yet (table values only).
+	MergeUnionByColumn
+
+	// DefaultMergeStrategy is the merge strategy that applies, if no merge strategy is specified. Simple values are
+	// replaced, rows of table values are merged by their row id (see MergeReplaceRowsByRid).
+	DefaultMergeStrategy
+)
+
+var mergeStrategyMapping = []string{
+	"replace",             // MergeReplace
+	"append-rows",         // MergeAppendRows
+	"replace-rows-by-rid", // MergeReplaceRowsByRid
+	"keep-base",           // MergeKeepBase
+	"union-by-column",     // MergeUnionByColumn
+	"default",             // DefaultMergeStrategy
+}
+
+// String returns the string representation of the merge strategy.
+func (strategy MergeStrategy) String() string {
+	return mergeStrategyMapping[strategy]
+}
+
+// ParseMergeStrategy parses the specified string as a merge strategy.
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	for i, item := range mergeStrategyMapping {
+		if item == s {
+			return MergeStrategy(i), nil
+		}
+	}
+	return DefaultMergeStrategy, fmt.Errorf("'%s' is not a valid merge strategy", s)
+}
+
+// isTableStrategy checks whether the merge strategy can be applied to table values only.
+func (strategy MergeStrategy) isTableStrategy() bool {
+	return strategy == MergeAppendRows || strategy == MergeReplaceRowsByRid || strategy == MergeUnionByColumn
+}
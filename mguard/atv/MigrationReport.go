@@ -0,0 +1,161 @@
+package atv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MigrationChangeKind specifies how a migration changed a setting.
+type MigrationChangeKind string
+
+const (
+	// ChangeAdded indicates a setting that was added (e.g. a new setting with its default value).
+	ChangeAdded MigrationChangeKind = "added"
+
+	// ChangeRemoved indicates a setting that was removed.
+	ChangeRemoved MigrationChangeKind = "removed"
+
+	// ChangeRenamed indicates a setting that was renamed (the value is kept).
+	ChangeRenamed MigrationChangeKind = "renamed"
+
+	// ChangeValueMapped indicates a setting whose value was mapped to the corresponding value in the target version.
+	ChangeValueMapped MigrationChangeKind = "value-mapped"
+
+	// ChangeConverted indicates a setting that was replaced with a setting of a different structure.
+	ChangeConverted MigrationChangeKind = "converted"
+)
+
+// MigrationReport describes the changes a migration made to an ATV document.
+type MigrationReport struct {
+	FromVersion string           `json:"from"`  // version of the document before the migration
+	ToVersion   string           `json:"to"`    // version of the document after the migration
+	Steps       []*MigrationStep `json:"steps"` // migration steps in the order they were applied
+}
+
+// MigrationStep describes the changes a single migration step (from one firmware version to the next one) made.
+type MigrationStep struct {
+	FromVersion string            `json:"from"`    // version of the document before the step
+	ToVersion   string            `json:"to"`      // version of the document after the step
+	Changes     []MigrationChange `json:"changes"` // changes made to settings
+}
+
+// MigrationChange describes a change a migration step made to a setting.
+type MigrationChange struct {
+	Kind     MigrationChangeKind `json:"kind"`               // kind of the change
+	Path     string              `json:"path"`               // path of the setting (before the change)
+	NewPath  string              `json:"newPath,omitempty"`  // path of the setting after the change (renamed/converted settings only)
+	OldValue *string             `json:"oldValue,omitempty"` // value before the change (simple values only)
+	NewValue *string             `json:"newValue,omitempty"` // value after the change (simple values only)
+	Lost     bool                `json:"lost,omitempty"`     // true, if the setting was removed, because it cannot be represented in the target version
+}
+
+// HasChanges checks whether the migration changed any settings.
+func (report *MigrationReport) HasChanges() bool {
+
+	if report != nil {
+		for _, step := range report.Steps {
+			if len(step.Changes) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// LostSettings returns the paths of the settings that were removed, because they cannot be represented in the target
+// version.
+func (report *MigrationReport) LostSettings() []string {
+
+	var paths []string
+	if report != nil {
+		for _, step := range report.Steps {
+			for _, change := range step.Changes {
+				if change.Lost {
+					paths = append(paths, change.Path)
+				}
+			}
+		}
+	}
+
+	return paths
+}
+
+// String returns the report in a human-readable form (multiple lines).
+func (report *MigrationReport) String() string {
+
+	if report == nil {
+		return "<nil>"
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Migration from version %s to version %s", report.FromVersion, report.ToVersion))
+	if len(report.Steps) == 0 {
+		builder.WriteString(": nothing to do\n")
+		return builder.String()
+	}
+
+	builder.WriteString("\n")
+	for _, step := range report.Steps {
+		builder.WriteString(fmt.Sprintf("- %s => %s", step.FromVersion, step.ToVersion))
+		if len(step.Changes) == 0 {
+			builder.WriteString(": no changes\n")
+			continue
+		}
+		builder.WriteString("\n")
+		for _, change := range step.Changes {
+			builder.WriteString("  - ")
+			builder.WriteString(change.String())
+			builder.WriteString("\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// String returns the change as a single line string.
+func (change MigrationChange) String() string {
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s: %s", change.Kind, change.Path))
+	if len(change.NewPath) > 0 {
+		builder.WriteString(" => " + change.NewPath)
+	}
+
+	switch {
+	case change.OldValue != nil && change.NewValue != nil:
+		builder.WriteString(fmt.Sprintf(" ('%s' => '%s')", *change.OldValue, *change.NewValue))
+	case change.OldValue != nil:
+		builder.WriteString(fmt.Sprintf(" ('%s')", *change.OldValue))
+	case change.NewValue != nil:
+		builder.WriteString(fmt.Sprintf(" ('%s')", *change.NewValue))
+	}
+
+	if change.Lost {
+		builder.WriteString(" (not supported by the target version)")
+	}
+
+	return builder.String()
+}
+
+// add adds a change to the migration step.
+func (step *MigrationStep) add(change MigrationChange) {
+	step.Changes = append(step.Changes, change)
+}
+
+// reportValue returns the simple value of the specified setting for use in a migration report
+// (nil, if the setting does not have a simple value).
+func reportValue(setting *documentSetting) *string {
+
+	value, err := setting.GetValue()
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
+
+// reportString returns a pointer to the specified string for use in a migration report.
+func reportString(s string) *string {
+	return &s
+}
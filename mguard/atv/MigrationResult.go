@@ -0,0 +1,8 @@
+package atv
+
+// MigrationResult is the result of migrating an ATV document to another version.
+type MigrationResult struct {
+	File         *File            // the migrated ATV document
+	LostSettings []string         // paths of settings that were removed, because they do not exist in the target version
+	Report       *MigrationReport // the changes the migration made to the document, step by step
+}
@@ -0,0 +1,93 @@
+package atv
+
+// Row represents a row in the table value of a setting in an ATV document.
+// The cells of a row are settings themselves, they can have simple values, table values or refer to other rows.
+// A row is a view on the document, changes are applied to the document immediately.
+type Row struct {
+	row *documentTableRow
+}
+
+// ID returns the row id of the row.
+// If the row does not have a row id, nil is returned.
+func (row *Row) ID() *RowID {
+
+	if row.row.RowID == nil {
+		return nil
+	}
+
+	id := *row.row.RowID
+	return &id
+}
+
+// SetID sets the row id of the row.
+func (row *Row) SetID(id RowID) {
+	row.row.RowID = &id
+}
+
+// RemoveID removes the row id of the row.
+func (row *Row) RemoveID() {
+	row.row.RowID = nil
+}
+
+// Cells returns all cells of the row in the order they appear in the document.
+func (row *Row) Cells() []*Setting {
+
+	cells := make([]*Setting, 0, len(row.row.Items))
+	for _, item := range row.row.Items {
+		cells = append(cells, &Setting{setting: item})
+	}
+
+	return cells
+}
+
+// Cell returns the cell with the specified name.
+// If the row does not contain a cell with the specified name, nil is returned.
+func (row *Row) Cell(name string) *Setting {
+	return newSetting(row.row.getItem(name))
+}
+
+// SetCell sets the cell with the specified name to the specified simple value and returns it.
+// The cell is added, if it does not exist, yet. Attributes of an existing cell are kept.
+func (row *Row) SetCell(name string, value string) *Setting {
+
+	item := row.row.getItem(name)
+	if item == nil {
+		item = &documentSetting{Name: name}
+		row.row.Items = append(row.row.Items, item)
+	}
+
+	item.setSimpleValue(value)
+	return &Setting{setting: item}
+}
+
+// SetTableCell sets the cell with the specified name to an empty table and returns the table.
+// The cell is added, if it does not exist, yet. Attributes of an existing cell are kept.
+func (row *Row) SetTableCell(name string) *Table {
+
+	item := row.row.getItem(name)
+	if item == nil {
+		item = &documentSetting{Name: name}
+		row.row.Items = append(row.row.Items, item)
+	}
+
+	return (&Setting{setting: item}).SetTable()
+}
+
+// RemoveCell removes the cell with the specified name.
+// Returns true, if the cell was removed; false, if the row does not contain a cell with the specified name.
+func (row *Row) RemoveCell(name string) bool {
+
+	for i, item := range row.row.Items {
+		if item.Name == name {
+			row.row.Items = append(row.row.Items[:i], row.row.Items[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns the row in ATV notation.
+func (row *Row) String() string {
+	return row.row.String()
+}
@@ -0,0 +1,256 @@
+package atv
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ValueType specifies the type of the value of a setting.
+type ValueType string
+
+const (
+	// StringType indicates a setting with an arbitrary string value.
+	StringType ValueType = "string"
+
+	// BooleanType indicates a setting with the value 'yes' or 'no'.
+	BooleanType ValueType = "boolean"
+
+	// IntegerType indicates a setting with an integer value.
+	IntegerType ValueType = "integer"
+
+	// PortType indicates a setting with a TCP/UDP port number (1-65535).
+	PortType ValueType = "port"
+
+	// IPAddressType indicates a setting with an IPv4 address.
+	IPAddressType ValueType = "ip"
+
+	// NetworkType indicates a setting with an IPv4 network in CIDR notation (e.g. 192.168.1.0/24).
+	NetworkType ValueType = "network"
+
+	// NetmaskType indicates a setting with an IPv4 netmask (dotted notation or number of bits).
+	NetmaskType ValueType = "netmask"
+
+	// EnumType indicates a setting with a value out of a set of allowed values.
+	EnumType ValueType = "enum"
+
+	// RowReferenceType indicates a setting referring to a table row.
+	// Some of these settings accept predefined simple values as well, so simple values are not checked.
+	RowReferenceType ValueType = "rowref"
+
+	// TableType indicates a setting with a table value.
+	TableType ValueType = "table"
+)
+
+// SettingSchema describes a setting (a top-level setting or a column of a table).
+type SettingSchema struct {
+	Name     string           // name of the setting
+	Type     ValueType        // type of the value of the setting
+	Values   []string         // allowed values (EnumType only)
+	Columns  []*SettingSchema // known columns of the table (TableType only)
+	Complete bool             // true, if all columns of the table are known (TableType only)
+	Since    *Version         // first version supporting the setting (nil: supported by all known versions)
+	Until    *Version         // first version that does not support the setting anymore (nil: still supported)
+	removed  []*SettingSchema // columns that do not exist in the firmware version (from other versions)
+}
+
+// Schema describes the settings of a specific firmware version.
+// The bundled schemas are not complete, settings that are not known to the schema are not checked (validating a
+// document reports them as unchecked).
+type Schema struct {
+	Version  Version          // the firmware version the schema describes
+	Settings []*SettingSchema // known top-level settings
+	removed  []*SettingSchema // settings that do not exist in the firmware version (from other versions)
+}
+
+// SchemaVersions returns the firmware versions schemas are bundled for (the versions File.Migrate knows).
+func SchemaVersions() []Version {
+
+	return append([]Version(nil), migrations.versions...)
+}
+
+// SchemaForVersion returns the schema describing the settings of the specified firmware version.
+// The suffix of the version is not taken into account.
+func SchemaForVersion(version Version) (*Schema, error) {
+
+	for _, knownVersion := range SchemaVersions() {
+		if knownVersion.CompareRelease(version) == 0 {
+			settings, removed := filterSettingSchemas(schemaDefinitions, version)
+			return &Schema{Version: version, Settings: settings, removed: removed}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("There is no schema for version %s", version)
+}
+
+// NumSettings returns the number of settings known to the schema (top-level settings and table columns).
+func (schema *Schema) NumSettings() int {
+	return countSettingSchemas(schema.Settings)
+}
+
+// countSettingSchemas returns the number of the specified setting schemas including their columns (recursively).
+func countSettingSchemas(schemas []*SettingSchema) int {
+
+	count := 0
+	for _, schema := range schemas {
+		count += 1 + countSettingSchemas(schema.Columns)
+	}
+
+	return count
+}
+
+// filterSettingSchemas returns copies of the setting schemas that are supported by the specified version and the
+// setting schemas that are not supported by the specified version.
+func filterSettingSchemas(schemas []*SettingSchema, version Version) ([]*SettingSchema, []*SettingSchema) {
+
+	var supported, unsupported []*SettingSchema
+	for _, schema := range schemas {
+		if !schema.supports(version) {
+			unsupported = append(unsupported, schema)
+			continue
+		}
+		copy := *schema
+		if len(schema.Columns) > 0 {
+			copy.Columns, copy.removed = filterSettingSchemas(schema.Columns, version)
+		}
+		supported = append(supported, &copy)
+	}
+
+	return supported, unsupported
+}
+
+// supports checks whether the setting exists in the specified version.
+func (schema *SettingSchema) supports(version Version) bool {
+
+	if schema.Since != nil && version.CompareRelease(*schema.Since) < 0 {
+		return false
+	}
+
+	if schema.Until != nil && version.CompareRelease(*schema.Until) >= 0 {
+		return false
+	}
+
+	return true
+}
+
+// Setting returns the schema of the setting at the specified path (e.g. VPN_CONNECTION.0.VPN_START).
+// Row selectors in the path are ignored. If the setting is not known to the schema, nil is returned.
+func (schema *Schema) Setting(settingPath string) (*SettingSchema, error) {
+
+	path, err := parseDocumentSettingPath(settingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := schema.Settings
+	var setting *SettingSchema
+	for _, token := range path {
+		if token.name == nil {
+			continue
+		}
+		setting = findSettingSchema(settings, *token.name)
+		if setting == nil {
+			return nil, nil
+		}
+		settings = setting.Columns
+	}
+
+	return setting, nil
+}
+
+// findSettingSchema returns the setting schema with the specified name (nil, if there is no such setting schema).
+func findSettingSchema(schemas []*SettingSchema, name string) *SettingSchema {
+
+	for _, schema := range schemas {
+		if schema.Name == name {
+			return schema
+		}
+	}
+
+	return nil
+}
+
+// CheckValue checks whether the specified simple value is valid for the setting.
+// Returns nil, if the value is valid; otherwise an error describing the problem.
+func (schema *SettingSchema) CheckValue(value string) error {
+
+	switch schema.Type {
+
+	case StringType:
+		return nil
+
+	case BooleanType:
+		if value != "yes" && value != "no" {
+			return fmt.Errorf("'%s' is not a valid boolean value (expecting 'yes' or 'no')", value)
+		}
+
+	case IntegerType:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("'%s' is not a valid integer", value)
+		}
+
+	case PortType:
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("'%s' is not a valid port number (expecting 1-65535)", value)
+		}
+
+	case IPAddressType:
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("'%s' is not a valid IPv4 address", value)
+		}
+
+	case NetworkType:
+		ip, _, err := net.ParseCIDR(value)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("'%s' is not a valid IPv4 network (expecting CIDR notation, e.g. 192.168.1.0/24)", value)
+		}
+
+	case NetmaskType:
+		if bits, err := strconv.Atoi(value); err == nil {
+			if bits < 0 || bits > 32 {
+				return fmt.Errorf("'%s' is not a valid netmask (expecting 0-32 bits)", value)
+			}
+			return nil
+		}
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("'%s' is not a valid netmask", value)
+		}
+		if _, bits := net.IPMask(ip.To4()).Size(); bits == 0 {
+			return fmt.Errorf("'%s' is not a valid netmask (bits are not contiguous)", value)
+		}
+
+	case EnumType:
+		for _, allowed := range schema.Values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' is not a valid value (expecting %s)", value, quoteAll(schema.Values))
+
+	case RowReferenceType:
+		return nil
+
+	case TableType:
+		return fmt.Errorf("The setting must be a table, but it has the value '%s'", value)
+
+	default:
+		panic("Unhandled value type")
+	}
+
+	return nil
+}
+
+// quoteAll returns the specified values in single quotes separated by commas.
+func quoteAll(values []string) string {
+
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+value+"'")
+	}
+
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,165 @@
+package atv
+
+import (
+	"fmt"
+)
+
+// Setting represents a setting in an ATV document - either a top-level setting or a cell in a table row.
+// A setting is a view on the document, changes are applied to the document immediately.
+type Setting struct {
+	setting *documentSetting
+}
+
+// newSetting returns a setting wrapping the specified document setting (nil, if the document setting is nil).
+func newSetting(setting *documentSetting) *Setting {
+
+	if setting == nil {
+		return nil
+	}
+
+	return &Setting{setting: setting}
+}
+
+// Name returns the name of the setting.
+func (setting *Setting) Name() string {
+	return setting.setting.Name
+}
+
+// IsTable checks whether the setting has a table value.
+func (setting *Setting) IsTable() bool {
+	return setting.setting.isTable()
+}
+
+// IsRowReference checks whether the setting is a reference to a table row.
+func (setting *Setting) IsRowReference() bool {
+	return setting.RowReference() != nil
+}
+
+// Value gets the value of the setting, if the setting has a simple value (with or without metadata).
+// If the setting has a table value or is a row reference, an error is returned.
+func (setting *Setting) Value() (string, error) {
+	return setting.setting.GetValue()
+}
+
+// SetValue sets the setting to the specified simple value.
+// Attributes of the setting are kept. A table value is replaced.
+func (setting *Setting) SetValue(value string) {
+	setting.setting.setSimpleValue(value)
+}
+
+// RowReference gets the row the setting refers to.
+// If the setting is not a row reference, nil is returned.
+func (setting *Setting) RowReference() *RowRef {
+
+	if setting.setting.isTable() {
+		return nil
+	}
+
+	value := setting.setting.getAttribute("rowref")
+	if value == nil {
+		return nil
+	}
+
+	rowref := RowRef(*value)
+	return &rowref
+}
+
+// SetRowReference lets the setting refer to the row with the specified row id.
+// Attributes of the setting are kept. A simple value or a table value is replaced.
+func (setting *Setting) SetRowReference(rowref RowRef) {
+
+	attributes := dictionary{}
+	if dict := setting.setting.attributes(); dict != nil && !setting.setting.isTable() {
+		attributes = append(attributes, *dict...)
+		attributes.Remove("value")
+	}
+	attributes.Set("rowref", string(rowref))
+
+	setting.setting.ClearValue()
+	setting.setting.TableValue = &documentTableValue{Attributes: attributes}
+}
+
+// Table returns the table value of the setting.
+// If the setting does not have a table value, nil is returned.
+func (setting *Setting) Table() *Table {
+
+	if !setting.setting.isTable() {
+		return nil
+	}
+
+	return &Table{table: setting.setting.TableValue}
+}
+
+// SetTable replaces the value of the setting with an empty table and returns it.
+// Attributes of the setting are kept.
+func (setting *Setting) SetTable() *Table {
+
+	attributes := dictionary{}
+	if dict := setting.setting.attributes(); dict != nil {
+		attributes = append(attributes, *dict...)
+		attributes.Remove("value")
+		attributes.Remove("rowref")
+	}
+
+	table := &documentTableValue{Attributes: attributes, Rows: []*documentTableRow{}}
+	setting.setting.ClearValue()
+	setting.setting.TableValue = table
+	return &Table{table: table}
+}
+
+// Attribute gets the attribute with the specified name (e.g. 'access' or 'uuid').
+// If the attribute does not exist, nil is returned.
+func (setting *Setting) Attribute(name string) *string {
+
+	if name == "value" || name == "rowref" {
+		return nil
+	}
+
+	return setting.setting.getAttribute(name)
+}
+
+// AttributeNames returns the names of all attributes of the setting in the order they appear in the document.
+func (setting *Setting) AttributeNames() []string {
+
+	var names []string
+	if dict := setting.setting.attributes(); dict != nil {
+		for _, kvp := range *dict {
+			if kvp.Key != "value" && kvp.Key != "rowref" {
+				names = append(names, kvp.Key)
+			}
+		}
+	}
+
+	return names
+}
+
+// SetAttribute sets the attribute with the specified name (e.g. 'access' or 'uuid').
+func (setting *Setting) SetAttribute(name string, value string) error {
+
+	if name == "value" || name == "rowref" {
+		return fmt.Errorf("'%s' is not an attribute, it is part of the value", name)
+	}
+
+	if setting.setting.SimpleValue == nil && setting.setting.attributes() == nil {
+		return fmt.Errorf("The setting '%s' does not have a value", setting.setting.Name)
+	}
+
+	setting.setting.setAttribute(name, value)
+	return nil
+}
+
+// RemoveAttribute removes the attribute with the specified name.
+// If the attribute does not exist, nothing is done.
+func (setting *Setting) RemoveAttribute(name string) {
+
+	if name == "value" || name == "rowref" {
+		return
+	}
+
+	setting.setting.removeAttribute(name)
+}
+
+// String returns the setting in ATV notation.
+func (setting *Setting) String() string {
+	return setting.setting.String()
+}
@@ -0,0 +1,119 @@
+package atv
+
+import (
+	"fmt"
+)
+
+// Table represents the table value of a setting in an ATV document.
+// A table is a view on the document, changes are applied to the document immediately.
+type Table struct {
+	table *documentTableValue
+}
+
+// Len returns the number of rows in the table.
+func (table *Table) Len() int {
+	return len(table.table.Rows)
+}
+
+// Rows returns all rows of the table.
+func (table *Table) Rows() []*Row {
+
+	rows := make([]*Row, 0, len(table.table.Rows))
+	for _, row := range table.table.Rows {
+		rows = append(rows, &Row{row: row})
+	}
+
+	return rows
+}
+
+// Row returns the row at the specified index.
+// If the index is out of range, nil is returned.
+func (table *Table) Row(index int) *Row {
+
+	if index < 0 || index >= len(table.table.Rows) {
+		return nil
+	}
+
+	return &Row{row: table.table.Rows[index]}
+}
+
+// RowByID returns the row with the specified row id.
+// If the table does not contain a row with the specified row id, nil is returned.
+func (table *Table) RowByID(id RowID) *Row {
+
+	for _, row := range table.table.Rows {
+		if row.RowID != nil && *row.RowID == id {
+			return &Row{row: row}
+		}
+	}
+
+	return nil
+}
+
+// IndexOf returns the index of the specified row in the table.
+// If the row is not in the table, -1 is returned.
+func (table *Table) IndexOf(row *Row) int {
+
+	for i, existingRow := range table.table.Rows {
+		if existingRow == row.row {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AppendRow appends an empty row to the table and returns it.
+func (table *Table) AppendRow() *Row {
+	row := &documentTableRow{Items: []*documentSetting{}}
+	table.table.Rows = append(table.table.Rows, row)
+	return &Row{row: row}
+}
+
+// InsertRow inserts an empty row at the specified index and returns it.
+// The index may be the number of rows in the table to append the row.
+func (table *Table) InsertRow(index int) (*Row, error) {
+
+	if index < 0 || index > len(table.table.Rows) {
+		return nil, fmt.Errorf("Row index %d is out of range [0,%d]", index, len(table.table.Rows))
+	}
+
+	row := &documentTableRow{Items: []*documentSetting{}}
+	table.table.Rows = append(table.table.Rows, nil)
+	copy(table.table.Rows[index+1:], table.table.Rows[index:])
+	table.table.Rows[index] = row
+	return &Row{row: row}, nil
+}
+
+// DeleteRow deletes the row at the specified index.
+func (table *Table) DeleteRow(index int) error {
+
+	if index < 0 || index >= len(table.table.Rows) {
+		return fmt.Errorf("Row index %d is out of range [0,%d]", index, len(table.table.Rows)-1)
+	}
+
+	table.table.Rows = append(table.table.Rows[:index], table.table.Rows[index+1:]...)
+	return nil
+}
+
+// MoveRow moves the row at the specified index to another index.
+// The rows in between are shifted accordingly.
+func (table *Table) MoveRow(from int, to int) error {
+
+	count := len(table.table.Rows)
+	if from < 0 || from >= count {
+		return fmt.Errorf("Row index %d is out of range [0,%d]", from, count-1)
+	}
+	if to < 0 || to >= count {
+		return fmt.Errorf("Row index %d is out of range [0,%d]", to, count-1)
+	}
+
+	row := table.table.Rows[from]
+	if from < to {
+		copy(table.table.Rows[from:to], table.table.Rows[from+1:to+1])
+	} else {
+		copy(table.table.Rows[to+1:from+1], table.table.Rows[to:from])
+	}
+	table.table.Rows[to] = row
+	return nil
+}
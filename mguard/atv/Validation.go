@@ -0,0 +1,101 @@
+package atv
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// ValidationSeverity specifies how severe a validation finding is.
+type ValidationSeverity string
+
+const (
+	// ValidationError indicates that the document is broken and must not be used.
+	ValidationError ValidationSeverity = "error"
+
+	// ValidationWarning indicates that the document is suspicious, but can be used.
+	ValidationWarning ValidationSeverity = "warning"
+)
+
+// ValidationFindingKind specifies the kind of a validation finding.
+type ValidationFindingKind string
+
+const (
+	// DanglingRowReference indicates that a row reference refers to a row that does not exist.
+	DanglingRowReference ValidationFindingKind = "dangling-rowref"
+
+	// DuplicateRowID indicates that multiple rows have the same row id.
+	DuplicateRowID ValidationFindingKind = "duplicate-rid"
+
+	// MissingRowID indicates that a row does not have a row id, but other rows of the same table have.
+	MissingRowID ValidationFindingKind = "missing-rid"
+
+	// UnsupportedSetting indicates that a setting does not exist in the firmware version the document is validated for.
+	UnsupportedSetting ValidationFindingKind = "unsupported-setting"
+
+	// UnknownSetting indicates that a setting is not known to the schema of a table whose columns are all known.
+	UnknownSetting ValidationFindingKind = "unknown-setting"
+
+	// UncheckedSetting indicates that a setting is not known to the schema, so neither its name nor its value could be
+	// checked (the bundled schemas do not know all settings, so this may be a misspelt setting as well).
+	UncheckedSetting ValidationFindingKind = "unchecked-setting"
+
+	// InvalidValue indicates that the value of a setting does not match the type or the allowed values of the setting.
+	InvalidValue ValidationFindingKind = "invalid-value"
+
+	// MissingSchema indicates that there is no schema for the firmware version the document is validated for,
+	// so settings and values were not checked.
+	MissingSchema ValidationFindingKind = "missing-schema"
+)
+
+// ValidationFinding describes a problem found when validating an ATV document.
+type ValidationFinding struct {
+	Severity ValidationSeverity    `json:"severity"` // severity of the finding
+	Kind     ValidationFindingKind `json:"kind"`     // kind of the finding
+	Path     string                `json:"path"`     // full path of the setting or row the finding refers to (empty for the entire document)
+	Position lexer.Position        `json:"position"` // position of the setting or row in the parsed document
+	Message  string                `json:"message"`  // description of the finding
+}
+
+// ValidationFindings is a list of validation findings.
+type ValidationFindings []ValidationFinding
+
+// String returns the finding as a single line string.
+func (finding ValidationFinding) String() string {
+	if finding.Position.Line > 0 {
+		return fmt.Sprintf(
+			"%s: %s (line %d, column %d): %s",
+			finding.Severity, finding.Path, finding.Position.Line, finding.Position.Column, finding.Message)
+	}
+	if len(finding.Path) > 0 {
+		return fmt.Sprintf("%s: %s: %s", finding.Severity, finding.Path, finding.Message)
+	}
+	return fmt.Sprintf("%s: %s", finding.Severity, finding.Message)
+}
+
+// Errors returns the findings with severity ValidationError.
+func (findings ValidationFindings) Errors() ValidationFindings {
+	var errors ValidationFindings
+	for _, finding := range findings {
+		if finding.Severity == ValidationError {
+			errors = append(errors, finding)
+		}
+	}
+	return errors
+}
+
+// OfKind returns the findings of the specified kind.
+func (findings ValidationFindings) OfKind(kind ValidationFindingKind) ValidationFindings {
+	var selected ValidationFindings
+	for _, finding := range findings {
+		if finding.Kind == kind {
+			selected = append(selected, finding)
+		}
+	}
+	return selected
+}
+
+// HasErrors checks whether the findings contain at least one finding with severity ValidationError.
+func (findings ValidationFindings) HasErrors() bool {
+	return len(findings.Errors()) > 0
+}
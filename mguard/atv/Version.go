@@ -2,8 +2,12 @@ package atv
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 )
 
+var versionRegex = regexp.MustCompile(`^([0-9]+)\.([0-9]+)\.([0-9]+)(?:\.(.+))?$`)
+
 // Version represents the version of an ATV document.
 type Version struct {
 	Major  int
@@ -12,12 +16,62 @@ type Version struct {
 	Suffix string
 }
 
+// ParseVersion parses the specified string as a version (format: <major>.<minor>.<patch>[.<suffix>]).
+// The suffix defaults to 'default', if it is omitted.
+func ParseVersion(s string) (Version, error) {
+
+	matches := versionRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return Version{}, fmt.Errorf("'%s' is not a properly formatted version number", s)
+	}
+
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+	version := Version{Major: major, Minor: minor, Patch: patch, Suffix: matches[4]}
+	if len(version.Suffix) == 0 {
+		version.Suffix = "default"
+	}
+
+	return version, nil
+}
+
 // Compare compares the current version with the specified one.
+// The numbers (major, minor, patch) are compared first. Versions with the same numbers, but different suffixes
+// (variants of the same firmware release) are ordered by their suffix with 'default' coming first.
 // Returns -1, if the current version is less than the specified one.
 // Returns 0, if the current version equals the current one.
 // Returns +1, if the current version is greater than the specified one.
 func (version Version) Compare(other Version) int {
 
+	result := version.CompareRelease(other)
+	if result != 0 {
+		return result
+	}
+
+	// same numbers, check suffix ('default' first)
+	if version.Suffix == other.Suffix {
+		return 0
+	}
+	if version.Suffix == "default" {
+		return -1
+	}
+	if other.Suffix == "default" {
+		return 1
+	}
+	if version.Suffix < other.Suffix {
+		return -1
+	}
+	return 1
+}
+
+// CompareRelease compares the numbers (major, minor, patch) of the current version with the numbers of the specified
+// one. The suffix is not taken into account.
+// Returns -1, if the current version is less than the specified one.
+// Returns 0, if the current version equals the current one.
+// Returns +1, if the current version is greater than the specified one.
+func (version Version) CompareRelease(other Version) int {
+
 	// check major version
 	if version.Major < other.Major {
 		return -1
@@ -46,6 +100,11 @@ func (version Version) Compare(other Version) int {
 	return 0
 }
 
+// release returns the version without suffix (identifies the firmware release regardless of its variant).
+func (version Version) release() Version {
+	return Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch}
+}
+
 // String returns the version as a string.
 func (version Version) String() string {
 	s := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
@@ -11,8 +11,9 @@ type dictionary []keyValuePair
 
 // keyValuePair represents a pair of two strings.
 type keyValuePair struct {
-	Key   string `@Ident "="`
-	Value string `@String`
+	Key      string `@Ident "="`
+	Value    string `@String`
+	comments *documentComments
 }
 
 // Add adds a new item to the dictionary.
@@ -51,9 +52,9 @@ func (dict *dictionary) TryGet(key string, value *string) bool {
 func (dict *dictionary) Set(key string, value string) {
 
 	// set value if the item exists already
-	for _, kvp := range *dict {
-		if kvp.Key == key {
-			kvp.Value = value
+	for i := range *dict {
+		if (*dict)[i].Key == key {
+			(*dict)[i].Value = value
 			return
 		}
 	}
@@ -76,6 +77,17 @@ func (dict *dictionary) Remove(key string) bool {
 	return false
 }
 
+// writeDocumentPart writes the key-value-pairs to the specified writer, one per line.
+func (dict *dictionary) writeDocumentPart(writer *strings.Builder, indent int, withComments bool) {
+	for _, kvp := range *dict {
+		if withComments {
+			kvp.comments.writeLeading(writer)
+		}
+		line := fmt.Sprintf("%s%s = %s%s", spacer(indent), kvp.Key, quote(kvp.Value), kvp.comments.lineEnd(withComments))
+		writer.WriteString(line)
+	}
+}
+
 // String gets the string representation of the dictionary.
 func (dict *dictionary) String() string {
 	builder := strings.Builder{}
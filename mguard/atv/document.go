@@ -19,6 +19,11 @@ type documentWriter interface {
 	WriteDocumentPart(writer *strings.Builder, indent int) error
 }
 
+// documentValueWriter is implemented by ATV document setting values to write them after the name of the setting.
+type documentValueWriter interface {
+	writeValue(writer *strings.Builder, indent int, lineEnd string, withComments bool) error
+}
+
 // GetRowReferences is implemented by ATV document setting nodes to return row references recursively.
 type getRowReferences interface {
 	GetRowReferences() []RowRef
@@ -40,8 +45,9 @@ type UUID string
 
 // document represents a mGuard configuration document.
 type document struct {
-	Pos   lexer.Position
-	Nodes []*documentNode `( @@ )*`
+	Pos    lexer.Position
+	Nodes  []*documentNode `( @@ )*`
+	layout *documentLayout
 }
 
 // FromFile reads the specified ATV file from disk.
@@ -76,13 +82,20 @@ func documentFromReader(reader io.Reader) (*document, error) {
 		return nil, err
 	}
 
+	// remember whether the document used CRLF line breaks consistently
+	lineBreaks := strings.Count(docData, "\n")
+	doc.layout.CRLF = lineBreaks > 0 && len(buf)-len(docData) == lineBreaks
+
 	return doc, nil
 }
 
 func (doc *document) parse(data string) error {
 
 	// let the document always end with a new line to avoid handling EOF and EOL separately
-	data += "\n"
+	missingFinalLineBreak := !strings.HasSuffix(data, "\n")
+	if missingFinalLineBreak {
+		data += "\n"
+	}
 
 	// build the parser
 	newDoc := &document{}
@@ -113,6 +126,13 @@ func (doc *document) parse(data string) error {
 			repr.String(*newDoc, repr.Indent("  "), repr.OmitEmpty(true)))
 	*/
 
+	// attach comments to the elements of the document to write them back later on
+	err = newDoc.attachComments(data)
+	if err != nil {
+		return err
+	}
+	newDoc.layout.MissingFinalLineBreak = missingFinalLineBreak
+
 	*doc = *newDoc
 	return nil
 }
@@ -129,8 +149,15 @@ func (doc *document) Dupe() *document {
 		nodesCopy = append(nodesCopy, node.Dupe())
 	}
 
+	var layoutCopy *documentLayout
+	if doc.layout != nil {
+		layout := *doc.layout
+		layoutCopy = &layout
+	}
+
 	return &document{
-		Nodes: nodesCopy,
+		Nodes:  nodesCopy,
+		layout: layoutCopy,
 	}
 }
 
@@ -197,7 +224,7 @@ func (doc *document) RemoveUUID(settingName string) error {
 // GetAccess gets the access modifier of the setting with the specified name.
 func (doc *document) GetAccess(settingName string) (*AccessModifier, error) {
 
-	value, err := doc.GetAttribute(settingName, "uuid")
+	value, err := doc.GetAttribute(settingName, "access")
 	if err != nil || value == nil {
 		return nil, err
 	}
@@ -212,7 +239,7 @@ func (doc *document) GetAccess(settingName string) (*AccessModifier, error) {
 
 // SetAccess sets the access modifier of the setting with the specified name.
 func (doc *document) SetAccess(settingName string, access AccessModifier) error {
-	return doc.SetAttribute(settingName, "access", string(access))
+	return doc.SetAttribute(settingName, "access", access.String())
 }
 
 // RemoveAccess removes the access modifier from the setting with the specified name.
@@ -224,32 +251,11 @@ func (doc *document) RemoveAccess(settingName string) error {
 func (doc *document) GetAttribute(settingName, attributeName string) (*string, error) {
 
 	setting, err := doc.GetSetting(settingName)
-	if err != nil {
+	if err != nil || setting == nil {
 		return nil, err
 	}
 
-	if setting != nil {
-
-		var dict *dictionary
-		if setting.ValueWithMetadata != nil {
-			dict = &setting.ValueWithMetadata.Data
-		} else if setting.TableValue != nil {
-			dict = &setting.TableValue.Attributes
-		}
-
-		if dict != nil {
-			var value string
-			if dict.TryGet(attributeName, &value) {
-				return &value, nil
-			}
-
-			return nil, nil
-		}
-
-		panic("Unhandled value type")
-	}
-
-	return nil, nil
+	return setting.getAttribute(attributeName), nil
 }
 
 // SetAttribute sets the specified attribute associated with the specified setting.
@@ -260,34 +266,12 @@ func (doc *document) SetAttribute(settingName, attributeName, attributeValue str
 		return err
 	}
 
-	if setting != nil {
-		if setting.SimpleValue != nil {
-
-			items := dictionary{
-				keyValuePair{attributeName, string(attributeValue)},
-				keyValuePair{"value", setting.SimpleValue.Value},
-			}
-			setting.ValueWithMetadata = &documentValueWithMetadata{Data: items}
-			setting.SimpleValue = nil
-			return nil
-		}
-
-		var dict *dictionary
-		if setting.ValueWithMetadata != nil {
-			dict = &setting.ValueWithMetadata.Data
-		} else if setting.TableValue != nil {
-			dict = &setting.TableValue.Attributes
-		}
-
-		if dict != nil {
-			dict.Set(attributeName, attributeValue)
-			return nil
-		}
-
-		panic("Unhandled value type")
+	if setting == nil {
+		return fmt.Errorf("Setting '%s' does not exist", settingName)
 	}
 
-	return fmt.Errorf("Setting '%s' does not exist", settingName)
+	setting.setAttribute(attributeName, attributeValue)
+	return nil
 }
 
 // RemoveAttribute removes an attribute from a setting with the specified name.
@@ -298,34 +282,12 @@ func (doc *document) RemoveAttribute(settingName, attributeName string) error {
 		return err
 	}
 
-	if setting != nil {
-
-		if setting.SimpleValue != nil {
-			return nil
-		}
-
-		if setting.ValueWithMetadata != nil {
-
-			dict := &setting.ValueWithMetadata.Data
-			if dict.Remove(attributeName) {
-				if len(*dict) == 1 && (*dict)[0].Key == "value" {
-					setting.SimpleValue = &documentSimpleValue{Value: (*dict)[0].Value}
-					setting.ValueWithMetadata = nil
-				}
-			}
-			return nil
-
-		} else if setting.TableValue != nil {
-
-			setting.TableValue.Attributes.Remove(attributeName)
-			return nil
-
-		}
-
-		panic("Unhandled value type")
+	if setting == nil {
+		return fmt.Errorf("Setting '%s' does not exist", settingName)
 	}
 
-	return fmt.Errorf("Setting '%s' does not exist", settingName)
+	setting.removeAttribute(attributeName)
+	return nil
 }
 
 // GetRowReferences returns all row references recursively.
@@ -435,92 +397,85 @@ func (doc *document) SetSetting(setting *documentSetting) error {
 	return nil
 }
 
-// MergeTableSetting replaces existing rows (same row id), appends rows that do not exist to the existing table
-// or adds a new table value, if the table does not exist at all.
-func (doc *document) MergeTableSetting(setting *documentSetting) error {
-
-	if setting.TableValue == nil {
-		return fmt.Errorf("Specified setting '%s' is not a table value", setting.Name)
-	}
-
-	copy := setting.Dupe()
-	for _, node := range doc.Nodes {
-		if node.Setting != nil {
-			if node.Setting.Name == copy.Name {
-
-				// found setting with the specified name
-				// => abort, if the setting is not a table value
-				if node.Setting.TableValue == nil {
-					return fmt.Errorf("Setting '%s' in the document is not a table value", copy.Name)
-				}
-
-				// update table
-			update_loop:
-				for _, rowToSet := range copy.TableValue.Rows {
-
-					// update existing row, if possible
-					for i, existingRow := range node.Setting.TableValue.Rows {
-						if existingRow.HasSameID(rowToSet) {
-							x := existingRow.String()
-							y := rowToSet.String()
-							log.Debugf("Table value '%s' contains row with id '%s'. Row changed\n--from--\n%s\n--to--\n%s", setting.Name, *rowToSet.RowID, x, y)
-							node.Setting.TableValue.Rows[i] = rowToSet
-							continue update_loop
-						}
-					}
-
-					// insert row, if there is no row with the same id, yet
-					log.Debugf("Table value '%s' does not contain row to set, yet. Appending\n%s", setting.Name, rowToSet.String())
-					node.Setting.TableValue.Rows = append(node.Setting.TableValue.Rows, rowToSet)
-				}
-
-				return nil
-			}
-		}
-	}
-
-	// specified setting was not found
-	// => add it at the end
-	newNode := &documentNode{Setting: copy}
-	doc.Nodes = append(doc.Nodes, newNode)
-	return nil
-}
-
 // Merge merges all settings of the specified ATV document into the current one.
-func (doc *document) Merge(other *document) (*document, error) {
+func (doc *document) Merge(other *document) (*document, *MergeReport, error) {
 	return doc.MergeSelectively(other, nil)
 }
 
 // Merge merges the configured settings of the specified ATV document into the current one.
+// The merge configuration can select top-level settings, settings nested in table rows and entire table rows.
+// Row indices in the merge configuration refer to the specified document and may be wildcards ('*').
+// Excluded settings are skipped. The merge strategy configured for a setting determines how it is merged.
+// The access modifiers of the settings in the current document are respected: settings that must not be overwritten
+// are kept, settings that may be appended to are only extended by new rows and settings that must be overwritten
+// must be supplied by the specified document. The returned report lists all access violations. Merging fails, if
+// a setting that must be overwritten was not supplied.
 // config : The merge configuration (nil merges all settings)
-func (doc *document) MergeSelectively(other *document, config *MergeConfiguration) (*document, error) {
+func (doc *document) MergeSelectively(other *document, config *MergeConfiguration) (*document, *MergeReport, error) {
 
 	if doc == nil {
-		return nil, ErrNilReceiver
+		return nil, nil, ErrNilReceiver
 	}
 
+	report := &MergeReport{}
+	supplied := map[string]bool{}
 	copy := doc.Dupe()
 	for _, otherNode := range other.Nodes {
 		if otherNode.Setting != nil {
-			otherSettingPath, _ := parseDocumentSettingPath(otherNode.Setting.Name) // works for top-level setting only!
-			if config == nil || config.ShouldMergeSetting(otherSettingPath) {
-				log.Infof("Merging setting '%s'...", otherNode.Setting.Name)
-				err := otherNode.Setting.mergeInto(copy)
+			otherSettingPath, _ := parseDocumentSettingPath(otherNode.Setting.Name)
+			if config.isExcluded(otherSettingPath) {
+				log.Debugf("Setting '%s' is excluded from merging. Skipping...", otherNode.Setting.Name)
+			} else if config == nil || config.ShouldMergeSetting(otherSettingPath) {
+				strategy, column := config.getStrategy(otherSettingPath)
+				log.Infof("Merging setting '%s' (strategy: %s)...", otherNode.Setting.Name, strategy)
+				err := otherNode.Setting.mergeInto(copy, strategy, column, config, report)
 				if err != nil {
-					return nil, err
+					return nil, report, err
 				}
+				supplied[otherNode.Setting.Name] = true
+			} else if nestedPaths := config.nestedPaths(otherSettingPath); len(nestedPaths) > 0 {
+				for _, path := range nestedPaths {
+					err := otherNode.Setting.mergePathInto(copy, path, config, report)
+					if err != nil {
+						return nil, report, err
+					}
+				}
+				supplied[otherNode.Setting.Name] = true
 			} else {
 				log.Debugf("Setting '%s' is not in merge list. Skipping...", otherNode.Setting.Name)
 			}
 		}
 	}
 
-	return copy, nil
+	// ensure that all settings that must be overwritten were supplied
+	for _, node := range doc.Nodes {
+		if node.Setting != nil && !supplied[node.Setting.Name] {
+			access, err := node.Setting.getAccess()
+			if err != nil {
+				return nil, report, err
+			}
+			if access == MustOverwrite {
+				report.addViolation(node.Setting.Name, access, true, "The setting must be overwritten, but it was not supplied")
+			}
+		}
+	}
+
+	if err := report.fatalError(); err != nil {
+		return nil, report, err
+	}
+
+	return copy, report, nil
 }
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
+// A document that was read is written along with its comments and keeps the formatting of all elements that
+// were not modified. Other documents are written with opening and closing comments.
 func (doc *document) WriteDocumentPart(writer *strings.Builder, indent int) error {
 
+	if doc.layout != nil {
+		return doc.writeWithLayout(writer, indent)
+	}
+
 	// write opening comment
 	line := "// mGuard Configuration Profile\n"
 	_, err := writer.WriteString(line)
@@ -555,6 +510,43 @@ func (doc *document) WriteDocumentPart(writer *strings.Builder, indent int) erro
 	return nil
 }
 
+// writeWithLayout writes the ATV document keeping the layout of the document it was read from.
+func (doc *document) writeWithLayout(writer *strings.Builder, indent int) error {
+
+	builder := strings.Builder{}
+	var lastNodeType reflect.Type
+	for _, node := range doc.Nodes {
+
+		// insert an extra newline to separate added nodes from nodes of different types
+		// (nodes that were read carry the blank lines preceding them)
+		nodeType := reflect.TypeOf(node.actual())
+		if lastNodeType != nil && nodeType != lastNodeType && node.comments() == nil {
+			builder.WriteString("\n")
+		}
+		lastNodeType = nodeType
+
+		// write node
+		err := node.WriteDocumentPart(&builder, indent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write text following the last node (usually the closing comment)
+	builder.WriteString(doc.layout.Trailing)
+
+	content := builder.String()
+	if doc.layout.MissingFinalLineBreak {
+		content = strings.TrimSuffix(content, "\n")
+	}
+	if doc.layout.CRLF {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+
+	_, err := writer.WriteString(content)
+	return err
+}
+
 // GetSetting finds the setting with the specified name.
 // If the setting does not exist, nil is returned.
 func (doc *document) GetSetting(settingName string) (*documentSetting, error) {
@@ -573,9 +565,20 @@ func (doc *document) GetSetting(settingName string) (*documentSetting, error) {
 }
 
 // getSetting gets the setting at the specified path.
-// If the setting does not exist, nil is returned.
+// If the path selects multiple settings, the first one is returned. If the setting does not exist, nil is returned.
 func (doc *document) getSetting(path documentSettingPath) (*documentSetting, error) {
 
+	settings, err := doc.findSettings(path)
+	if err != nil || len(settings) == 0 {
+		return nil, err
+	}
+
+	return settings[0], nil
+}
+
+// findSettings returns all settings selected by the specified path.
+func (doc *document) findSettings(path documentSettingPath) ([]*documentSetting, error) {
+
 	if len(path) == 0 {
 		return nil, fmt.Errorf("Path is empty")
 	}
@@ -587,7 +590,7 @@ func (doc *document) getSetting(path documentSettingPath) (*documentSetting, err
 	for _, node := range doc.Nodes {
 		if node.Setting != nil {
 			if node.Setting.Name == *path[0].name {
-				return node.Setting.getSetting(path, 1)
+				return node.Setting.findSettings(path, 1)
 			}
 		}
 	}
@@ -596,6 +599,26 @@ func (doc *document) getSetting(path documentSettingPath) (*documentSetting, err
 	return nil, nil
 }
 
+// findRows returns all table rows selected by the specified path (the last token must select rows).
+func (doc *document) findRows(path documentSettingPath) ([]*documentTableRow, error) {
+
+	if len(path) < 2 || !path[len(path)-1].isRowSelector() {
+		return nil, fmt.Errorf("The path '%s' does not address table rows", path)
+	}
+
+	tables, err := doc.findSettings(path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*documentTableRow
+	for _, table := range tables {
+		rows = append(rows, table.findRows(path[len(path)-1])...)
+	}
+
+	return rows, nil
+}
+
 // SetSimpleValueSetting sets the setting with the specified name to a simple string value.
 func (doc *document) SetSimpleValueSetting(settingName string, value string) error {
 
@@ -623,27 +646,34 @@ func (doc *document) setSimpleValueSetting(path documentSettingPath, value strin
 		return fmt.Errorf("The first path token '%s' is not a setting name", path[0])
 	}
 
-	// try to get existing setting
-	setting, err := doc.getSetting(path)
+	// try to get existing settings
+	settings, err := doc.findSettings(path)
 	if err != nil {
 		return err
 	}
 
 	// create the path to the setting, if the setting does not exist, yet
-	if setting == nil {
-		setting, err = doc.createSettingPlaceholder(path)
+	// (not possible, if the path contains wildcards or query expressions)
+	if len(settings) == 0 {
+		if path.isQuery() {
+			return fmt.Errorf("The path '%s' does not select any setting", path)
+		}
+		setting, err := doc.createSettingPlaceholder(path)
 		if err != nil {
 			return err
 		}
+		settings = append(settings, setting)
 	}
 
-	// set setting value
-	setting.ClearValue()
-	setting.SimpleValue = &documentSimpleValue{Value: value}
+	// set setting values (keeps attributes)
+	for _, setting := range settings {
+		setting.setSimpleValue(value)
+	}
 	return nil
 }
 
 // RemoveSetting removes the setting with the specified name.
+// If the path ends with a row index, the table row is removed.
 // If the setting does not exist, no error is signalled.
 func (doc *document) RemoveSetting(settingName string) error {
 
@@ -655,7 +685,7 @@ func (doc *document) RemoveSetting(settingName string) error {
 	return doc.removeSetting(path)
 }
 
-// removeSetting removes the setting at the specified path.
+// removeSetting removes the settings or table rows selected by the specified path.
 // If the setting does not exist, no error is signalled.
 func (doc *document) removeSetting(path documentSettingPath) error {
 
@@ -667,22 +697,44 @@ func (doc *document) removeSetting(path documentSettingPath) error {
 		return fmt.Errorf("The first path token '%s' is not a setting name", path[0])
 	}
 
-	for i, node := range doc.Nodes {
-		if node.Setting != nil {
-			if node.Setting.Name == *path[0].name {
-				if len(path) == 1 {
-					// top-level item => just remove it
-					doc.Nodes = append(doc.Nodes[:i], doc.Nodes[i+1:]...)
-					return nil
-				} else {
-					// nested setting => dive deeper
-					return node.Setting.removeSetting(path, 1)
-				}
+	// top-level setting => just remove it
+	if len(path) == 1 {
+		for i, node := range doc.Nodes {
+			if node.Setting != nil && node.Setting.Name == *path[0].name {
+				doc.Nodes = append(doc.Nodes[:i], doc.Nodes[i+1:]...)
+				return nil
+			}
+		}
+		return nil
+	}
+
+	// table rows => remove the rows from the tables
+	last := path[len(path)-1]
+	if last.isRowSelector() {
+		tables, err := doc.findSettings(path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			table.removeRows(last)
+		}
+		return nil
+	}
+
+	// nested setting => remove the setting from the rows
+	rows, err := doc.findRows(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i, item := range row.Items {
+			if item.Name == *last.name {
+				row.Items = append(row.Items[:i], row.Items[i+1:]...)
+				break
 			}
 		}
 	}
 
-	// specified setting does not exist
 	return nil
 }
 
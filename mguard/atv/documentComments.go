@@ -0,0 +1,391 @@
+package atv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// documentComments holds comments attached to an element of an ATV document.
+type documentComments struct {
+	Leading  []string // lines preceding the element (comments and blank lines, without line breaks)
+	Trailing string   // text following the element on the same line (usually a comment)
+}
+
+// writeLeading writes the lines preceding the element to the specified writer.
+func (comments *documentComments) writeLeading(writer *strings.Builder) {
+
+	if comments == nil {
+		return
+	}
+
+	for _, line := range comments.Leading {
+		writer.WriteString(line)
+		writer.WriteString("\n")
+	}
+}
+
+// lineEnd returns the text terminating the line of the element (trailing comment and line break).
+func (comments *documentComments) lineEnd(withComments bool) string {
+
+	if comments == nil || !withComments {
+		return "\n"
+	}
+
+	return comments.Trailing + "\n"
+}
+
+// documentSource holds the original text of a top-level element of an ATV document.
+// The original text is written instead of the formatted element as long as the element is not modified.
+type documentSource struct {
+	Formatted string // the formatted element at the time it was read
+	Text      string // the original text of the element (including leading comments)
+}
+
+// write writes the original text of the element, if the element was not modified.
+// Otherwise the formatted element is written.
+func (source *documentSource) write(writer *strings.Builder, formatted string) {
+
+	if source != nil && source.Formatted == formatted {
+		writer.WriteString(source.Text)
+		return
+	}
+
+	writer.WriteString(formatted)
+}
+
+// documentLayout holds the layout of an ATV document that was read.
+type documentLayout struct {
+	Trailing              string // text following the last element (comments and blank lines)
+	CRLF                  bool   // true, if the document used CRLF line breaks
+	MissingFinalLineBreak bool   // true, if the last line of the document was not terminated with a line break
+}
+
+// triviaToken is a significant token of an ATV document along with the whitespace, comments and
+// line breaks preceding it.
+type triviaToken struct {
+	Pos    lexer.Position
+	Value  string
+	Offset int    // offset of the token in the document
+	Trivia string // whitespace, comments and line breaks preceding the token
+}
+
+// documentScanner attaches comments to the elements of a parsed ATV document.
+type documentScanner struct {
+	tokens []triviaToken // the last token is a pseudo token at the end of the document
+	next   int
+}
+
+// attachComments scans the specified data the document was parsed from and attaches comments
+// and the original text to the elements of the document.
+func (doc *document) attachComments(data string) error {
+
+	scanner, err := newDocumentScanner(data)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range doc.Nodes {
+
+		first := scanner.next
+		if node.Pragma != nil {
+			index, err := scanner.expect("#")
+			if err != nil {
+				return err
+			}
+			node.Pragma.comments = &documentComments{
+				Leading:  scanner.leading(index),
+				Trailing: scanner.trailing(index),
+			}
+		} else if node.Setting != nil {
+			err := scanner.scanSetting(node.Setting)
+			if err != nil {
+				return err
+			}
+		}
+
+		// keep the original text of the top-level element, if it occupies entire lines
+		start := scanner.lineStart(first)
+		end := scanner.lineEnd(scanner.next - 1)
+		if start >= 0 && end >= 0 {
+			source := &documentSource{Text: data[start:end]}
+			builder := strings.Builder{}
+			if node.Pragma != nil {
+				node.Pragma.writePart(&builder, 0)
+				node.Pragma.source = source
+			} else {
+				node.Setting.writePart(&builder, 0, true)
+				node.Setting.source = source
+			}
+			source.Formatted = builder.String()
+		}
+	}
+
+	// keep the text following the last element
+	layout := &documentLayout{}
+	last := scanner.tokens[len(scanner.tokens)-1].Trivia
+	if len(doc.Nodes) == 0 {
+		layout.Trailing = last
+	} else if index := strings.Index(last, "\n"); index >= 0 {
+		layout.Trailing = last[index+1:]
+	}
+	doc.layout = layout
+
+	return nil
+}
+
+// newDocumentScanner splits the specified data into significant tokens and the trivia preceding them.
+func newDocumentScanner(data string) (*documentScanner, error) {
+
+	lex, err := lexerDefinition.Lex(strings.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := &documentScanner{}
+	symbols := lexerDefinition.Symbols()
+	insignificant := map[rune]bool{
+		symbols["Whitespace"]: true,
+		symbols["Comment"]:    true,
+		symbols["EOL"]:        true,
+	}
+
+	offset := 0
+	trivia := strings.Builder{}
+	for {
+		token, err := lex.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if token.EOF() {
+			scanner.tokens = append(scanner.tokens, triviaToken{Pos: token.Pos, Offset: offset, Trivia: trivia.String()})
+			break
+		}
+
+		if insignificant[token.Type] {
+			trivia.WriteString(token.Value)
+		} else {
+			scanner.tokens = append(scanner.tokens, triviaToken{Pos: token.Pos, Value: token.Value, Offset: offset, Trivia: trivia.String()})
+			trivia.Reset()
+		}
+		offset += len(token.Value)
+	}
+
+	if offset != len(data) {
+		return nil, fmt.Errorf("Scanning the document failed (scanned %d of %d bytes)", offset, len(data))
+	}
+
+	return scanner, nil
+}
+
+// expect consumes the next token and checks whether it is the specified text.
+// Pragmas and strings are checked by their first character ('#' and '"') only.
+func (scanner *documentScanner) expect(text string) (int, error) {
+
+	index := scanner.next
+	token := scanner.tokens[index]
+	matches := token.Value == text || ((text == "#" || text == `"`) && strings.HasPrefix(token.Value, text))
+	if index >= len(scanner.tokens)-1 || !matches {
+		return 0, fmt.Errorf("Attaching comments failed at line %d, column %d (expected '%s', got '%s')", token.Pos.Line, token.Pos.Column, text, token.Value)
+	}
+
+	scanner.next++
+	return index, nil
+}
+
+// scanSetting attaches comments to the specified setting and its value.
+func (scanner *documentScanner) scanSetting(setting *documentSetting) error {
+
+	name, err := scanner.expect(setting.Name)
+	if err == nil {
+		_, err = scanner.expect("=")
+	}
+	if err != nil {
+		return err
+	}
+
+	comments := &documentComments{Leading: scanner.leading(name)}
+	setting.comments = comments
+
+	if setting.SimpleValue != nil {
+		index, err := scanner.expect(`"`)
+		if err != nil {
+			return err
+		}
+		comments.Trailing = scanner.trailing(index)
+		return nil
+	}
+
+	var attributes dictionary
+	var rows []*documentTableRow
+	var closing **documentComments
+	if setting.TableValue != nil {
+		attributes = setting.TableValue.Attributes
+		rows = setting.TableValue.Rows
+		closing = &setting.TableValue.closing
+	} else if setting.ValueWithMetadata != nil {
+		attributes = setting.ValueWithMetadata.Data
+		closing = &setting.ValueWithMetadata.closing
+	} else {
+		panic("Unhandled setting value")
+	}
+
+	index, err := scanner.expect("{")
+	if err != nil {
+		return err
+	}
+	comments.Trailing = scanner.trailing(index)
+
+	err = scanner.scanDictionary(attributes)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		err := scanner.scanTableRow(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	index, err = scanner.expect("}")
+	if err != nil {
+		return err
+	}
+	*closing = scanner.comments(index)
+
+	return nil
+}
+
+// scanDictionary attaches comments to the key-value-pairs in the specified dictionary.
+func (scanner *documentScanner) scanDictionary(dict dictionary) error {
+
+	for i := range dict {
+		key, err := scanner.expect(dict[i].Key)
+		if err == nil {
+			_, err = scanner.expect("=")
+		}
+		if err != nil {
+			return err
+		}
+		value, err := scanner.expect(`"`)
+		if err != nil {
+			return err
+		}
+		dict[i].comments = &documentComments{
+			Leading:  scanner.leading(key),
+			Trailing: scanner.trailing(value),
+		}
+	}
+
+	return nil
+}
+
+// scanTableRow attaches comments to the specified table row and its items.
+func (scanner *documentScanner) scanTableRow(row *documentTableRow) error {
+
+	index, err := scanner.expect("{")
+	if err != nil {
+		return err
+	}
+	row.comments = scanner.comments(index)
+
+	if row.RowID != nil {
+		first := scanner.next
+		for _, text := range []string{"{", "rid", "=", `"`, "}"} {
+			index, err = scanner.expect(text)
+			if err != nil {
+				return err
+			}
+		}
+		row.ridComments = &documentComments{
+			Leading:  scanner.leading(first),
+			Trailing: scanner.trailing(index),
+		}
+	}
+
+	for _, item := range row.Items {
+		err := scanner.scanSetting(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	index, err = scanner.expect("}")
+	if err != nil {
+		return err
+	}
+	row.closing = scanner.comments(index)
+
+	return nil
+}
+
+// comments returns the comments attached to the token with the specified index.
+func (scanner *documentScanner) comments(index int) *documentComments {
+	return &documentComments{
+		Leading:  scanner.leading(index),
+		Trailing: scanner.trailing(index),
+	}
+}
+
+// leading returns the lines preceding the line of the token with the specified index.
+func (scanner *documentScanner) leading(index int) []string {
+
+	lines := strings.Split(scanner.tokens[index].Trivia, "\n")
+
+	// the first line is the end of the line of the preceding token
+	// (the first token of the document does not have a preceding token)
+	if index > 0 {
+		lines = lines[1:]
+	}
+
+	// the last line is the indentation of the token itself
+	if len(lines) <= 1 {
+		return nil
+	}
+
+	return lines[:len(lines)-1]
+}
+
+// trailing returns the text following the token with the specified index on the same line.
+func (scanner *documentScanner) trailing(index int) string {
+
+	trivia := scanner.tokens[index+1].Trivia
+	if end := strings.Index(trivia, "\n"); end >= 0 {
+		return trivia[:end]
+	}
+
+	return ""
+}
+
+// lineStart returns the offset of the first line preceding the token with the specified index that belongs
+// to the token (-1, if the token does not start a line).
+func (scanner *documentScanner) lineStart(index int) int {
+
+	token := scanner.tokens[index]
+	start := token.Offset - len(token.Trivia)
+	if index == 0 {
+		return start
+	}
+
+	if i := strings.Index(token.Trivia, "\n"); i >= 0 {
+		return start + i + 1
+	}
+
+	return -1
+}
+
+// lineEnd returns the offset following the line break that terminates the line of the token with the specified
+// index (-1, if the token does not end a line).
+func (scanner *documentScanner) lineEnd(index int) int {
+
+	token := scanner.tokens[index+1]
+	start := token.Offset - len(token.Trivia)
+	if i := strings.Index(token.Trivia, "\n"); i >= 0 {
+		return start + i + 1
+	}
+
+	return -1
+}
@@ -0,0 +1,284 @@
+package atv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff compares the current document with the specified one and returns the settings that differ.
+// Table rows are matched by their row id, if both rows have one. Rows without a row id are matched by their
+// position among the rows without a row id. UUIDs associated with settings are not taken into account.
+// The paths of rows with a row id select the row by its row id (e.g. FW_INCOMING[rid="abc"]), so they refer to the
+// same row in both documents. The paths of rows without a row id contain the position of the row among the rows without
+// a row id, which is the same in both documents (and the index of the row, if the rows of the table do not have row ids).
+func (doc *document) Diff(other *document) ([]Difference, error) {
+
+	var diffs []Difference
+
+	// compare pragmas
+	for _, node := range doc.Nodes {
+		if node.Pragma != nil {
+			path := "#" + node.Pragma.Name
+			otherPragma, _ := other.GetPragma(node.Pragma.Name)
+			if otherPragma == nil {
+				diffs = append(diffs, Difference{Kind: SettingRemoved, Path: path, OldValue: quote(node.Pragma.Value)})
+			} else if otherPragma.Value != node.Pragma.Value {
+				diffs = append(diffs, Difference{Kind: SettingChanged, Path: path, OldValue: quote(node.Pragma.Value), NewValue: quote(otherPragma.Value)})
+			}
+		}
+	}
+	for _, node := range other.Nodes {
+		if node.Pragma != nil {
+			pragma, _ := doc.GetPragma(node.Pragma.Name)
+			if pragma == nil {
+				diffs = append(diffs, Difference{Kind: SettingAdded, Path: "#" + node.Pragma.Name, NewValue: quote(node.Pragma.Value)})
+			}
+		}
+	}
+
+	// compare settings
+	settingDiffs, err := diffSettingLists("", doc.settings(), other.settings())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(diffs, settingDiffs...), nil
+}
+
+// settings returns the top-level settings of the document.
+func (doc *document) settings() []*documentSetting {
+	var settings []*documentSetting
+	for _, node := range doc.Nodes {
+		if node.Setting != nil {
+			settings = append(settings, node.Setting)
+		}
+	}
+	return settings
+}
+
+// diffSettingLists compares two lists of settings (top-level settings or the settings within a table row).
+func diffSettingLists(prefix string, settings []*documentSetting, otherSettings []*documentSetting) ([]Difference, error) {
+
+	var diffs []Difference
+
+	for _, setting := range settings {
+		path := prefix + setting.Name
+		otherSetting := findSettingByName(otherSettings, setting.Name)
+		if otherSetting == nil {
+			value, err := setting.diffValue(path)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, Difference{Kind: SettingRemoved, Path: path, OldValue: value})
+		} else {
+			settingDiffs, err := diffSettings(path, setting, otherSetting)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, settingDiffs...)
+		}
+	}
+
+	for _, otherSetting := range otherSettings {
+		if findSettingByName(settings, otherSetting.Name) == nil {
+			path := prefix + otherSetting.Name
+			value, err := otherSetting.diffValue(path)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, Difference{Kind: SettingAdded, Path: path, NewValue: value})
+		}
+	}
+
+	return diffs, nil
+}
+
+// diffSettings compares two settings with the same name.
+func diffSettings(path string, setting *documentSetting, otherSetting *documentSetting) ([]Difference, error) {
+
+	// compare tables row by row
+	if setting.TableValue != nil && otherSetting.TableValue != nil {
+		return diffTables(path, setting.TableValue, otherSetting.TableValue)
+	}
+
+	// compare simple values (or values of different types)
+	value, err := setting.diffValue(path)
+	if err != nil {
+		return nil, err
+	}
+	otherValue, err := otherSetting.diffValue(path)
+	if err != nil {
+		return nil, err
+	}
+	if value != otherValue {
+		return []Difference{{Kind: SettingChanged, Path: path, OldValue: value, NewValue: otherValue}}, nil
+	}
+
+	return nil, nil
+}
+
+// diffTables compares two table values.
+func diffTables(path string, table *documentTableValue, otherTable *documentTableValue) ([]Difference, error) {
+
+	var diffs []Difference
+
+	// compare table attributes
+	attributes := table.Attributes.diffString()
+	otherAttributes := otherTable.Attributes.diffString()
+	if attributes != otherAttributes {
+		diffs = append(diffs, Difference{Kind: SettingChanged, Path: path, OldValue: attributes, NewValue: otherAttributes})
+	}
+
+	// match rows and compare matching rows setting by setting
+	rowMapping := matchTableRows(table.Rows, otherTable.Rows)
+	rowPaths := diffRowPaths(path, table.Rows)
+	otherRowMatched := make([]bool, len(otherTable.Rows))
+	for i, row := range table.Rows {
+		j := rowMapping[i]
+		if j < 0 {
+			diffs = append(diffs, Difference{Kind: SettingRemoved, Path: rowPaths[i], OldValue: row.String()})
+			continue
+		}
+		otherRowMatched[j] = true
+		rowDiffs, err := diffSettingLists(rowPaths[i]+".", row.Items, otherTable.Rows[j].Items)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, rowDiffs...)
+	}
+
+	// rows that exist in the other table only
+	otherRowPaths := diffRowPaths(path, otherTable.Rows)
+	for j, otherRow := range otherTable.Rows {
+		if !otherRowMatched[j] {
+			diffs = append(diffs, Difference{Kind: SettingAdded, Path: otherRowPaths[j], NewValue: otherRow.String()})
+		}
+	}
+
+	return diffs, nil
+}
+
+// diffRowPaths returns the paths of the specified rows of the table at the specified path as used when comparing
+// documents. Rows with a row id are selected by their row id, rows without a row id by their position among the rows
+// without a row id (rows are matched the same way, see matchTableRows).
+func diffRowPaths(path string, rows []*documentTableRow) []string {
+
+	paths := make([]string, len(rows))
+	position := 0
+	for i, row := range rows {
+		if row.RowID != nil {
+			paths[i] = fmt.Sprintf("%s[rid=%s]", path, quote(string(*row.RowID)))
+			continue
+		}
+		paths[i] = fmt.Sprintf("%s.%d", path, position)
+		position++
+	}
+
+	return paths
+}
+
+// matchTableRows determines which rows of the first table correspond to the rows of the second table.
+// The returned slice contains the index of the matching row in the second table for every row of the
+// first table (-1, if there is no matching row).
+func matchTableRows(rows []*documentTableRow, otherRows []*documentTableRow) []int {
+
+	mapping := make([]int, len(rows))
+	otherRowMatched := make([]bool, len(otherRows))
+
+	// match rows with a row id
+	for i, row := range rows {
+		mapping[i] = -1
+		if row.RowID == nil {
+			continue
+		}
+		for j, otherRow := range otherRows {
+			if !otherRowMatched[j] && otherRow.RowID != nil && *otherRow.RowID == *row.RowID {
+				mapping[i] = j
+				otherRowMatched[j] = true
+				break
+			}
+		}
+	}
+
+	// match rows without a row id by their position among the rows without a row id
+	j := 0
+	for i, row := range rows {
+		if row.RowID != nil {
+			continue
+		}
+		for j < len(otherRows) && otherRows[j].RowID != nil {
+			j++
+		}
+		if j < len(otherRows) {
+			mapping[i] = j
+			otherRowMatched[j] = true
+			j++
+		}
+	}
+
+	return mapping
+}
+
+// findSettingByName returns the setting with the specified name from the specified list of settings.
+// If the setting does not exist, nil is returned.
+func findSettingByName(settings []*documentSetting, name string) *documentSetting {
+	for _, setting := range settings {
+		if setting.Name == name {
+			return setting
+		}
+	}
+	return nil
+}
+
+// diffValue returns the value of the setting at the specified path as used when comparing documents (UUIDs are
+// ignored).
+func (setting *documentSetting) diffValue(path string) (string, error) {
+
+	if setting.SimpleValue != nil {
+		return quote(setting.SimpleValue.Value), nil
+	}
+
+	// the parser cannot distinguish a value with metadata from a table without rows
+	// => handle both the same way
+	var metadata *dictionary
+	if setting.ValueWithMetadata != nil {
+		metadata = &setting.ValueWithMetadata.Data
+	} else if setting.TableValue != nil && len(setting.TableValue.Rows) == 0 {
+		metadata = &setting.TableValue.Attributes
+	}
+
+	if metadata != nil {
+		data := metadata.withoutUUID()
+		if len(data) == 1 && data[0].Key == "value" {
+			return quote(data[0].Value), nil
+		}
+		return data.diffString(), nil
+	}
+
+	if setting.TableValue != nil {
+		return setting.TableValue.String(), nil
+	}
+
+	return "", fmt.Errorf("The setting '%s' has a value of an unknown type", path)
+}
+
+// withoutUUID returns a copy of the dictionary without the 'uuid' item.
+func (dict *dictionary) withoutUUID() dictionary {
+	var result dictionary
+	for _, kvp := range *dict {
+		if kvp.Key != "uuid" {
+			result = append(result, kvp)
+		}
+	}
+	return result
+}
+
+// diffString returns the dictionary as a single line string as used when comparing documents (UUIDs are ignored).
+func (dict *dictionary) diffString() string {
+	var items []string
+	withoutUUID := dict.withoutUUID()
+	for _, kvp := range withoutUUID {
+		items = append(items, fmt.Sprintf("%s = %s", kvp.Key, quote(kvp.Value)))
+	}
+	return "{ " + strings.Join(items, ", ") + " }"
+}
@@ -0,0 +1,497 @@
+package atv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var pragmaNameRegex = regexp.MustCompile(`^[A-Za-z]+$`)
+var identifierRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._]*$`)
+
+// interchangeDocument represents an ATV document in a form that can be serialized to JSON and YAML.
+// The nodes (pragmas and settings) are kept in the order they appear in the ATV document. The lines following the
+// last node (usually the closing comment) are kept in 'trailing'.
+type interchangeDocument struct {
+	Nodes    []*interchangeNode `json:"nodes" yaml:"nodes"`
+	Trailing []string           `json:"trailing,omitempty" yaml:"trailing,omitempty"`
+}
+
+// interchangeNode represents a pragma or a setting in an interchange document.
+// Pragmas have a name ('pragma') and a value. Settings have a name ('setting') and either a simple value ('value') or
+// attributes (e.g. 'value', 'rowref', 'uuid' and 'access') and/or table rows. The comments attached to the node, its
+// attributes and the closing brace of its table are kept in 'comments', 'attribute_comments' and 'closing'.
+type interchangeNode struct {
+	Pragma            *string                         `json:"pragma,omitempty" yaml:"pragma,omitempty"`
+	Setting           *string                         `json:"setting,omitempty" yaml:"setting,omitempty"`
+	Value             *string                         `json:"value,omitempty" yaml:"value,omitempty"`
+	Attributes        interchangeAttributes           `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Rows              *[]*interchangeTableRow         `json:"rows,omitempty" yaml:"rows,omitempty"`
+	Comments          *interchangeComments            `json:"comments,omitempty" yaml:"comments,omitempty"`
+	AttributeComments map[string]*interchangeComments `json:"attribute_comments,omitempty" yaml:"attribute_comments,omitempty"`
+	Closing           *interchangeComments            `json:"closing,omitempty" yaml:"closing,omitempty"`
+}
+
+// interchangeTableRow represents a table row in an interchange document.
+// The comments attached to the opening brace, the row id and the closing brace of the row are kept in 'comments',
+// 'rid_comments' and 'closing'.
+type interchangeTableRow struct {
+	RowID       *string              `json:"rid,omitempty" yaml:"rid,omitempty"`
+	Settings    []*interchangeNode   `json:"settings" yaml:"settings"`
+	Comments    *interchangeComments `json:"comments,omitempty" yaml:"comments,omitempty"`
+	RidComments *interchangeComments `json:"rid_comments,omitempty" yaml:"rid_comments,omitempty"`
+	Closing     *interchangeComments `json:"closing,omitempty" yaml:"closing,omitempty"`
+}
+
+// interchangeComments represents the comments attached to an element in an interchange document.
+type interchangeComments struct {
+	Leading  []string `json:"leading,omitempty" yaml:"leading,omitempty"`   // lines preceding the element (comments and blank lines)
+	Trailing string   `json:"trailing,omitempty" yaml:"trailing,omitempty"` // text following the element on the same line
+}
+
+// interchangeAttributes represents the attributes of a setting in an interchange document.
+// The attributes are serialized as an object/mapping keeping the order of the attributes.
+type interchangeAttributes dictionary
+
+// toInterchange converts the ATV document to an interchange document.
+func (doc *document) toInterchange() *interchangeDocument {
+
+	result := &interchangeDocument{Nodes: []*interchangeNode{}}
+	for _, node := range doc.Nodes {
+		if node.Pragma != nil {
+			name, value := node.Pragma.Name, node.Pragma.Value
+			result.Nodes = append(result.Nodes, &interchangeNode{
+				Pragma:   &name,
+				Value:    &value,
+				Comments: node.Pragma.comments.toInterchange(),
+			})
+		} else if node.Setting != nil {
+			result.Nodes = append(result.Nodes, node.Setting.toInterchange())
+		}
+	}
+
+	if doc.layout != nil && len(doc.layout.Trailing) > 0 {
+		result.Trailing = strings.Split(strings.TrimSuffix(doc.layout.Trailing, "\n"), "\n")
+	}
+
+	return result
+}
+
+// toInterchange converts the comments to comments in an interchange document (nil, if there are no comments).
+func (comments *documentComments) toInterchange() *interchangeComments {
+
+	if comments == nil || (len(comments.Leading) == 0 && len(comments.Trailing) == 0) {
+		return nil
+	}
+
+	return &interchangeComments{
+		Leading:  append([]string(nil), comments.Leading...),
+		Trailing: comments.Trailing,
+	}
+}
+
+// attributeCommentsToInterchange returns the comments attached to the attributes in the specified dictionary
+// (nil, if there are no comments).
+func attributeCommentsToInterchange(dict dictionary) map[string]*interchangeComments {
+
+	var result map[string]*interchangeComments
+	for _, kvp := range dict {
+		if comments := kvp.comments.toInterchange(); comments != nil {
+			if result == nil {
+				result = map[string]*interchangeComments{}
+			}
+			result[kvp.Key] = comments
+		}
+	}
+
+	return result
+}
+
+// toInterchange converts the setting to a node in an interchange document.
+func (setting *documentSetting) toInterchange() *interchangeNode {
+
+	name := setting.Name
+	node := &interchangeNode{Setting: &name, Comments: setting.comments.toInterchange()}
+
+	if setting.SimpleValue != nil {
+		value := setting.SimpleValue.Value
+		node.Value = &value
+		return node
+	}
+
+	if setting.ValueWithMetadata != nil {
+		if len(setting.ValueWithMetadata.Data) > 0 {
+			node.Attributes = append(interchangeAttributes(nil), setting.ValueWithMetadata.Data...)
+			node.AttributeComments = attributeCommentsToInterchange(setting.ValueWithMetadata.Data)
+		} else {
+			node.Rows = &[]*interchangeTableRow{}
+		}
+		node.Closing = setting.ValueWithMetadata.closing.toInterchange()
+		return node
+	}
+
+	if setting.TableValue != nil {
+		if len(setting.TableValue.Attributes) > 0 {
+			node.Attributes = append(interchangeAttributes(nil), setting.TableValue.Attributes...)
+			node.AttributeComments = attributeCommentsToInterchange(setting.TableValue.Attributes)
+		}
+		if len(setting.TableValue.Rows) > 0 || len(setting.TableValue.Attributes) == 0 {
+			rows := make([]*interchangeTableRow, 0, len(setting.TableValue.Rows))
+			for _, row := range setting.TableValue.Rows {
+				rows = append(rows, row.toInterchange())
+			}
+			node.Rows = &rows
+		}
+		node.Closing = setting.TableValue.closing.toInterchange()
+		return node
+	}
+
+	panic("Unhandled setting value")
+}
+
+// toInterchange converts the table row to a row in an interchange document.
+func (row *documentTableRow) toInterchange() *interchangeTableRow {
+
+	result := &interchangeTableRow{
+		Settings: make([]*interchangeNode, 0, len(row.Items)),
+		Comments: row.comments.toInterchange(),
+		Closing:  row.closing.toInterchange(),
+	}
+	if row.RowID != nil {
+		rid := string(*row.RowID)
+		result.RowID = &rid
+		result.RidComments = row.ridComments.toInterchange()
+	}
+
+	for _, item := range row.Items {
+		result.Settings = append(result.Settings, item.toInterchange())
+	}
+
+	return result
+}
+
+// documentFromInterchange creates an ATV document from the specified interchange document.
+// The document is written with the comments it carries, it does not get the opening and closing comments of
+// documents that are created from scratch.
+func documentFromInterchange(source *interchangeDocument) (*document, error) {
+
+	doc := &document{layout: &documentLayout{}}
+	for i, node := range source.Nodes {
+
+		if node == nil {
+			return nil, fmt.Errorf("Node %d is empty", i)
+		}
+
+		if node.Pragma != nil {
+			if node.Setting != nil || node.Attributes != nil || node.Rows != nil || node.AttributeComments != nil || node.Closing != nil {
+				return nil, fmt.Errorf("Node %d (pragma '%s') must have a value only", i, *node.Pragma)
+			}
+			if !pragmaNameRegex.MatchString(*node.Pragma) {
+				return nil, fmt.Errorf("Node %d: '%s' is not a valid pragma name", i, *node.Pragma)
+			}
+			comments, err := node.Comments.toDocumentComments()
+			if err != nil {
+				return nil, fmt.Errorf("Node %d (pragma '%s'): %s", i, *node.Pragma, err)
+			}
+			pragma := &documentPragma{Name: *node.Pragma, comments: comments}
+			if node.Value != nil {
+				pragma.Value = *node.Value
+			}
+			doc.Nodes = append(doc.Nodes, &documentNode{Pragma: pragma})
+			continue
+		}
+
+		setting, err := node.toSetting()
+		if err != nil {
+			return nil, fmt.Errorf("Node %d: %s", i, err)
+		}
+		doc.Nodes = append(doc.Nodes, &documentNode{Setting: setting})
+	}
+
+	if len(source.Trailing) > 0 {
+		trailing := &interchangeComments{Leading: source.Trailing}
+		if _, err := trailing.toDocumentComments(); err != nil {
+			return nil, fmt.Errorf("Trailing lines: %s", err)
+		}
+		doc.layout.Trailing = strings.Join(source.Trailing, "\n") + "\n"
+	}
+
+	return doc, nil
+}
+
+// toDocumentComments converts comments in an interchange document to comments attached to an element of an ATV
+// document. The lines must be blank or comments ('//') to keep the ATV document intact. An empty set of comments is
+// returned, if there are no comments (nodes without comments do not get additional blank lines).
+func (comments *interchangeComments) toDocumentComments() (*documentComments, error) {
+
+	if comments == nil {
+		return &documentComments{}, nil
+	}
+
+	isComment := func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		return !strings.ContainsAny(line, "\r\n") && (len(trimmed) == 0 || strings.HasPrefix(trimmed, "//"))
+	}
+
+	for _, line := range comments.Leading {
+		if !isComment(line) {
+			return nil, fmt.Errorf("'%s' is neither a blank line nor a comment", line)
+		}
+	}
+	if !isComment(comments.Trailing) {
+		return nil, fmt.Errorf("'%s' is not a comment", comments.Trailing)
+	}
+
+	return &documentComments{
+		Leading:  append([]string(nil), comments.Leading...),
+		Trailing: comments.Trailing,
+	}, nil
+}
+
+// attachAttributeComments attaches the specified comments to the attributes in the specified dictionary.
+func attachAttributeComments(dict dictionary, comments map[string]*interchangeComments) error {
+
+	for key := range comments {
+		if !dict.ContainsKey(key) {
+			return fmt.Errorf("There are comments for attribute '%s', but the attribute does not exist", key)
+		}
+	}
+
+	for i := range dict {
+		if c, ok := comments[dict[i].Key]; ok {
+			documentComments, err := c.toDocumentComments()
+			if err != nil {
+				return fmt.Errorf("Attribute '%s': %s", dict[i].Key, err)
+			}
+			dict[i].comments = documentComments
+		}
+	}
+
+	return nil
+}
+
+// toSetting converts the node of an interchange document to a setting.
+func (node *interchangeNode) toSetting() (*documentSetting, error) {
+
+	if node.Setting == nil {
+		return nil, fmt.Errorf("The node is neither a pragma nor a setting")
+	}
+
+	if !identifierRegex.MatchString(*node.Setting) {
+		return nil, fmt.Errorf("'%s' is not a valid setting name", *node.Setting)
+	}
+
+	comments, err := node.Comments.toDocumentComments()
+	if err != nil {
+		return nil, fmt.Errorf("Setting '%s': %s", *node.Setting, err)
+	}
+	setting := &documentSetting{Name: *node.Setting, comments: comments}
+
+	// simple value
+	if node.Value != nil {
+		if node.Attributes != nil || node.Rows != nil || node.AttributeComments != nil || node.Closing != nil {
+			return nil, fmt.Errorf("The setting '%s' has a simple value, it must not have attributes or rows", setting.Name)
+		}
+		setting.SimpleValue = &documentSimpleValue{Value: *node.Value}
+		return setting, nil
+	}
+
+	// table value or value with metadata (both are read as table values from ATV documents)
+	if node.Attributes == nil && node.Rows == nil {
+		return nil, fmt.Errorf("The setting '%s' has neither a value nor attributes nor rows", setting.Name)
+	}
+
+	table := &documentTableValue{Attributes: dictionary(node.Attributes)}
+	if err := attachAttributeComments(table.Attributes, node.AttributeComments); err != nil {
+		return nil, fmt.Errorf("Setting '%s': %s", setting.Name, err)
+	}
+	if table.closing, err = node.Closing.toDocumentComments(); err != nil {
+		return nil, fmt.Errorf("Setting '%s': %s", setting.Name, err)
+	}
+	if node.Rows != nil {
+		table.Rows = make([]*documentTableRow, 0, len(*node.Rows))
+		for i, row := range *node.Rows {
+			if row == nil {
+				return nil, fmt.Errorf("Row %d of setting '%s' is empty", i, setting.Name)
+			}
+			tableRow := &documentTableRow{Items: make([]*documentSetting, 0, len(row.Settings))}
+			if row.RowID != nil {
+				rid := RowID(*row.RowID)
+				tableRow.RowID = &rid
+			} else if row.RidComments != nil {
+				return nil, fmt.Errorf("Row %d of setting '%s' has comments for the row id, but no row id", i, setting.Name)
+			}
+			rowComments := []struct {
+				source *interchangeComments
+				target **documentComments
+			}{
+				{row.Comments, &tableRow.comments},
+				{row.RidComments, &tableRow.ridComments},
+				{row.Closing, &tableRow.closing},
+			}
+			for _, c := range rowComments {
+				if *c.target, err = c.source.toDocumentComments(); err != nil {
+					return nil, fmt.Errorf("Row %d of setting '%s': %s", i, setting.Name, err)
+				}
+			}
+			for _, item := range row.Settings {
+				if item == nil || item.Pragma != nil {
+					return nil, fmt.Errorf("Row %d of setting '%s' contains a node that is not a setting", i, setting.Name)
+				}
+				itemSetting, err := item.toSetting()
+				if err != nil {
+					return nil, err
+				}
+				tableRow.Items = append(tableRow.Items, itemSetting)
+			}
+			table.Rows = append(table.Rows, tableRow)
+		}
+	}
+	setting.TableValue = table
+
+	return setting, nil
+}
+
+// MarshalJSON writes the attributes as a JSON object keeping the order of the attributes.
+func (attributes interchangeAttributes) MarshalJSON() ([]byte, error) {
+
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+
+	buffer.WriteString("{")
+	for i, kvp := range attributes {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		if err := encoder.Encode(kvp.Key); err != nil {
+			return nil, err
+		}
+		buffer.WriteString(":")
+		if err := encoder.Encode(kvp.Value); err != nil {
+			return nil, err
+		}
+	}
+	buffer.WriteString("}")
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalJSON reads the attributes from a JSON object keeping the order of the attributes.
+func (attributes *interchangeAttributes) UnmarshalJSON(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("Attributes must be an object")
+	}
+
+	result := interchangeAttributes{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string) // keys of JSON objects are always strings
+		var value string
+		if err := decoder.Decode(&value); err != nil {
+			return fmt.Errorf("The value of attribute '%s' must be a string", key)
+		}
+		if err := (*dictionary)(&result).Add(key, value); err != nil {
+			return err
+		}
+	}
+
+	*attributes = result
+	return nil
+}
+
+// MarshalYAML returns the attributes as a YAML mapping keeping the order of the attributes.
+func (attributes interchangeAttributes) MarshalYAML() (interface{}, error) {
+
+	mapping := make(yaml.MapSlice, 0, len(attributes))
+	for _, kvp := range attributes {
+		mapping = append(mapping, yaml.MapItem{Key: kvp.Key, Value: kvp.Value})
+	}
+
+	return mapping, nil
+}
+
+// UnmarshalYAML reads the attributes from a YAML mapping keeping the order of the attributes.
+func (attributes *interchangeAttributes) UnmarshalYAML(unmarshal func(interface{}) error) error {
+
+	// read the mapping twice: to determine the order of the attributes and to get the values as strings
+	// (unquoted scalars like 'yes' or '1' are taken as they are)
+	var mapping yaml.MapSlice
+	if err := unmarshal(&mapping); err != nil {
+		return err
+	}
+	var values map[string]string
+	if err := unmarshal(&values); err != nil {
+		return fmt.Errorf("Attributes must be a mapping of strings: %s", err)
+	}
+
+	result := interchangeAttributes{}
+	for _, item := range mapping {
+		key := fmt.Sprint(item.Key)
+		if err := (*dictionary)(&result).Add(key, values[key]); err != nil {
+			return err
+		}
+	}
+
+	*attributes = result
+	return nil
+}
+
+// toJSON serializes the ATV document to JSON.
+func (doc *document) toJSON() ([]byte, error) {
+
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(doc.toInterchange())
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// documentFromJSON deserializes an ATV document from JSON.
+func documentFromJSON(data []byte) (*document, error) {
+
+	var source interchangeDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&source)
+	if err != nil {
+		return nil, fmt.Errorf("Reading JSON document failed: %s", err)
+	}
+
+	return documentFromInterchange(&source)
+}
+
+// toYAML serializes the ATV document to YAML.
+func (doc *document) toYAML() ([]byte, error) {
+	return yaml.Marshal(doc.toInterchange())
+}
+
+// documentFromYAML deserializes an ATV document from YAML.
+func documentFromYAML(data []byte) (*document, error) {
+
+	var source interchangeDocument
+	err := yaml.UnmarshalStrict(data, &source)
+	if err != nil {
+		return nil, fmt.Errorf("Reading YAML document failed: %s", err)
+	}
+
+	return documentFromInterchange(&source)
+}
@@ -0,0 +1,100 @@
+package atv
+
+import (
+	"strings"
+	"testing"
+)
+
+// interchangeTestDocument is an ATV document with comments attached to all kinds of elements.
+const interchangeTestDocument = `// mGuard Configuration Profile
+
+#version 8.8.1.default // firmware version
+#serial 1234
+
+// hostname of the device
+MY_HOSTNAME = "mguard" // trailing comment
+ROOT_PASSWORD = {
+  // the hash of the password
+  value = "$1$ZL0WsCrL$iQgd6BqvPGBs6eMt5b6Zt0" // md5
+  access = "must-not-overwrite"
+}
+
+VPN_CONNECTION = { // connections
+  { // first connection
+    // row id
+    { rid = "abc" } // rid
+    NAME = "plant-a"
+    CA_CERT_REF = {
+      rowref = "xyz"
+    } // reference
+    // closing comment of the row
+  }
+  {
+    NAME = "plant-b"
+  }
+  // no more connections
+} // end of connections
+EMPTY_TABLE = {
+}
+// End of configuration profile
+`
+
+func TestInterchangeRoundTripKeepsComments(t *testing.T) {
+
+	conversions := []struct {
+		name string
+		to   func(file *File) ([]byte, error)
+		from func(data []byte) (*File, error)
+	}{
+		{"JSON", (*File).ToJSON, FromJSON},
+		{"YAML", (*File).ToYAML, FromYAML},
+	}
+
+	documents := []struct {
+		name string
+		text string
+	}{
+		{"with comments", interchangeTestDocument},
+		{"without comments", "#version 8.8.1.default\nMY_HOSTNAME = \"mguard\"\nVPN_CONNECTION = {\n  {\n    NAME = \"plant-a\"\n  }\n}\n"},
+	}
+
+	for _, conversion := range conversions {
+		for _, document := range documents {
+
+			file := fileFromString(t, document.text)
+			data, err := conversion.to(file)
+			if err != nil {
+				t.Fatalf("%s (%s): Converting to %s failed: %v", conversion.name, document.name, conversion.name, err)
+			}
+
+			converted, err := conversion.from(data)
+			if err != nil {
+				t.Fatalf("%s (%s): Converting from %s failed: %v\n%s", conversion.name, document.name, conversion.name, err, data)
+			}
+
+			if text := converted.String(); text != document.text {
+				t.Errorf("%s (%s): The converted document differs from the original document\n--expected--\n%s\n--got--\n%s\n--%s--\n%s",
+					conversion.name, document.name, document.text, text, conversion.name, data)
+			}
+		}
+	}
+}
+
+func TestInterchangeRejectsCommentsThatAreNoComments(t *testing.T) {
+
+	documents := []string{
+		`{ "nodes": [ { "pragma": "version", "value": "8.8.1.default" }, { "setting": "A", "value": "x", "comments": { "leading": [ "B = \"y\"" ] } } ] }`,
+		`{ "nodes": [ { "pragma": "version", "value": "8.8.1.default" }, { "setting": "A", "value": "x", "comments": { "trailing": " B = \"y\"" } } ] }`,
+		`{ "nodes": [ { "pragma": "version", "value": "8.8.1.default" } ], "trailing": [ "// ok", "B = \"y\"" ] }`,
+		`{ "nodes": [ { "pragma": "version", "value": "8.8.1.default" }, { "setting": "A", "attributes": { "value": "x" }, "attribute_comments": { "other": { "trailing": "// x" } } } ] }`,
+	}
+
+	for _, document := range documents {
+		_, err := FromJSON([]byte(document))
+		if err == nil {
+			t.Errorf("Reading the document succeeded unexpectedly\n%s", document)
+		} else if !strings.Contains(err.Error(), "comment") && !strings.Contains(err.Error(), "blank line") {
+			t.Errorf("Reading the document failed with an unexpected error: %v\n%s", err, document)
+		}
+	}
+}
@@ -61,6 +61,20 @@ func (node *documentNode) actual() documentWriter {
 	panic("Unhandled node type")
 }
 
+// comments returns the comments attached to the actual node (nil, if the node was not read from a document).
+func (node *documentNode) comments() *documentComments {
+
+	if node.Pragma != nil {
+		return node.Pragma.comments
+	}
+
+	if node.Setting != nil {
+		return node.Setting.comments
+	}
+
+	return nil
+}
+
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
 func (node *documentNode) WriteDocumentPart(writer *strings.Builder, indent int) error {
 
@@ -10,9 +10,11 @@ import (
 
 // documentPragma represents a pragma in an ATV configuration document.
 type documentPragma struct {
-	Pos   lexer.Position
-	Name  string
-	Value string
+	Pos      lexer.Position
+	Name     string
+	Value    string
+	comments *documentComments
+	source   *documentSource
 }
 
 // Dupe returns a copy of the document node.
@@ -23,8 +25,10 @@ func (pragma *documentPragma) Dupe() *documentPragma {
 	}
 
 	return &documentPragma{
-		Name:  pragma.Name,
-		Value: pragma.Value,
+		Name:     pragma.Name,
+		Value:    pragma.Value,
+		comments: pragma.comments,
+		source:   pragma.source,
 	}
 }
 
@@ -42,8 +46,17 @@ func (pragma *documentPragma) Capture(values []string) error {
 }
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
+// The original text of the pragma is written, if the pragma was read from a document and not modified since.
 func (pragma *documentPragma) WriteDocumentPart(writer *strings.Builder, indent int) error {
-	line := fmt.Sprintf("%s#%s %s\n", spacer(indent), pragma.Name, pragma.Value)
-	_, err := writer.WriteString(line)
-	return err
+	builder := strings.Builder{}
+	pragma.writePart(&builder, indent)
+	pragma.source.write(writer, builder.String())
+	return nil
+}
+
+// writePart writes the pragma along with the attached comments to the specified writer.
+func (pragma *documentPragma) writePart(writer *strings.Builder, indent int) {
+	pragma.comments.writeLeading(writer)
+	line := fmt.Sprintf("%s#%s %s%s", spacer(indent), pragma.Name, pragma.Value, pragma.comments.lineEnd(true))
+	writer.WriteString(line)
 }
@@ -0,0 +1,89 @@
+package atv
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// remapCollidingRowIDs returns a copy of the document with all row ids that are used in the specified document as
+// well renamed to fresh unique row ids. Row references referring to renamed rows are rewritten accordingly.
+// The returned map maps the old row ids to the new ones.
+func (doc *document) remapCollidingRowIDs(other *document) (*document, map[RowID]RowID) {
+
+	// collect row ids used in both documents
+	// (fresh row ids must not be used in any of the documents)
+	usedRowIDs := map[RowID]bool{}
+	otherRowIDs := map[RowID]bool{}
+	for _, rid := range other.GetRowIDs() {
+		usedRowIDs[rid] = true
+		otherRowIDs[rid] = true
+	}
+	for _, rid := range doc.GetRowIDs() {
+		usedRowIDs[rid] = true
+	}
+
+	// determine fresh row ids for colliding row ids
+	mapping := map[RowID]RowID{}
+	for _, rid := range doc.GetRowIDs() {
+		if !otherRowIDs[rid] {
+			continue
+		}
+		if _, exists := mapping[rid]; exists {
+			continue
+		}
+		for i := 1; ; i++ {
+			newRowID := RowID(fmt.Sprintf("%s-%d", rid, i))
+			if !usedRowIDs[newRowID] {
+				log.Infof("Row id '%s' is used in both documents, renaming it to '%s'.", rid, newRowID)
+				usedRowIDs[newRowID] = true
+				mapping[rid] = newRowID
+				break
+			}
+		}
+	}
+
+	copy := doc.Dupe()
+	if len(mapping) > 0 {
+		for _, node := range copy.Nodes {
+			if node.Setting != nil {
+				node.Setting.renameRowIDs(mapping)
+			}
+		}
+	}
+
+	return copy, mapping
+}
+
+// renameRowIDs renames the row ids of rows in the setting and rewrites row references as specified by the mapping
+// (recursively). Row ids may be shared with other copies of the row, so they are replaced, not modified in place.
+func (setting *documentSetting) renameRowIDs(mapping map[RowID]RowID) {
+
+	renameRowRef := func(dict *dictionary) {
+		var rowref string
+		if dict.TryGet("rowref", &rowref) {
+			if newRowID, ok := mapping[RowID(rowref)]; ok {
+				log.Debugf("Rewriting row reference in setting '%s' from '%s' to '%s'.", setting.Name, rowref, newRowID)
+				dict.Set("rowref", string(newRowID))
+			}
+		}
+	}
+
+	if setting.ValueWithMetadata != nil {
+		renameRowRef(&setting.ValueWithMetadata.Data)
+	}
+
+	if setting.TableValue != nil {
+		renameRowRef(&setting.TableValue.Attributes)
+		for _, row := range setting.TableValue.Rows {
+			if row.RowID != nil {
+				if newRowID, ok := mapping[*row.RowID]; ok {
+					row.RowID = &newRowID
+				}
+			}
+			for _, item := range row.Items {
+				item.renameRowIDs(mapping)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/alecthomas/participle/lexer"
+	log "github.com/sirupsen/logrus"
 )
 
 // documentSetting represents a setting node in an ATV document.
@@ -14,6 +15,8 @@ type documentSetting struct {
 	SimpleValue       *documentSimpleValue       `( @@`
 	TableValue        *documentTableValue        `| @@`
 	ValueWithMetadata *documentValueWithMetadata `| @@ )`
+	comments          *documentComments
+	source            *documentSource
 }
 
 // Dupe returns a deep copy of the setting.
@@ -23,7 +26,7 @@ func (setting *documentSetting) Dupe() *documentSetting {
 		return nil
 	}
 
-	var copy = &documentSetting{Name: setting.Name}
+	var copy = &documentSetting{Name: setting.Name, comments: setting.comments, source: setting.source}
 	if setting.SimpleValue != nil {
 		copy.SimpleValue = setting.SimpleValue.Dupe()
 	} else if setting.ValueWithMetadata != nil {
@@ -57,7 +60,7 @@ func (setting *documentSetting) String() string {
 	}
 
 	builder := strings.Builder{}
-	err := setting.WriteDocumentPart(&builder, 0)
+	err := setting.writePart(&builder, 0, false)
 	if err != nil {
 		return fmt.Sprintf("Error: %s", err)
 	}
@@ -65,33 +68,560 @@ func (setting *documentSetting) String() string {
 	return strings.TrimSpace(builder.String())
 }
 
-// mergeInto merges the current setting into the specified document.
-func (setting *documentSetting) mergeInto(doc *document) error {
+// mergeInto merges the current (top-level) setting into the specified document using the specified merge strategy.
+// The column is needed for MergeUnionByColumn only. Rows and settings nested in the setting that are excluded by the
+// merge configuration are not merged. Access violations are added to the specified report.
+func (setting *documentSetting) mergeInto(
+	doc *document,
+	strategy MergeStrategy,
+	column string,
+	config *MergeConfiguration,
+	report *MergeReport) error {
 
 	if setting == nil {
 		return nil
 	}
 
-	if setting.SimpleValue != nil || setting.ValueWithMetadata != nil {
-		// top level simple value
-		// => simply overwrite the setting in the document
-		if err := doc.SetSetting(setting); err != nil {
-			return err // document seems to contain a value with that name, but a different type (not a simple/complex value)
+	// get the setting to merge into
+	path := documentSettingPath{{name: &setting.Name}}
+	target, err := doc.getSetting(path)
+	if err != nil {
+		return err
+	}
+
+	// specified setting was not found
+	// => add it at the end
+	if target == nil {
+		newNode := &documentNode{Setting: setting.withoutExcluded(nil, path, strategy, config)}
+		doc.Nodes = append(doc.Nodes, newNode)
+		return nil
+	}
+
+	return setting.withoutExcluded(target, path, strategy, config).mergeValueInto(target, path, strategy, column, report)
+}
+
+// mergePathInto merges the settings/rows selected by the specified path from the current (top-level) setting into
+// the specified document. The path may contain wildcards instead of row indices. The merge configuration determines
+// the settings to exclude and the merge strategies to use. Access violations are added to the specified report.
+func (setting *documentSetting) mergePathInto(
+	doc *document,
+	path documentSettingPath,
+	config *MergeConfiguration,
+	report *MergeReport) error {
+
+	if setting == nil {
+		return nil
+	}
+
+	if len(path) < 2 || path[0].name == nil || *path[0].name != setting.Name {
+		return fmt.Errorf("The path '%s' does not address a setting nested in setting '%s'", path, setting.Name)
+	}
+
+	if setting.TableValue == nil {
+		return fmt.Errorf("Setting '%s' is not a table value, but the path '%s' specifies a more nested setting", setting.Name, path)
+	}
+
+	// get the setting to merge into
+	// (add an empty table, if the document does not contain the setting, yet)
+	target, err := doc.getSetting(path[0:1])
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		target = &documentSetting{
+			Name: setting.Name,
+			TableValue: &documentTableValue{
+				Attributes: setting.TableValue.Attributes,
+				Rows:       []*documentTableRow{}}}
+		doc.Nodes = append(doc.Nodes, &documentNode{Setting: target})
+	}
+
+	return setting.mergeNestedInto(target, path, 1, path[0:1], config, report)
+}
+
+// mergeNestedInto merges the settings/rows selected by the specified path from the current setting into the
+// specified setting. Both settings correspond to the path up to the specified index (settingPath is the same
+// path with wildcards resolved).
+func (setting *documentSetting) mergeNestedInto(
+	target *documentSetting,
+	path documentSettingPath,
+	index int,
+	settingPath documentSettingPath,
+	config *MergeConfiguration,
+	report *MergeReport) error {
+
+	// the path selects the entire setting
+	if index == len(path) {
+		strategy, column := config.getStrategy(settingPath)
+		log.Infof("Merging setting '%s' (strategy: %s)...", settingPath, strategy)
+		return setting.withoutExcluded(target, settingPath, strategy, config).mergeValueInto(target, settingPath, strategy, column, report)
+	}
+
+	// the path specifies a more nested setting
+	// => both settings must be tables
+	if setting.TableValue == nil {
+		return fmt.Errorf("Setting '%s' is not a table value, but the path '%s' specifies a more nested setting", settingPath, path)
+	}
+	if target.TableValue == nil {
+		return fmt.Errorf("Setting '%s' in the document is not a table value", settingPath)
+	}
+	if !path[index].isRowSelector() {
+		return fmt.Errorf("Setting '%s' is a table value, but the path '%s' does not address a row", settingPath, path)
+	}
+
+	// settings that must not be overwritten are kept as they are
+	// (settings that may be extended only do not allow to change existing rows)
+	access, err := target.getAccess()
+	if err != nil {
+		return err
+	}
+	if access == MustNotOverwrite {
+		report.addViolation(settingPath.String(), access, false, "The setting must not be overwritten, skipping merging '%s'", path)
+		return nil
+	}
+	if access == MayAppend && index+1 < len(path) {
+		report.addViolation(settingPath.String(), access, false, "Rows of the setting must not be changed, skipping merging '%s'", path)
+		return nil
+	}
+
+	for i, row := range setting.TableValue.Rows {
+
+		// skip row, if it is not selected by the path
+		if !path[index].selectsRow(i, row) {
+			continue
+		}
+
+		// skip row, if it is excluded
+		rowPath := settingPath.withRow(i, row)
+		if config.isExcluded(rowPath) {
+			log.Debugf("Row '%s' is excluded from merging. Skipping...", rowPath)
+			continue
+		}
+
+		targetRowIndex := target.TableValue.findCorrespondingRow(row, i)
+
+		// the path selects the entire row
+		// => replace the corresponding row or append the row, if there is no corresponding row
+		if index+1 == len(path) {
+			strategy, _ := config.getStrategy(rowPath)
+			if targetRowIndex >= 0 && strategy == MergeKeepBase {
+				log.Infof("Keeping row corresponding to row '%s' (strategy: %s)...", rowPath, strategy)
+				continue
+			}
+			if targetRowIndex >= 0 && access == MayAppend {
+				if target.TableValue.Rows[targetRowIndex].String() != row.String() {
+					report.addViolation(rowPath.String(), access, false, "The setting may be extended by appending rows only, keeping the existing row")
+				}
+				continue
+			}
+			log.Infof("Merging row '%s'...", rowPath)
+			if targetRowIndex >= 0 {
+				target.TableValue.Rows[targetRowIndex] = row.Dupe()
+			} else {
+				target.TableValue.Rows = append(target.TableValue.Rows, row.Dupe())
+			}
+			continue
+		}
+
+		// the path selects a setting within the row
+		// => the document must contain the corresponding row
+		if targetRowIndex < 0 {
+			log.Warnf("The document does not contain a row corresponding to row '%s', skipping merging '%s'.", rowPath, path)
+			continue
+		}
+
+		// get the setting within the row
+		// (skip the row, if the setting does not exist or is excluded)
+		name := *path[index+1].name
+		itemPath := append(rowPath, documentSettingPathToken{name: &name})
+		item := row.getItem(name)
+		if item == nil {
+			log.Debugf("Row '%s' does not contain setting '%s'. Skipping...", rowPath, name)
+			continue
+		}
+		if config.isExcluded(itemPath) {
+			log.Debugf("Setting '%s' is excluded from merging. Skipping...", itemPath)
+			continue
 		}
 
+		// get the setting to merge into
+		// (add an empty setting, if the row does not contain the setting, yet)
+		targetRow := target.TableValue.Rows[targetRowIndex]
+		targetItem := targetRow.getItem(name)
+		if targetItem == nil {
+			targetItem = &documentSetting{Name: name}
+			targetRow.Items = append(targetRow.Items, targetItem)
+		}
+
+		err := item.mergeNestedInto(targetItem, path, index+2, itemPath, config, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withoutExcluded returns a copy of the current setting without the rows and settings nested in it that are excluded
+// by the merge configuration, so merging the copy as a whole does not touch them (path corresponds to the current
+// setting). Excluded parts are taken over from the specified target setting (may be nil), if merging with the specified
+// strategy replaces them. Otherwise they are removed.
+func (setting *documentSetting) withoutExcluded(
+	target *documentSetting,
+	path documentSettingPath,
+	strategy MergeStrategy,
+	config *MergeConfiguration) *documentSetting {
+
+	copy := setting.Dupe()
+	if config == nil || len(config.excludes) == 0 || copy.TableValue == nil {
+		return copy
+	}
+
+	// determine whether the merge strategy replaces the entire table or existing rows
+	// (default strategy for table values: replace rows by row id)
+	var targetTable *documentTableValue
+	if target != nil {
+		targetTable = target.TableValue
+	}
+	replacesTable := strategy == MergeReplace
+	replacesRows := replacesTable || strategy == MergeReplaceRowsByRid || strategy == DefaultMergeStrategy
+
+	rows := make([]*documentTableRow, 0, len(copy.TableValue.Rows))
+	correspondingRows := map[*documentTableRow]bool{}
+	for i, row := range copy.TableValue.Rows {
+
+		// determine the corresponding row in the target table
+		var targetRow *documentTableRow
+		if targetTable != nil {
+			if targetRowIndex := targetTable.findCorrespondingRow(row, i); targetRowIndex >= 0 {
+				targetRow = targetTable.Rows[targetRowIndex]
+				correspondingRows[targetRow] = true
+			}
+		}
+
+		// keep the corresponding row of the target table, if the row is excluded
+		rowPath := path.withRow(i, row)
+		if config.isExcluded(rowPath) {
+			log.Debugf("Row '%s' is excluded from merging. Skipping...", rowPath)
+			if replacesTable && targetRow != nil {
+				rows = append(rows, targetRow.Dupe())
+			}
+			continue
+		}
+
+		// keep the corresponding settings of the target row, if settings in the row are excluded
+		// (settings in more deeply nested tables are replaced along with the row)
+		items := make([]*documentSetting, 0, len(row.Items))
+		for _, item := range row.Items {
+			itemPath := append(rowPath, documentSettingPathToken{name: &item.Name})
+			var targetItem *documentSetting
+			if replacesRows && targetRow != nil {
+				targetItem = targetRow.getItem(item.Name)
+			}
+			if config.isExcluded(itemPath) {
+				log.Debugf("Setting '%s' is excluded from merging. Skipping...", itemPath)
+				if targetItem != nil {
+					items = append(items, targetItem.Dupe())
+				}
+				continue
+			}
+			items = append(items, item.withoutExcluded(targetItem, itemPath, MergeReplace, config))
+		}
+		if replacesRows && targetRow != nil {
+			for _, targetItem := range targetRow.Items {
+				itemPath := append(rowPath, documentSettingPathToken{name: &targetItem.Name})
+				if row.getItem(targetItem.Name) == nil && config.isExcluded(itemPath) {
+					items = append(items, targetItem.Dupe())
+				}
+			}
+		}
+		row.Items = items
+		rows = append(rows, row)
+	}
+
+	// keep excluded rows of the target table that do not correspond to a row of the current table
+	if replacesTable && targetTable != nil {
+		for i, targetRow := range targetTable.Rows {
+			if !correspondingRows[targetRow] && config.isExcluded(path.withRow(i, targetRow)) {
+				rows = append(rows, targetRow.Dupe())
+			}
+		}
+	}
+
+	copy.TableValue.Rows = rows
+
+	return copy
+}
+
+// mergeValueInto merges the value of the current setting into the specified setting using the specified merge
+// strategy. The column is needed for MergeUnionByColumn only. The access modifier of the specified setting is
+// respected, access violations are added to the specified report (path is used for reporting purposes only).
+func (setting *documentSetting) mergeValueInto(
+	target *documentSetting,
+	path documentSettingPath,
+	strategy MergeStrategy,
+	column string,
+	report *MergeReport) error {
+
+	copy := setting.Dupe()
+
+	// determine the strategy to use, if no strategy was specified explicitly
+	// (simple values are replaced, table rows are merged by their row id)
+	if strategy == DefaultMergeStrategy {
+		if copy.TableValue != nil {
+			strategy = MergeReplaceRowsByRid
+		} else {
+			strategy = MergeReplace
+		}
+	}
+
+	// the target setting may be a placeholder without a value
+	// => there is nothing to merge with, simply take over the value
+	targetHasValue := target.SimpleValue != nil || target.ValueWithMetadata != nil || target.TableValue != nil
+	if !targetHasValue {
+		target.setValue(copy)
+		return nil
+	}
+
+	// respect the access modifier of the setting
+	access, err := target.getAccess()
+	if err != nil {
+		return err
+	}
+	switch access {
+	case MustNotOverwrite:
+		if strategy != MergeKeepBase && !target.hasSameValue(copy) {
+			report.addViolation(path.String(), access, false, "The setting must not be overwritten, keeping the existing value")
+		}
+		return nil
+
+	case MayAppend:
+		if strategy == MergeKeepBase || strategy == MergeAppendRows || strategy == MergeUnionByColumn {
+			break // the strategy does not change the existing value
+		}
+		if copy.TableValue == nil || target.TableValue == nil {
+			report.addViolation(path.String(), access, false, "The setting may be extended by appending rows only, but it is not a table value")
+			return nil
+		}
+		for i, row := range copy.TableValue.Rows {
+			targetRowIndex := target.TableValue.findCorrespondingRow(row, i)
+			if targetRowIndex < 0 {
+				log.Debugf("Appending row to table value '%s'\n%s", copy.Name, row.String())
+				target.TableValue.Rows = append(target.TableValue.Rows, row)
+			} else if target.TableValue.Rows[targetRowIndex].String() != row.String() {
+				report.addViolation(path.withRow(i, row).String(), access, false, "The setting may be extended by appending rows only, keeping the existing row")
+			}
+		}
+		return nil
+	}
+
+	// strategies that work for all kinds of values
+	switch strategy {
+	case MergeReplace:
+		x := target.String()
+		y := copy.String()
+		if x != y {
+			log.Debugf("Setting '%s' changed.\nFrom: %s\nTo:   %s", copy.Name, x, y)
+			target.setValue(copy)
+		} else {
+			log.Debugf("Setting '%s' unchanged.\nValue: %s", copy.Name, x)
+		}
+		return nil
+
+	case MergeKeepBase:
+		log.Debugf("Setting '%s' exists in the document, keeping it.", copy.Name)
+		return nil
+	}
+
+	// strategies that work for table values only
+	if !strategy.isTableStrategy() {
+		panic("Unhandled merge strategy")
+	}
+	if copy.TableValue == nil {
+		return fmt.Errorf("Setting '%s' is not a table value, merge strategy '%s' cannot be applied", copy.Name, strategy)
+	}
+	if target.TableValue == nil {
+		return fmt.Errorf("Setting '%s' in the document is not a table value", target.Name)
+	}
+
+	switch strategy {
+	case MergeAppendRows:
+		for _, row := range copy.TableValue.Rows {
+			log.Debugf("Appending row to table value '%s'\n%s", copy.Name, row.String())
+			target.TableValue.Rows = append(target.TableValue.Rows, row)
+		}
+
+	case MergeReplaceRowsByRid:
+		target.TableValue.mergeRows(copy.Name, copy.TableValue.Rows)
+
+	case MergeUnionByColumn:
+		target.TableValue.unionRowsByColumn(copy.Name, column, copy.TableValue.Rows)
+	}
+
+	return nil
+}
+
+// getAccess returns the access modifier of the setting (DefaultAccessModifier, if the setting does not have one).
+func (setting *documentSetting) getAccess() (AccessModifier, error) {
+
+	var dict *dictionary
+	if setting.ValueWithMetadata != nil {
+		dict = &setting.ValueWithMetadata.Data
 	} else if setting.TableValue != nil {
-		// a table value => merge table settings
-		if err := doc.MergeTableSetting(setting); err != nil {
-			return err // document seems to contain a value with that name, but a different type (not a table)
+		dict = &setting.TableValue.Attributes
+	}
+
+	var value string
+	if dict == nil || !dict.TryGet("access", &value) {
+		return DefaultAccessModifier, nil
+	}
+
+	access, err := ParseAccessModifier(value)
+	if err != nil {
+		return DefaultAccessModifier, fmt.Errorf("Setting '%s' has an invalid access modifier: %s", setting.Name, err)
+	}
+
+	return access, nil
+}
+
+// hasSameValue checks whether the setting has the same value as the specified setting (attributes are ignored).
+func (setting *documentSetting) hasSameValue(other *documentSetting) bool {
+	return setting.plainValue() == other.plainValue()
+}
+
+// plainValue returns the value of the setting without any attributes.
+func (setting *documentSetting) plainValue() string {
+
+	if setting.SimpleValue != nil {
+		return quote(setting.SimpleValue.Value)
+	}
+
+	var value string
+	if setting.ValueWithMetadata != nil {
+		setting.ValueWithMetadata.Data.TryGet("value", &value)
+		return quote(value)
+	}
+
+	if setting.TableValue != nil {
+
+		// the parser cannot distinguish a value with metadata from a table without rows
+		if len(setting.TableValue.Rows) == 0 && setting.TableValue.Attributes.TryGet("value", &value) {
+			return quote(value)
 		}
 
-	} else {
-		panic("Unhandled setting type")
+		builder := strings.Builder{}
+		for _, row := range setting.TableValue.Rows {
+			builder.WriteString(row.String())
+			builder.WriteString("\n")
+		}
+		return builder.String()
+	}
+
+	return ""
+}
+
+// setSimpleValue sets the setting to the specified simple value.
+// Attributes of a value with metadata are kept.
+func (setting *documentSetting) setSimpleValue(value string) {
+
+	if setting.ValueWithMetadata != nil {
+		setting.ValueWithMetadata.Data.Set("value", value)
+		return
+	}
+
+	// the parser cannot distinguish a value with metadata from a table without rows
+	if setting.TableValue != nil && len(setting.TableValue.Rows) == 0 && setting.TableValue.Attributes.ContainsKey("value") {
+		setting.TableValue.Attributes.Set("value", value)
+		return
+	}
+
+	setting.ClearValue()
+	setting.SimpleValue = &documentSimpleValue{Value: value}
+}
+
+// isTable checks whether the setting has a table value.
+// Values with metadata and row references are not considered tables, although the parser reads them as tables.
+func (setting *documentSetting) isTable() bool {
+
+	if setting.TableValue == nil {
+		return false
+	}
+
+	attributes := setting.TableValue.Attributes
+	return len(setting.TableValue.Rows) > 0 || (!attributes.ContainsKey("value") && !attributes.ContainsKey("rowref"))
+}
+
+// attributes returns the attributes of the setting (nil for simple values).
+func (setting *documentSetting) attributes() *dictionary {
+
+	if setting.ValueWithMetadata != nil {
+		return &setting.ValueWithMetadata.Data
+	}
+
+	if setting.TableValue != nil {
+		return &setting.TableValue.Attributes
+	}
+
+	return nil
+}
+
+// getAttribute gets the specified attribute of the setting.
+// If the attribute does not exist, nil is returned.
+func (setting *documentSetting) getAttribute(name string) *string {
+
+	var value string
+	if dict := setting.attributes(); dict != nil && dict.TryGet(name, &value) {
+		return &value
 	}
 
 	return nil
 }
 
+// setAttribute sets the specified attribute of the setting.
+// A simple value is turned into a value with metadata.
+func (setting *documentSetting) setAttribute(name, value string) {
+
+	if setting.SimpleValue != nil {
+		items := dictionary{
+			keyValuePair{Key: name, Value: value},
+			keyValuePair{Key: "value", Value: setting.SimpleValue.Value},
+		}
+		setting.ValueWithMetadata = &documentValueWithMetadata{Data: items}
+		setting.SimpleValue = nil
+		return
+	}
+
+	setting.attributes().Set(name, value)
+}
+
+// removeAttribute removes the specified attribute from the setting.
+// A value with metadata is turned into a simple value, if the value is the only remaining item.
+func (setting *documentSetting) removeAttribute(name string) {
+
+	if setting.ValueWithMetadata != nil {
+		dict := &setting.ValueWithMetadata.Data
+		if dict.Remove(name) {
+			if len(*dict) == 1 && (*dict)[0].Key == "value" {
+				setting.SimpleValue = &documentSimpleValue{Value: (*dict)[0].Value}
+				setting.ValueWithMetadata = nil
+			}
+		}
+		return
+	}
+
+	if setting.TableValue != nil {
+		setting.TableValue.Attributes.Remove(name)
+	}
+}
+
+// setValue sets the value of the current setting to the value of the specified setting (no deep copy).
+func (setting *documentSetting) setValue(other *documentSetting) {
+	setting.ClearValue()
+	setting.SimpleValue = other.SimpleValue
+	setting.ValueWithMetadata = other.ValueWithMetadata
+	setting.TableValue = other.TableValue
+}
+
 // GetRowReferences returns all row references recursively.
 func (setting *documentSetting) GetRowReferences() []RowRef {
 
@@ -120,13 +650,12 @@ func (setting *documentSetting) GetRowIDs() []RowID {
 	return []RowID{}
 }
 
-// getSetting gets the setting at the specified path.
-// If the setting does not exist, nil is returned.
-func (setting *documentSetting) getSetting(path documentSettingPath, index int) (*documentSetting, error) {
+// findSettings returns all settings selected by the specified path starting at the specified index.
+func (setting *documentSetting) findSettings(path documentSettingPath, index int) ([]*documentSetting, error) {
 
 	// abort, if the setting is found
 	if index == len(path) {
-		return setting, nil
+		return []*documentSetting{setting}, nil
 	}
 
 	if setting.SimpleValue != nil || setting.ValueWithMetadata != nil {
@@ -135,7 +664,7 @@ func (setting *documentSetting) getSetting(path documentSettingPath, index int)
 
 	if setting.TableValue != nil {
 
-		if path[index].row == nil {
+		if !path[index].isRowSelector() {
 			return nil, fmt.Errorf("Setting '%s' is a table value, but the path '%s' does not address a specific row", path[0:index], path)
 		}
 
@@ -143,19 +672,21 @@ func (setting *documentSetting) getSetting(path documentSettingPath, index int)
 			return nil, fmt.Errorf("Setting '%s' is a table value, but the path '%s' does not address a value within a row", path[0:index], path)
 		}
 
-		rowIndex := *path[index].row
-		if rowIndex >= len(setting.TableValue.Rows) {
-			return nil, nil
-		}
-
-		row := setting.TableValue.Rows[rowIndex]
-		for _, item := range row.Items {
-			if item.Name == *path[index+1].name {
-				return item.getSetting(path, index+2)
+		var settings []*documentSetting
+		for i, row := range setting.TableValue.Rows {
+			if path[index].selectsRow(i, row) {
+				item := row.getItem(*path[index+1].name)
+				if item != nil {
+					found, err := item.findSettings(path, index+2)
+					if err != nil {
+						return nil, err
+					}
+					settings = append(settings, found...)
+				}
 			}
 		}
 
-		return nil, nil
+		return settings, nil
 	}
 
 	panic("Unhandled setting type")
@@ -221,57 +752,38 @@ func (setting *documentSetting) createSettingPlaceholder(path documentSettingPat
 	panic("Unhandled setting type")
 }
 
-// removeSetting removes the setting at the specified path.
-// If the setting does not exist, nil is returned (no error).
-func (setting *documentSetting) removeSetting(path documentSettingPath, index int) error {
+// findRows returns all rows of the table value selected by the specified row selector.
+func (setting *documentSetting) findRows(selector documentSettingPathToken) []*documentTableRow {
 
-	// abort, if the setting is found
-	if index == len(path) {
+	if setting.TableValue == nil {
 		return nil
 	}
 
-	if setting.SimpleValue != nil || setting.ValueWithMetadata != nil {
-		return fmt.Errorf("Setting '%s' is a single value, but the path '%s' specifies a more nested setting", path[0:index], path)
-	}
-
-	if setting.TableValue != nil {
-
-		if path[index].row == nil {
-			return fmt.Errorf("Setting '%s' is a table value, but the path '%s' does not address a specific row", path[0:index], path)
-		}
-
-		if index+1 == len(path) {
-			return fmt.Errorf("Setting '%s' is a table value, but the path '%s' does not address a value within a row", path[0:index], path)
-		}
-
-		rowIndex := *path[index].row
-		if rowIndex >= len(setting.TableValue.Rows) {
-			return nil
+	var rows []*documentTableRow
+	for i, row := range setting.TableValue.Rows {
+		if selector.selectsRow(i, row) {
+			rows = append(rows, row)
 		}
+	}
 
-		row := setting.TableValue.Rows[rowIndex]
-		for i, item := range row.Items {
-			if item.Name == *path[index+1].name {
+	return rows
+}
 
-				// dive deeper
-				err := item.removeSetting(path, index+2)
-				if err != nil {
-					return err
-				}
+// removeRows removes the rows of the table value selected by the specified row selector.
+func (setting *documentSetting) removeRows(selector documentSettingPathToken) {
 
-				// remove the item, if the appropriate depth is reached
-				if index+2 == len(path) {
-					row.Items = append(row.Items[:i], row.Items[i+1:]...)
-				}
+	if setting.TableValue == nil {
+		return
+	}
 
-				return nil
-			}
+	rows := []*documentTableRow{}
+	for i, row := range setting.TableValue.Rows {
+		if !selector.selectsRow(i, row) {
+			rows = append(rows, row)
 		}
-
-		return nil
 	}
 
-	panic("Unhandled setting type")
+	setting.TableValue.Rows = rows
 }
 
 // GetValue gets the value of the setting, if the setting is a simple value - with or without metadata.
@@ -291,13 +803,36 @@ func (setting *documentSetting) GetValue() (string, error) {
 		return "", fmt.Errorf("The setting does not contain a value")
 	}
 
+	// the parser cannot distinguish a value with metadata from a table without rows
+	var value string
+	if setting.TableValue != nil && len(setting.TableValue.Rows) == 0 && setting.TableValue.Attributes.TryGet("value", &value) {
+		return value, nil
+	}
+
 	return "", fmt.Errorf("The setting is not a simple value")
 }
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
+// The original text of the setting is written, if the setting was read from a document and not modified since.
 func (setting *documentSetting) WriteDocumentPart(writer *strings.Builder, indent int) error {
 
+	builder := strings.Builder{}
+	err := setting.writePart(&builder, indent, true)
+	if err != nil {
+		return err
+	}
+
+	setting.source.write(writer, builder.String())
+	return nil
+}
+
+// writePart writes the setting to the specified writer, optionally along with the attached comments.
+func (setting *documentSetting) writePart(writer *strings.Builder, indent int, withComments bool) error {
+
 	// write the name of the setting
+	if withComments {
+		setting.comments.writeLeading(writer)
+	}
 	line := fmt.Sprintf("%s%s = ", spacer(indent), setting.Name)
 	_, err := writer.WriteString(line)
 	if err != nil {
@@ -305,11 +840,12 @@ func (setting *documentSetting) WriteDocumentPart(writer *strings.Builder, inden
 	}
 
 	// write the setting value
-	parts := []documentWriter{setting.SimpleValue, setting.ValueWithMetadata, setting.TableValue}
+	lineEnd := setting.comments.lineEnd(withComments)
+	parts := []documentValueWriter{setting.SimpleValue, setting.ValueWithMetadata, setting.TableValue}
 	for _, part := range parts {
 
 		if !isNil(part) {
-			err := part.WriteDocumentPart(writer, indent)
+			err := part.writeValue(writer, indent, lineEnd, withComments)
 			if err != nil {
 				return err
 			}
@@ -9,52 +9,120 @@ import (
 
 // documentSettingPathToken represents a token in a setting path.
 type documentSettingPathToken struct {
-	name *string // set, if the token specifies a setting name.
-	row  *int    // set, if the token specifies a row in a table
+	name     *string           // set, if the token specifies a setting name.
+	row      *int              // set, if the token specifies a row in a table
+	anyRow   bool              // true, if the token specifies any row in a table (wildcard '*')
+	filter   documentRowFilter // set, if the token specifies rows in a table by their content
+	tableRow *documentTableRow // the row a row index refers to (set for resolved paths only, needed to match filters)
 }
 
 // documentSettingPath represents a parsed setting path.
 type documentSettingPath []documentSettingPathToken
 
+// documentRowFilter selects table rows by their content (all conditions must be met).
+type documentRowFilter []documentRowCondition
+
+// documentRowCondition is a condition a table row must meet to be selected by a row filter.
+type documentRowCondition struct {
+	column  string         // name of the setting in the row to check ('rid' checks the row id)
+	value   string         // the value the setting must have (may contain wildcards '*')
+	pattern *regexp.Regexp // the value as a regular expression
+}
+
 var settingNameRegex = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
 var tableRowAccessRegex = regexp.MustCompile(`^[0-9]+$`)
+var rowConditionColumnRegex = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9_]*)\s*=\s*`)
 
 // parseDocumentSettingPath parses the specified string and returns the corresponding tokens.
+// Tokens are separated by dots. Rows in tables can be addressed by their index, by the wildcard '*' selecting all
+// rows or by a query expression selecting rows by their content, e.g. VPN_CONNECTION[NAME="plant-a"].VPN_START or
+// FW_INCOMING[rid="abc"]. Row indices and wildcards can be put in brackets as well, e.g. VPN_CONNECTION[0].
+// Values in query expressions may contain wildcards ('*'), multiple conditions are separated by commas.
 func parseDocumentSettingPath(s string) (documentSettingPath, error) {
 
 	var tokens documentSettingPath
 	settingPreceding := false
-	for _, token := range strings.Split(s, ".") {
+	for len(s) > 0 {
+
+		// extract the next token (up to the next dot or opening bracket)
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		token := s[:end]
+		s = s[end:]
+
+		if len(token) > 0 {
+
+			if settingNameRegex.MatchString(token) {
+
+				if settingPreceding {
+					return nil, fmt.Errorf("Invalid path, '%s' not expected", token)
+				}
+
+				copy := token // workaround to avoid reusing iteration variable which would break the resulting tokens
+				tokens = append(tokens, documentSettingPathToken{name: &copy})
+				settingPreceding = true
+
+			} else if tableRowAccessRegex.MatchString(token) {
+
+				if !settingPreceding {
+					return nil, fmt.Errorf("Invalid path, '%s' not expected", token)
+				}
+
+				row, err := strconv.Atoi(token)
+				if err != nil {
+					return nil, err
+				}
+
+				tokens = append(tokens, documentSettingPathToken{row: &row})
+				settingPreceding = false
+
+			} else if token == "*" {
 
-		if settingNameRegex.MatchString(token) {
+				if !settingPreceding {
+					return nil, fmt.Errorf("Invalid path, '%s' not expected", token)
+				}
 
-			if settingPreceding {
-				return nil, fmt.Errorf("Invalid path, '%s' not expected", token)
+				tokens = append(tokens, documentSettingPathToken{anyRow: true})
+				settingPreceding = false
+
+			} else {
+				return nil, fmt.Errorf("Invalid path, '%s' is not a valid path token", token)
 			}
 
-			copy := token // workaround to avoid reusing iteration variable which would break the resulting tokens
-			tokens = append(tokens, documentSettingPathToken{name: &copy})
-			settingPreceding = true
-			continue
+		} else if len(s) == 0 || s[0] == '.' {
+			return nil, fmt.Errorf("Invalid path, empty path token")
 		}
 
-		if tableRowAccessRegex.MatchString(token) {
+		// parse the row selector in brackets, if any
+		if strings.HasPrefix(s, "[") {
 
 			if !settingPreceding {
-				return nil, fmt.Errorf("Invalid path, '%s' not expected", token)
+				return nil, fmt.Errorf("Invalid path, row selector '%s' not expected", s)
 			}
 
-			row, err := strconv.Atoi(token)
+			selector, rest, err := parseRowSelector(s)
 			if err != nil {
 				return nil, err
 			}
 
-			tokens = append(tokens, documentSettingPathToken{row: &row})
+			tokens = append(tokens, selector)
 			settingPreceding = false
-			continue
+			s = rest
+
+			if len(s) > 0 && s[0] != '.' {
+				return nil, fmt.Errorf("Invalid path, '%s' not expected after row selector", s)
+			}
 		}
 
-		return nil, fmt.Errorf("Invalid path, '%s' is not a valid path token", token)
+		// skip the separating dot
+		if strings.HasPrefix(s, ".") {
+			s = s[1:]
+			if len(s) == 0 {
+				return nil, fmt.Errorf("Invalid path, empty path token")
+			}
+		}
 	}
 
 	if len(tokens) == 0 {
@@ -64,6 +132,156 @@ func parseDocumentSettingPath(s string) (documentSettingPath, error) {
 	return tokens, nil
 }
 
+// parseRowSelector parses the row selector in brackets at the beginning of the specified string.
+// It returns the corresponding token and the rest of the string.
+func parseRowSelector(s string) (documentSettingPathToken, string, error) {
+
+	selector := strings.TrimSpace(s[1:])
+
+	// row index or wildcard
+	if end := strings.Index(selector, "]"); end >= 0 {
+		content := strings.TrimSpace(selector[:end])
+		rest := selector[end+1:]
+		if tableRowAccessRegex.MatchString(content) {
+			row, err := strconv.Atoi(content)
+			if err != nil {
+				return documentSettingPathToken{}, "", err
+			}
+			return documentSettingPathToken{row: &row}, rest, nil
+		}
+		if content == "*" {
+			return documentSettingPathToken{anyRow: true}, rest, nil
+		}
+	}
+
+	// query expression
+	var filter documentRowFilter
+	for {
+		match := rowConditionColumnRegex.FindStringSubmatch(selector)
+		if match == nil {
+			return documentSettingPathToken{}, "", fmt.Errorf("Invalid row selector '%s', expecting a condition like NAME=\"value\"", s)
+		}
+		selector = selector[len(match[0]):]
+
+		// find the end of the quoted value
+		end := -1
+		if strings.HasPrefix(selector, `"`) {
+			for i := 1; i < len(selector); i++ {
+				if selector[i] == '\\' {
+					i++
+				} else if selector[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+		}
+		if end < 0 {
+			return documentSettingPathToken{}, "", fmt.Errorf("Invalid row selector '%s', the value of '%s' must be enclosed in double quotes", s, match[1])
+		}
+
+		value, err := unquote(selector[:end])
+		if err != nil {
+			return documentSettingPathToken{}, "", err
+		}
+		filter = append(filter, newDocumentRowCondition(match[1], value))
+		selector = strings.TrimSpace(selector[end:])
+
+		if strings.HasPrefix(selector, ",") {
+			selector = selector[1:]
+			continue
+		}
+
+		if strings.HasPrefix(selector, "]") {
+			return documentSettingPathToken{filter: filter}, selector[1:], nil
+		}
+
+		return documentSettingPathToken{}, "", fmt.Errorf("Invalid row selector '%s', expecting ',' or ']'", s)
+	}
+}
+
+// newDocumentRowCondition creates a condition checking whether the specified column has the specified value.
+// The value may contain wildcards ('*').
+func newDocumentRowCondition(column string, value string) documentRowCondition {
+	parts := strings.Split(value, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	pattern := regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+	return documentRowCondition{column: column, value: value, pattern: pattern}
+}
+
+// matches checks whether the specified row meets all conditions of the filter.
+func (filter documentRowFilter) matches(row *documentTableRow) bool {
+
+	for _, condition := range filter {
+
+		var value string
+		if condition.column == "rid" {
+			if row.RowID == nil {
+				return false
+			}
+			value = string(*row.RowID)
+		} else {
+			item := row.getItem(condition.column)
+			if item == nil {
+				return false
+			}
+			v, err := item.GetValue()
+			if err != nil {
+				rowref := item.getAttribute("rowref")
+				if rowref == nil {
+					return false
+				}
+				v = *rowref
+			}
+			value = v
+		}
+
+		if !condition.pattern.MatchString(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the string representation of the filter.
+func (filter documentRowFilter) String() string {
+
+	builder := strings.Builder{}
+	builder.WriteString("[")
+	for i, condition := range filter {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(condition.column)
+		builder.WriteString("=")
+		builder.WriteString(quote(condition.value))
+	}
+	builder.WriteString("]")
+
+	return builder.String()
+}
+
+// isRowSelector checks whether the token selects rows in a table.
+func (token documentSettingPathToken) isRowSelector() bool {
+	return token.row != nil || token.anyRow || token.filter != nil
+}
+
+// selectsRow checks whether the token selects the specified row at the specified index.
+func (token documentSettingPathToken) selectsRow(index int, row *documentTableRow) bool {
+
+	if token.row != nil {
+		return *token.row == index
+	}
+
+	if token.filter != nil {
+		return token.filter.matches(row)
+	}
+
+	return token.anyRow
+}
+
 // String returns the string representation of the token.
 func (token documentSettingPathToken) String() string {
 
@@ -75,6 +293,14 @@ func (token documentSettingPathToken) String() string {
 		return fmt.Sprintf("%d", *token.row)
 	}
 
+	if token.anyRow {
+		return "*"
+	}
+
+	if token.filter != nil {
+		return token.filter.String()
+	}
+
 	panic("Unhandled token type")
 }
 
@@ -83,7 +309,7 @@ func (path documentSettingPath) String() string {
 
 	builder := strings.Builder{}
 	for i, token := range path {
-		if i > 0 {
+		if i > 0 && token.filter == nil {
 			builder.WriteString(".")
 		}
 		builder.WriteString(token.String())
@@ -91,3 +317,68 @@ func (path documentSettingPath) String() string {
 
 	return builder.String()
 }
+
+// isQuery checks whether the path contains wildcards or query expressions, so it may select multiple settings.
+func (path documentSettingPath) isQuery() bool {
+
+	for _, token := range path {
+		if token.anyRow || token.filter != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches checks whether the specified path is matched by the current path.
+// A wildcard in the current path matches any row index in the specified path. A query expression in the current
+// path matches a row index in the specified path, if the row the index refers to meets the conditions.
+func (path documentSettingPath) Matches(other documentSettingPath) bool {
+
+	if len(path) != len(other) {
+		return false
+	}
+
+	for i, token := range path {
+		otherToken := other[i]
+		if token.name != nil {
+			if otherToken.name == nil || *token.name != *otherToken.name {
+				return false
+			}
+		} else if token.row != nil {
+			if otherToken.row == nil || *token.row != *otherToken.row {
+				return false
+			}
+		} else if token.anyRow {
+			if !otherToken.isRowSelector() {
+				return false
+			}
+		} else if token.filter != nil {
+			if otherToken.filter != nil {
+				if token.filter.String() != otherToken.filter.String() {
+					return false
+				}
+			} else if otherToken.row == nil || otherToken.tableRow == nil || !token.filter.matches(otherToken.tableRow) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// withRow returns a copy of the current path with the specified row index appended.
+// The row itself is kept along with the index to match query expressions.
+func (path documentSettingPath) withRow(index int, row *documentTableRow) documentSettingPath {
+	result := make(documentSettingPath, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, documentSettingPathToken{row: &index, tableRow: row})
+}
+
+// withName returns a copy of the current path with the last token replaced by the specified setting name.
+func (path documentSettingPath) withName(name string) documentSettingPath {
+	result := make(documentSettingPath, len(path))
+	copy(result, path)
+	result[len(result)-1] = documentSettingPathToken{name: &name}
+	return result
+}
@@ -35,7 +35,12 @@ func (value *documentSimpleValue) GetRowIDs() []RowID {
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
 func (value *documentSimpleValue) WriteDocumentPart(writer *strings.Builder, indent int) error {
-	line := fmt.Sprintf("%s\n", quote(value.Value))
+	return value.writeValue(writer, indent, "\n", true)
+}
+
+// writeValue writes the value to the specified writer terminating the line with the specified line end.
+func (value *documentSimpleValue) writeValue(writer *strings.Builder, indent int, lineEnd string, withComments bool) error {
+	line := fmt.Sprintf("%s%s", quote(value.Value), lineEnd)
 	_, err := writer.WriteString(line)
 	return err
 }
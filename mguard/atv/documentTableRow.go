@@ -9,9 +9,12 @@ import (
 
 // documentTableRow represents a table row in an ATV document.
 type documentTableRow struct {
-	Pos   lexer.Position
-	RowID *RowID             `"{" ( "{" "rid" "=" @String "}" )?`
-	Items []*documentSetting `@@* "}"`
+	Pos         lexer.Position
+	RowID       *RowID             `"{" ( "{" "rid" "=" @String "}" )?`
+	Items       []*documentSetting `@@* "}"`
+	comments    *documentComments
+	ridComments *documentComments
+	closing     *documentComments
 }
 
 // Dupe returns a deep copy of the table row.
@@ -27,8 +30,11 @@ func (row *documentTableRow) Dupe() *documentTableRow {
 	}
 
 	return &documentTableRow{
-		RowID: row.RowID,
-		Items: itemsCopy,
+		RowID:       row.RowID,
+		Items:       itemsCopy,
+		comments:    row.comments,
+		ridComments: row.ridComments,
+		closing:     row.closing,
 	}
 }
 
@@ -75,7 +81,28 @@ func (row *documentTableRow) HasID() bool {
 
 // HasSameID checks whether the current row and the specified one has the same row id.
 func (row *documentTableRow) HasSameID(other *documentTableRow) bool {
-	return row != nil && other != nil && row.RowID != nil && other.RowID != nil && row.RowID == other.RowID
+	return row != nil && other != nil && row.RowID != nil && other.RowID != nil && *row.RowID == *other.RowID
+}
+
+// getItem returns the setting with the specified name in the row.
+// If the setting does not exist, nil is returned.
+func (row *documentTableRow) getItem(name string) *documentSetting {
+	for _, item := range row.Items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// removeItem removes the specified setting from the row.
+func (row *documentTableRow) removeItem(item *documentSetting) {
+	for i, existing := range row.Items {
+		if existing == item {
+			row.Items = append(row.Items[:i], row.Items[i+1:]...)
+			return
+		}
+	}
 }
 
 // SetSimpleValueByName replaces the setting with the specified name with a simple value with the specified string.
@@ -88,6 +115,7 @@ func (row *documentTableRow) SetSimpleValueByName(name string, value string) err
 		if item.Name == name {
 			item.ClearValue()
 			item.SimpleValue = newItem
+			return nil
 		}
 	}
 
@@ -105,15 +133,23 @@ func (row *documentTableRow) String() string {
 	}
 
 	builder := strings.Builder{}
-	row.WriteDocumentPart(&builder, 0)
+	row.writePart(&builder, 0, false)
 	return strings.TrimSpace(builder.String())
 }
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
 func (row *documentTableRow) WriteDocumentPart(writer *strings.Builder, indent int) error {
+	return row.writePart(writer, indent, true)
+}
+
+// writePart writes the table row to the specified writer, optionally along with the attached comments.
+func (row *documentTableRow) writePart(writer *strings.Builder, indent int, withComments bool) error {
 
 	// write opening brace of the table row
-	line := fmt.Sprintf("%s{\n", spacer(indent))
+	if withComments {
+		row.comments.writeLeading(writer)
+	}
+	line := fmt.Sprintf("%s{%s", spacer(indent), row.comments.lineEnd(withComments))
 	_, err := writer.WriteString(line)
 	if err != nil {
 		return err
@@ -121,7 +157,10 @@ func (row *documentTableRow) WriteDocumentPart(writer *strings.Builder, indent i
 
 	// write row id, if available
 	if row.RowID != nil {
-		line := fmt.Sprintf("%s{ rid = \"%s\" }\n", spacer(indent+1), *row.RowID)
+		if withComments {
+			row.ridComments.writeLeading(writer)
+		}
+		line := fmt.Sprintf("%s{ rid = %s }%s", spacer(indent+1), quote(string(*row.RowID)), row.ridComments.lineEnd(withComments))
 		_, err := writer.WriteString(line)
 		if err != nil {
 			return err
@@ -130,14 +169,17 @@ func (row *documentTableRow) WriteDocumentPart(writer *strings.Builder, indent i
 
 	// write settings in the table row
 	for _, item := range row.Items {
-		err = item.WriteDocumentPart(writer, indent+1)
+		err = item.writePart(writer, indent+1, withComments)
 		if err != nil {
 			return err
 		}
 	}
 
 	// write closing brace of the table row
-	line = fmt.Sprintf("%s}\n", spacer(indent))
+	if withComments {
+		row.closing.writeLeading(writer)
+	}
+	line = fmt.Sprintf("%s}%s", spacer(indent), row.closing.lineEnd(withComments))
 	_, err = writer.WriteString(line)
 	if err != nil {
 		return err
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/alecthomas/participle/lexer"
+	log "github.com/sirupsen/logrus"
 )
 
 // documentTableValue represents a table value in an ATV document.
@@ -12,6 +13,7 @@ type documentTableValue struct {
 	Pos        lexer.Position
 	Attributes dictionary          `"{" @@*`
 	Rows       []*documentTableRow `@@* "}"`
+	closing    *documentComments
 }
 
 // Dupe returns a deep copy of the table value.
@@ -27,16 +29,98 @@ func (table *documentTableValue) Dupe() *documentTableValue {
 	}
 
 	return &documentTableValue{
-		Attributes: table.Attributes,
+		Attributes: append(dictionary(nil), table.Attributes...),
 		Rows:       rowsCopy,
+		closing:    table.closing,
 	}
 }
 
+// mergeRows replaces existing rows with the same row id and appends rows that do not exist in the table, yet.
+// The name of the table is used for logging purposes only.
+func (table *documentTableValue) mergeRows(name string, rows []*documentTableRow) {
+
+update_loop:
+	for _, rowToSet := range rows {
+
+		// update existing row, if possible
+		for i, existingRow := range table.Rows {
+			if existingRow.HasSameID(rowToSet) {
+				x := existingRow.String()
+				y := rowToSet.String()
+				log.Debugf("Table value '%s' contains row with id '%s'. Row changed\n--from--\n%s\n--to--\n%s", name, *rowToSet.RowID, x, y)
+				table.Rows[i] = rowToSet
+				continue update_loop
+			}
+		}
+
+		// insert row, if there is no row with the same id, yet
+		log.Debugf("Table value '%s' does not contain row to set, yet. Appending\n%s", name, rowToSet.String())
+		table.Rows = append(table.Rows, rowToSet)
+	}
+}
+
+// unionRowsByColumn appends the specified rows, if the table does not contain a row with the same value in the
+// specified column, yet. Rows that do not contain the column are always appended.
+// The name of the table is used for logging purposes only.
+func (table *documentTableValue) unionRowsByColumn(name string, column string, rows []*documentTableRow) {
+
+union_loop:
+	for _, rowToAdd := range rows {
+
+		// skip row, if the table contains a row with the same value in the column
+		item := rowToAdd.getItem(column)
+		if item != nil {
+			value := item.String()
+			for _, existingRow := range table.Rows {
+				existingItem := existingRow.getItem(column)
+				if existingItem != nil && existingItem.String() == value {
+					log.Debugf("Table value '%s' contains row with the same value in column '%s' already. Skipping\n%s", name, column, rowToAdd.String())
+					continue union_loop
+				}
+			}
+		}
+
+		// append row
+		log.Debugf("Table value '%s' does not contain row to add, yet. Appending\n%s", name, rowToAdd.String())
+		table.Rows = append(table.Rows, rowToAdd)
+	}
+}
+
+// findCorrespondingRow returns the index of the row that corresponds to the specified row of another table.
+// If the specified row has a row id, the row with the same row id is the corresponding row. If the specified
+// row does not have a row id, the row at the specified index is the corresponding row, if it does not have a
+// row id either. If there is no corresponding row, -1 is returned.
+func (table *documentTableValue) findCorrespondingRow(row *documentTableRow, index int) int {
+
+	if row.HasID() {
+		for i, existingRow := range table.Rows {
+			if existingRow.HasSameID(row) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if index < len(table.Rows) && !table.Rows[index].HasID() {
+		return index
+	}
+
+	return -1
+}
+
 // GetRowReferences returns all row references recursively.
 func (table *documentTableValue) GetRowReferences() []RowRef {
 
 	if table != nil {
 		var allRowRefs []RowRef
+
+		// the parser cannot distinguish a value with metadata from a table without rows
+		// => a row reference may be stored in the attributes
+		var rowref string
+		if table.Attributes.TryGet("rowref", &rowref) {
+			allRowRefs = append(allRowRefs, RowRef(rowref))
+		}
+
 		for _, row := range table.Rows {
 			allRowRefs = append(allRowRefs, row.GetRowReferences()...)
 		}
@@ -60,29 +144,35 @@ func (table *documentTableValue) GetRowIDs() []RowID {
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
 func (table *documentTableValue) WriteDocumentPart(writer *strings.Builder, indent int) error {
+	return table.writeValue(writer, indent, "\n", true)
+}
+
+// writeValue writes the value to the specified writer terminating the line of the opening brace with the
+// specified line end.
+func (table *documentTableValue) writeValue(writer *strings.Builder, indent int, lineEnd string, withComments bool) error {
 
 	// write opening brace for table
-	_, err := writer.WriteString("{\n")
+	_, err := writer.WriteString("{" + lineEnd)
 	if err != nil {
 		return err
 	}
 
 	// write key-value-pairs forming the attributes
-	for _, item := range table.Attributes {
-		line := fmt.Sprintf("%s%s = \"%s\"\n", spacer(indent+1), item.Key, item.Value)
-		_, err := writer.WriteString(line)
-		if err != nil {
-			return err
-		}
-	}
+	table.Attributes.writeDocumentPart(writer, indent+1, withComments)
 
 	// write table rows
 	for _, row := range table.Rows {
-		row.WriteDocumentPart(writer, indent+1)
+		err := row.writePart(writer, indent+1, withComments)
+		if err != nil {
+			return err
+		}
 	}
 
 	// write closing brace of the table
-	line := fmt.Sprintf("%s}\n", spacer(indent))
+	if withComments {
+		table.closing.writeLeading(writer)
+	}
+	line := fmt.Sprintf("%s}%s", spacer(indent), table.closing.lineEnd(withComments))
 	_, err = writer.WriteString(line)
 	return err
 }
@@ -95,6 +185,6 @@ func (table *documentTableValue) String() string {
 	}
 
 	builder := strings.Builder{}
-	table.WriteDocumentPart(&builder, 0)
+	table.writeValue(&builder, 0, "\n", false)
 	return strings.TrimSpace(builder.String())
 }
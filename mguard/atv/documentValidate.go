@@ -0,0 +1,114 @@
+package atv
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// documentValidator collects information about an ATV document while walking it for validation.
+type documentValidator struct {
+	rowIDs   map[RowID]string       // maps row ids to the path of the first row having the id
+	rowRefs  []documentRowRefSource // row references found in the document
+	findings ValidationFindings     // findings collected so far
+}
+
+// documentRowRefSource describes a setting containing a row reference.
+type documentRowRefSource struct {
+	path     string
+	position lexer.Position
+	rowRef   RowRef
+}
+
+// Validate checks the referential integrity of the document, i.e. it reports row references that do not refer to
+// an existing row, row ids that are used multiple times and rows without a row id in tables that use row ids.
+func (doc *document) Validate() ValidationFindings {
+
+	validator := documentValidator{rowIDs: map[RowID]string{}}
+	for _, node := range doc.Nodes {
+		if node.Setting != nil {
+			validator.visitSetting(node.Setting.Name, node.Setting)
+		}
+	}
+
+	// check whether row references refer to existing rows
+	// (row ids are unique in the entire document, so rows can be looked up without taking the table into account)
+	for _, source := range validator.rowRefs {
+		if _, exists := validator.rowIDs[RowID(source.rowRef)]; !exists {
+			validator.add(
+				ValidationError, DanglingRowReference, source.path, source.position,
+				"Row reference '%s' does not refer to an existing row", source.rowRef)
+		}
+	}
+
+	return validator.findings
+}
+
+// visitSetting collects row ids and row references of the specified setting recursively.
+func (validator *documentValidator) visitSetting(path string, setting *documentSetting) {
+
+	// collect row reference, if the setting is a row reference
+	// (the parser cannot distinguish a value with metadata from a table without rows)
+	var rowRef string
+	if setting.ValueWithMetadata != nil && setting.ValueWithMetadata.Data.TryGet("rowref", &rowRef) ||
+		setting.TableValue != nil && setting.TableValue.Attributes.TryGet("rowref", &rowRef) {
+		validator.rowRefs = append(validator.rowRefs, documentRowRefSource{
+			path:     path,
+			position: setting.Pos,
+			rowRef:   RowRef(rowRef),
+		})
+	}
+
+	if setting.TableValue == nil {
+		return
+	}
+
+	// determine whether the table uses row ids
+	tableUsesRowIDs := false
+	for _, row := range setting.TableValue.Rows {
+		if row.HasID() {
+			tableUsesRowIDs = true
+			break
+		}
+	}
+
+	for i, row := range setting.TableValue.Rows {
+
+		rowPath := fmt.Sprintf("%s.%d", path, i)
+		if row.HasID() {
+			if otherPath, exists := validator.rowIDs[*row.RowID]; exists {
+				validator.add(
+					ValidationError, DuplicateRowID, rowPath, row.Pos,
+					"Row id '%s' is used by row '%s' already", *row.RowID, otherPath)
+			} else {
+				validator.rowIDs[*row.RowID] = rowPath
+			}
+		} else if tableUsesRowIDs {
+			validator.add(
+				ValidationWarning, MissingRowID, rowPath, row.Pos,
+				"Row does not have a row id, but other rows of the table have")
+		}
+
+		for _, item := range row.Items {
+			validator.visitSetting(rowPath+"."+item.Name, item)
+		}
+	}
+}
+
+// add adds a finding to the validator.
+func (validator *documentValidator) add(
+	severity ValidationSeverity,
+	kind ValidationFindingKind,
+	path string,
+	position lexer.Position,
+	format string,
+	args ...interface{}) {
+
+	validator.findings = append(validator.findings, ValidationFinding{
+		Severity: severity,
+		Kind:     kind,
+		Path:     path,
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
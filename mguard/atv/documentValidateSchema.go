@@ -0,0 +1,97 @@
+package atv
+
+import (
+	"fmt"
+)
+
+// ValidateSchema checks the settings of the document against the specified schema, i.e. it reports settings that
+// do not exist in the firmware version the schema describes and values that do not match the type or the allowed
+// values of the settings. Settings that are not known to the schema are reported as unknown, if the schema of the table
+// they are in lists all columns, and as unchecked otherwise (warnings).
+func (doc *document) ValidateSchema(schema *Schema) ValidationFindings {
+
+	validator := documentValidator{}
+	for _, node := range doc.Nodes {
+		if node.Setting != nil {
+			name := node.Setting.Name
+			validator.visitSettingWithSchema(
+				name, node.Setting, schema.Version,
+				findSettingSchema(schema.Settings, name),
+				findSettingSchema(schema.removed, name),
+				false)
+		}
+	}
+
+	return validator.findings
+}
+
+// visitSettingWithSchema checks the specified setting against the specified setting schema recursively.
+// The schema of the setting (if known) and the schema of the setting in other versions (if known) are passed along
+// with a flag indicating whether the schema of the enclosing table lists all columns.
+func (validator *documentValidator) visitSettingWithSchema(
+	path string,
+	setting *documentSetting,
+	version Version,
+	schema *SettingSchema,
+	removedSchema *SettingSchema,
+	complete bool) {
+
+	// check whether the setting exists in the version
+	if schema == nil {
+		if removedSchema != nil {
+			validator.add(
+				ValidationError, UnsupportedSetting, path, setting.Pos,
+				"The setting does not exist in version %s", version)
+		} else if complete {
+			validator.add(
+				ValidationWarning, UnknownSetting, path, setting.Pos,
+				"The setting is not known in version %s", version)
+		} else {
+			validator.add(
+				ValidationWarning, UncheckedSetting, path, setting.Pos,
+				"The setting is not known to the schema of version %s, it was not checked", version)
+		}
+		return
+	}
+
+	switch schema.Type {
+
+	case TableType:
+		if !setting.isTable() {
+			validator.add(
+				ValidationError, InvalidValue, path, setting.Pos,
+				"The setting must be a table")
+			return
+		}
+		for i, row := range setting.TableValue.Rows {
+			for _, item := range row.Items {
+				validator.visitSettingWithSchema(
+					fmt.Sprintf("%s.%d.%s", path, i, item.Name), item, version,
+					findSettingSchema(schema.Columns, item.Name),
+					findSettingSchema(schema.removed, item.Name),
+					schema.Complete)
+			}
+		}
+
+	case RowReferenceType:
+		if setting.isTable() {
+			validator.add(
+				ValidationError, InvalidValue, path, setting.Pos,
+				"The setting must refer to a table row, but it is a table")
+		}
+
+	default:
+		value, err := setting.GetValue()
+		if err != nil {
+			validator.add(
+				ValidationError, InvalidValue, path, setting.Pos,
+				"The setting must have a simple value (%s)", schema.Type)
+			return
+		}
+		if err := schema.CheckValue(value); err != nil {
+			validator.add(
+				ValidationError, InvalidValue, path, setting.Pos,
+				"%s", err)
+		}
+	}
+}
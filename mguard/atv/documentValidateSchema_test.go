@@ -0,0 +1,85 @@
+package atv
+
+import (
+	"testing"
+)
+
+func TestValidateReportsUncheckedSettings(t *testing.T) {
+
+	file := fileFromString(t, `#version 8.8.1.default
+
+MY_HOSTNAME = "mguard"
+MY_HOSTNAEM = "typo"
+VPN_CONNECTION = {
+  {
+    NAME = "plant-a"
+    VPN_STRAT = "started"
+    TUNNEL = {
+      {
+        LOCAL = "192.168.1.0/24"
+        LOCAL_N_TO_N_NAT = {
+          {
+            FROM_NET = "10.1.1.0"
+            MASK = "24"
+            TO_NET = "192.168.1.0"
+            TO_NTE = "192.168.1.0"
+          }
+        }
+      }
+    }
+  }
+}
+`)
+
+	version, _ := ParseVersion("8.8.1")
+	findings, err := file.Validate(version)
+	if err != nil {
+		t.Fatalf("Validating failed: %v", err)
+	}
+
+	if findings.HasErrors() {
+		t.Errorf("Validating reported errors unexpectedly: %v", findings.Errors())
+	}
+
+	expected := map[string]ValidationFindingKind{
+		"MY_HOSTNAEM":                UncheckedSetting,
+		"VPN_CONNECTION.0.VPN_STRAT": UncheckedSetting,
+		"VPN_CONNECTION.0.TUNNEL.0.LOCAL_N_TO_N_NAT.0.TO_NTE": UnknownSetting,
+	}
+
+	if len(findings) != len(expected) {
+		t.Errorf("Unexpected number of findings (expected: %d, got: %d): %v", len(expected), len(findings), findings)
+	}
+
+	for _, finding := range findings {
+		kind, ok := expected[finding.Path]
+		if !ok {
+			t.Errorf("Unexpected finding: %s", finding)
+			continue
+		}
+		if finding.Kind != kind || finding.Severity != ValidationWarning {
+			t.Errorf("Unexpected finding for '%s' (expected: warning, %s): %s (%s)", finding.Path, kind, finding, finding.Kind)
+		}
+	}
+
+	if unchecked := findings.OfKind(UncheckedSetting); len(unchecked) != 2 {
+		t.Errorf("Unexpected number of unchecked settings (expected: 2, got: %d): %v", len(unchecked), unchecked)
+	}
+}
+
+func TestSchemaNumSettings(t *testing.T) {
+
+	schema := Schema{Settings: []*SettingSchema{
+		{Name: "A", Type: StringType},
+		{Name: "T", Type: TableType, Columns: []*SettingSchema{
+			{Name: "B", Type: StringType},
+			{Name: "U", Type: TableType, Columns: []*SettingSchema{
+				{Name: "C", Type: StringType},
+			}},
+		}},
+	}}
+
+	if schema.NumSettings() != 5 {
+		t.Errorf("Expected 5 settings, got %d", schema.NumSettings())
+	}
+}
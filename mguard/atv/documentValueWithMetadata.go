@@ -9,8 +9,9 @@ import (
 
 // documentValueWithMetadata represents a value with some metadata attached to it in an ATV document.
 type documentValueWithMetadata struct {
-	Pos  lexer.Position
-	Data dictionary `"{" @@* "}"`
+	Pos     lexer.Position
+	Data    dictionary `"{" @@* "}"`
+	closing *documentComments
 }
 
 // Dupe returns a copy of the value.
@@ -21,7 +22,8 @@ func (value *documentValueWithMetadata) Dupe() *documentValueWithMetadata {
 	}
 
 	return &documentValueWithMetadata{
-		Data: value.Data,
+		Data:    append(dictionary(nil), value.Data...),
+		closing: value.closing,
 	}
 }
 
@@ -45,24 +47,27 @@ func (value *documentValueWithMetadata) GetRowIDs() []RowID {
 
 // WriteDocumentPart writes a part of the ATV document to the specified writer.
 func (value *documentValueWithMetadata) WriteDocumentPart(writer *strings.Builder, indent int) error {
+	return value.writeValue(writer, indent, "\n", true)
+}
+
+// writeValue writes the value to the specified writer terminating the line of the opening brace with the
+// specified line end.
+func (value *documentValueWithMetadata) writeValue(writer *strings.Builder, indent int, lineEnd string, withComments bool) error {
 
 	// write opening brace of the complex type
-	_, err := writer.WriteString("{\n")
+	_, err := writer.WriteString("{" + lineEnd)
 	if err != nil {
 		return err
 	}
 
 	// write key-value-pairs forming the value
-	for _, item := range value.Data {
-		line := fmt.Sprintf("%s%s = \"%s\"\n", spacer(indent+1), item.Key, item.Value)
-		_, err := writer.WriteString(line)
-		if err != nil {
-			return err
-		}
-	}
+	value.Data.writeDocumentPart(writer, indent+1, withComments)
 
 	// write closing brace of the complex type
-	line := fmt.Sprintf("%s}\n", spacer(indent))
+	if withComments {
+		value.closing.writeLeading(writer)
+	}
+	line := fmt.Sprintf("%s}%s", spacer(indent), value.closing.lineEnd(withComments))
 	_, err = writer.WriteString(line)
 	if err != nil {
 		return err
@@ -74,6 +79,6 @@ func (value *documentValueWithMetadata) WriteDocumentPart(writer *strings.Builde
 // String returns the complex value as a string.
 func (value *documentValueWithMetadata) String() string {
 	builder := strings.Builder{}
-	value.WriteDocumentPart(&builder, 0)
+	value.writeValue(&builder, 0, "\n", false)
 	return strings.TrimSpace(builder.String())
 }
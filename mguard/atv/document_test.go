@@ -0,0 +1,243 @@
+package atv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fileFromString parses the specified ATV document or fails the test.
+func fileFromString(t *testing.T, s string) *File {
+	t.Helper()
+	file, err := FromReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Parsing ATV document failed: %v", err)
+	}
+	return file
+}
+
+// mergeConfigurationFromString loads the specified merge configuration (YAML format) or fails the test.
+func mergeConfigurationFromString(t *testing.T, s string) *MergeConfiguration {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mguard-config-tool-test")
+	if err != nil {
+		t.Fatalf("Creating the temporary directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "merge.yaml")
+	if err := ioutil.WriteFile(path, []byte(s), 0600); err != nil {
+		t.Fatalf("Writing the merge configuration failed: %v", err)
+	}
+	config, err := LoadMergeConfiguration(path)
+	if err != nil {
+		t.Fatalf("Loading the merge configuration failed: %v", err)
+	}
+	return config
+}
+
+// expectSettingValues checks the values of the settings selected by the specified path.
+func expectSettingValues(t *testing.T, file *File, path string, expected ...string) {
+	t.Helper()
+	values, err := file.GetSettingValues(path)
+	if err != nil {
+		t.Fatalf("Getting the values of '%s' failed: %v", path, err)
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Unexpected values of '%s'\nexpected: %q\ngot:      %q", path, expected, values)
+	}
+}
+
+func TestMergeSelectivelyKeepsNestedExcludes(t *testing.T) {
+
+	base := fileFromString(t, `#version 8.8.1.default
+
+ROOT_PASSWORD = "basepassword"
+ACCESS_FTP = "no"
+VPN_CONNECTION = {
+  {
+    { rid = "rid-a" }
+    NAME = "plant-a"
+    PSK = "basepsk"
+    VPN_START = "stopped"
+  }
+  {
+    { rid = "rid-b" }
+    NAME = "plant-b"
+    PSK = "basepsk-b"
+    VPN_START = "stopped"
+  }
+}
+`)
+
+	other := fileFromString(t, `#version 8.8.1.default
+
+ROOT_PASSWORD = "otherpassword"
+ACCESS_FTP = "yes"
+VPN_CONNECTION = {
+  {
+    { rid = "rid-a" }
+    NAME = "plant-a"
+    PSK = "otherpsk"
+    VPN_START = "started"
+  }
+  {
+    { rid = "rid-c" }
+    NAME = "plant-c"
+    PSK = "otherpsk-c"
+    VPN_START = "started"
+  }
+}
+`)
+
+	config := mergeConfigurationFromString(t, `settings:
+  - '*'
+  - '!ROOT_PASSWORD'
+  - '!VPN_CONNECTION.*.PSK'
+`)
+
+	merged, _, err := base.MergeSelectively(other, config)
+	if err != nil {
+		t.Fatalf("Merging failed: %v", err)
+	}
+
+	expectSettingValues(t, merged, "ROOT_PASSWORD", "basepassword")
+	expectSettingValues(t, merged, "ACCESS_FTP", "yes")
+
+	// the row with the same row id is replaced, but its PSK is kept, the other base row is kept as well,
+	// the new row is added without its PSK
+	expectSettingValues(t, merged, "VPN_CONNECTION.*.NAME", "plant-a", "plant-b", "plant-c")
+	expectSettingValues(t, merged, "VPN_CONNECTION.*.VPN_START", "started", "stopped", "started")
+	expectSettingValues(t, merged, "VPN_CONNECTION.*.PSK", "basepsk", "basepsk-b")
+}
+
+func TestMergeSelectivelyKeepsExcludedRowsWhenReplacingTable(t *testing.T) {
+
+	base := fileFromString(t, `#version 8.8.1.default
+
+VPN_CONNECTION = {
+  {
+    NAME = "plant-a"
+    PSK = "basepsk"
+  }
+  {
+    NAME = "plant-b"
+    PSK = "basepsk-b"
+  }
+}
+`)
+
+	other := fileFromString(t, `#version 8.8.1.default
+
+VPN_CONNECTION = {
+  {
+    NAME = "plant-x"
+    PSK = "otherpsk"
+  }
+  {
+    NAME = "plant-y"
+    PSK = "otherpsk-y"
+  }
+}
+`)
+
+	config := mergeConfigurationFromString(t, `settings:
+  - path: VPN_CONNECTION
+    strategy: replace
+  - '!VPN_CONNECTION.1'
+  - '!VPN_CONNECTION.*.PSK'
+`)
+
+	merged, _, err := base.MergeSelectively(other, config)
+	if err != nil {
+		t.Fatalf("Merging failed: %v", err)
+	}
+
+	expectSettingValues(t, merged, "VPN_CONNECTION.*.NAME", "plant-x", "plant-b")
+	expectSettingValues(t, merged, "VPN_CONNECTION.*.PSK", "basepsk", "basepsk-b")
+}
+
+func TestDiffAddressesRowsConsistently(t *testing.T) {
+
+	first := fileFromString(t, `#version 8.8.1.default
+FW_INCOMING = {
+  {
+    { rid = "a" }
+    COMMENT = "first"
+  }
+  {
+    { rid = "b" }
+    COMMENT = "second"
+  }
+}
+VPN_CONNECTION = {
+  {
+    NAME = "plant-a"
+  }
+  {
+    NAME = "plant-b"
+  }
+}
+`)
+
+	second := fileFromString(t, `#version 8.8.1.default
+FW_INCOMING = {
+  {
+    { rid = "c" }
+    COMMENT = "new"
+  }
+  {
+    { rid = "b" }
+    COMMENT = "changed"
+  }
+}
+VPN_CONNECTION = {
+  {
+    NAME = "plant-c"
+  }
+}
+`)
+
+	diffs, err := first.Diff(second)
+	if err != nil {
+		t.Fatalf("Comparing the documents failed: %v", err)
+	}
+
+	var actual []string
+	for _, diff := range diffs {
+		actual = append(actual, fmt.Sprintf("%s %s", diff.Kind, diff.Path))
+	}
+
+	expected := []string{
+		`removed FW_INCOMING[rid="a"]`,
+		`changed FW_INCOMING[rid="b"].COMMENT`,
+		`added FW_INCOMING[rid="c"]`,
+		`changed VPN_CONNECTION.0.NAME`,
+		`removed VPN_CONNECTION.1`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected differences\nexpected: %v\nactual:   %v", expected, actual)
+	}
+
+	// the paths must select the rows in the documents
+	values, err := second.GetSettingValues(`FW_INCOMING[rid="b"].COMMENT`)
+	if err != nil || !reflect.DeepEqual(values, []string{"changed"}) {
+		t.Errorf("The path of the changed setting does not select the setting (values: %v, error: %v)", values, err)
+	}
+	rows, err := second.FindRows(`FW_INCOMING[rid="c"]`)
+	if err != nil || len(rows) != 1 {
+		t.Errorf("The path of the added row does not select the row (rows: %d, error: %v)", len(rows), err)
+	}
+}
+
+func TestDiffValueReportsUnknownValueTypes(t *testing.T) {
+
+	setting := &documentSetting{Name: "BROKEN"}
+	_, err := setting.diffValue("BROKEN")
+	if err == nil {
+		t.Fatalf("Getting the value of a setting without value succeeded unexpectedly")
+	}
+}
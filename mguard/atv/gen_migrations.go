@@ -0,0 +1,71 @@
+// +build ignore
+
+// gen_migrations generates migrationRulesData.go from the migration rules in the 'migrations' directory
+// (one YAML file per migration step). Run 'go generate' in the package directory after changing the rules.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const header = `// Code generated by gen_migrations.go from the files in the 'migrations' directory; DO NOT EDIT.
+
+package atv
+
+// migrationRulesFiles contains the bundled migration rules (one YAML file per migration step, see migrations/README.md).
+var migrationRulesFiles = []migrationRulesFile{
+`
+
+func main() {
+
+	paths, err := filepath.Glob(filepath.Join("migrations", "*.yaml"))
+	if err != nil {
+		fail(err)
+	}
+	sort.Strings(paths)
+
+	buffer := bytes.Buffer{}
+	buffer.WriteString(header)
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fail(err)
+		}
+		buffer.WriteString(fmt.Sprintf("{name: %q, data: %s},\n", filepath.Base(path), quote(string(data))))
+	}
+	buffer.WriteString("}\n")
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		fail(err)
+	}
+
+	err = ioutil.WriteFile("migrationRulesData.go", source, 0644)
+	if err != nil {
+		fail(err)
+	}
+}
+
+// quote returns a Go string literal with the specified string (a raw string literal, if possible).
+func quote(s string) string {
+
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+
+	return "`" + s + "`"
+}
+
+// fail prints the specified error and exits.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Generating migration rules failed: %s\n", err)
+	os.Exit(1)
+}
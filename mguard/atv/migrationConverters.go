@@ -0,0 +1,209 @@
+package atv
+
+import (
+	"fmt"
+	"net"
+)
+
+// migrationConverter converts settings that cannot be described by the generic migration rules.
+// Migration rules of type 'convert' refer to converters by name.
+type migrationConverter struct {
+	applyFunc  func(doc *document, step *MigrationStep) error
+	revertFunc func(doc *document, step *MigrationStep) error
+}
+
+// migrationConverters contains all converters migration rules can refer to.
+var migrationConverters = map[string]*migrationConverter{
+
+	// VPN_CONNECTION.x.TUNNEL.y.LOCAL_1TO1NAT <=> VPN_CONNECTION.x.TUNNEL.y.LOCAL_N_TO_N_NAT (8.0.2 => 8.1.0)
+	"local-1to1nat-to-n-to-n-nat": {
+		applyFunc:  convertLocal1To1Nat,
+		revertFunc: revertLocal1To1Nat,
+	},
+
+	// VPN_EXTERNAL_SWITCH_REF + VPN_RS_EXTERNAL_SWITCH_TYPE <=> VPN_CONNECTION.x.CONTROL + VPN_CONNECTION.x.CONTROL_INV +
+	// SERVICE_SWITCH1_TYPE (8.0.2 => 8.1.0)
+	"vpn-external-switch-to-service-switch": {
+		applyFunc:  convertVpnExternalSwitch,
+		revertFunc: revertVpnExternalSwitch,
+	},
+}
+
+func (converter *migrationConverter) apply(doc *document, step *MigrationStep) error {
+	return converter.applyFunc(doc, step)
+}
+
+func (converter *migrationConverter) revert(doc *document, step *MigrationStep) error {
+	return converter.revertFunc(doc, step)
+}
+
+var local1To1NatPath = mustParseMigrationRulePath("VPN_CONNECTION.*.TUNNEL.*.LOCAL_1TO1NAT")
+var localNToNNatPath = mustParseMigrationRulePath("VPN_CONNECTION.*.TUNNEL.*.LOCAL_N_TO_N_NAT")
+
+// convertLocal1To1Nat replaces VPN_CONNECTION.x.TUNNEL.y.LOCAL_1TO1NAT with a VPN_CONNECTION.x.TUNNEL.y.LOCAL_N_TO_N_NAT
+// table containing a single entry. The mask and the target network are taken from the local network of the tunnel.
+func convertLocal1To1Nat(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, local1To1NatPath) {
+
+		value, err := match.setting.GetValue()
+		if err != nil {
+			return fmt.Errorf("The setting '%s' is not a simple value", match.path)
+		}
+
+		natIP := net.ParseIP(value)
+		if natIP == nil {
+			return fmt.Errorf("The setting '%s' is not a valid IP address (%s)", match.path, value)
+		}
+
+		local := match.row.getItem("LOCAL")
+		if local == nil {
+			return fmt.Errorf("The setting '%s' cannot be migrated, the local network of the tunnel is missing", match.path)
+		}
+
+		localValue, _ := local.GetValue()
+		localIP, localNet, err := net.ParseCIDR(localValue)
+		if err != nil {
+			return fmt.Errorf("The setting '%s' cannot be migrated, the local network of the tunnel (%s) is invalid", match.path, localValue)
+		}
+
+		maskbits, _ := localNet.Mask.Size()
+		step.add(MigrationChange{Kind: ChangeConverted, Path: match.path, NewPath: match.path[:len(match.path)-len(match.setting.Name)] + "LOCAL_N_TO_N_NAT.0", OldValue: reportString(value)})
+		match.setting.Name = "LOCAL_N_TO_N_NAT"
+		match.setting.ClearValue()
+		match.setting.TableValue = &documentTableValue{
+			Rows: []*documentTableRow{
+				{
+					Items: []*documentSetting{
+						{Name: "COMMENT", SimpleValue: &documentSimpleValue{Value: ""}},
+						{Name: "FROM_NET", SimpleValue: &documentSimpleValue{Value: natIP.String()}},
+						{Name: "MASK", SimpleValue: &documentSimpleValue{Value: fmt.Sprintf("%d", maskbits)}},
+						{Name: "TO_NET", SimpleValue: &documentSimpleValue{Value: localIP.String()}},
+					},
+				},
+			},
+		}
+	}
+
+	return nil
+}
+
+// revertLocal1To1Nat replaces VPN_CONNECTION.x.TUNNEL.y.LOCAL_N_TO_N_NAT with VPN_CONNECTION.x.TUNNEL.y.LOCAL_1TO1NAT.
+// The older version supports a single 1:1 NAT only, so multiple NAT entries are removed.
+func revertLocal1To1Nat(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, localNToNNatPath) {
+
+		// no NAT configured
+		// => the older version does not need the setting at all
+		if match.setting.TableValue == nil || len(match.setting.TableValue.Rows) == 0 {
+			step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path})
+			match.remove(doc)
+			continue
+		}
+
+		// a single NAT entry can be represented in the older version
+		if len(match.setting.TableValue.Rows) == 1 {
+			if fromNet := match.setting.TableValue.Rows[0].getItem("FROM_NET"); fromNet != nil {
+				if value, err := fromNet.GetValue(); err == nil {
+					newPath := match.path[:len(match.path)-len(match.setting.Name)] + "LOCAL_1TO1NAT"
+					step.add(MigrationChange{Kind: ChangeConverted, Path: match.path, NewPath: newPath, NewValue: reportString(value)})
+					match.setting.Name = "LOCAL_1TO1NAT"
+					match.setting.ClearValue()
+					match.setting.SimpleValue = &documentSimpleValue{Value: value}
+					continue
+				}
+			}
+		}
+
+		step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, Lost: true})
+		match.remove(doc)
+	}
+
+	return nil
+}
+
+var vpnExternalSwitchTypePath = mustParseMigrationRulePath("VPN_RS_EXTERNAL_SWITCH_TYPE")
+var serviceSwitch1TypePath = mustParseMigrationRulePath("SERVICE_SWITCH1_TYPE")
+
+// vpnExternalSwitchRefRule replaces the reference to the VPN connection controlled by the button/switch with columns in
+// the VPN connection (the older version supports only one input, which cannot be inverted).
+var vpnExternalSwitchRefRule = &convertRowReferenceRule{
+	path:  mustParseMigrationRulePath("VPN_EXTERNAL_SWITCH_REF"),
+	table: mustParseMigrationRulePath("VPN_CONNECTION"),
+	columns: []*migrationColumnDefinition{
+		{Name: "CONTROL", Value: "cmd1", Neutral: []string{"", "none"}},
+		{Name: "CONTROL_INV", Value: "no"},
+	},
+}
+
+// convertVpnExternalSwitch replaces VPN_EXTERNAL_SWITCH_REF with VPN_CONNECTION.x.CONTROL and
+// VPN_CONNECTION.x.CONTROL_INV in the referenced VPN connection and VPN_RS_EXTERNAL_SWITCH_TYPE with
+// SERVICE_SWITCH1_TYPE. The type of the input falls back to 'button', if VPN_RS_EXTERNAL_SWITCH_TYPE is not set.
+// Both settings are kept as they are, if no VPN connection is controlled by the button/switch.
+func convertVpnExternalSwitch(doc *document, step *MigrationStep) error {
+
+	// abort, if there is no VPN connection linked to the button/switch
+	ref, err := doc.getSetting(vpnExternalSwitchRefRule.path)
+	if err != nil || ref == nil || ref.getAttribute("rowref") == nil {
+		return err
+	}
+
+	// determine the type (button or switch) of the input
+	switchType, err := doc.getSetting(vpnExternalSwitchTypePath)
+	if err != nil {
+		return err
+	}
+	switchTypeValue := "button"
+	if switchType != nil {
+		switchTypeValue, err = switchType.GetValue()
+		if err != nil {
+			return fmt.Errorf("The setting '%s' is not a simple value", vpnExternalSwitchTypePath)
+		}
+	}
+
+	// set VPN_CONNECTION.x.CONTROL and VPN_CONNECTION.x.CONTROL_INV in the referenced VPN connection
+	// and remove VPN_EXTERNAL_SWITCH_REF
+	err = vpnExternalSwitchRefRule.apply(doc, step)
+	if err != nil {
+		return err
+	}
+
+	// replace VPN_RS_EXTERNAL_SWITCH_TYPE with SERVICE_SWITCH1_TYPE
+	err = doc.SetSetting(&documentSetting{
+		Name:        *serviceSwitch1TypePath[0].name,
+		SimpleValue: &documentSimpleValue{Value: switchTypeValue}})
+	if err != nil {
+		return err
+	}
+	if switchType == nil {
+		step.add(MigrationChange{Kind: ChangeAdded, Path: serviceSwitch1TypePath.String(), NewValue: reportString(switchTypeValue)})
+		return nil
+	}
+	step.add(MigrationChange{Kind: ChangeRenamed, Path: vpnExternalSwitchTypePath.String(), NewPath: serviceSwitch1TypePath.String()})
+	return doc.removeSetting(vpnExternalSwitchTypePath)
+}
+
+// revertVpnExternalSwitch replaces VPN_CONNECTION.x.CONTROL and VPN_CONNECTION.x.CONTROL_INV with
+// VPN_EXTERNAL_SWITCH_REF and SERVICE_SWITCH1_TYPE with VPN_RS_EXTERNAL_SWITCH_TYPE.
+func revertVpnExternalSwitch(doc *document, step *MigrationStep) error {
+
+	err := vpnExternalSwitchRefRule.revert(doc, step)
+	if err != nil {
+		return err
+	}
+
+	renameSettings(doc, step, serviceSwitch1TypePath, *vpnExternalSwitchTypePath[0].name)
+	return nil
+}
+
+// mustParseMigrationRulePath parses the specified path of a setting and panics, if that fails.
+func mustParseMigrationRulePath(s string) documentSettingPath {
+
+	path, err := parseMigrationRulePath(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return path
+}
@@ -3,5 +3,22 @@ package atv
 type migrationProvider interface {
 	FromVersion() Version
 	ToVersion() Version
-	Migrate(file *File) (*File, error)
+
+	// Migrate performs the migration and records the changes in the specified migration step.
+	Migrate(file *File, step *MigrationStep) (*File, error)
+}
+
+// reverseMigrationProvider is implemented by migrations that can migrate a document back to the version the migration
+// starts with (optional).
+type reverseMigrationProvider interface {
+	migrationProvider
+
+	// MigrateDown performs the reverse migration and records the changes in the specified migration step.
+	// Settings that cannot be represented in the older version are removed and recorded as lost.
+	MigrateDown(file *File, step *MigrationStep) (*File, error)
 }
+
+//go:generate go run gen_migrations.go
+
+// migrations contains all migrations (loaded from the bundled migration rules, see migrations/README.md).
+var migrations = mustNewMigrationRegistry(mustLoadMigrations(migrationRulesFiles))
@@ -0,0 +1,185 @@
+package atv
+
+import (
+	"fmt"
+	"sort"
+)
+
+// migrationRegistry keeps the migrations between firmware versions as a graph and works out the migrations that are
+// needed to get from one version to another. Versions are identified by their numbers only, i.e. a migration applies
+// to all variants (suffixes) of the versions it starts and ends with.
+type migrationRegistry struct {
+	versions []Version                              // versions migrations start or end with (ascending)
+	up       map[Version][]migrationProvider        // migrations by the version they start with
+	down     map[Version][]reverseMigrationProvider // reverse migrations by the version they end with
+	known    map[Version]bool                       // versions migrations start or end with
+}
+
+// migrationPathStep is a migration on the path from one version to another.
+type migrationPathStep struct {
+	migration migrationProvider
+	down      bool // true, if the migration is applied in reverse direction
+}
+
+// from returns the version the step starts with.
+func (step migrationPathStep) from() Version {
+	if step.down {
+		return step.migration.ToVersion()
+	}
+	return step.migration.FromVersion()
+}
+
+// to returns the version the step ends with.
+func (step migrationPathStep) to() Version {
+	if step.down {
+		return step.migration.FromVersion()
+	}
+	return step.migration.ToVersion()
+}
+
+// mustNewMigrationRegistry creates a registry containing the specified migrations and panics, if that fails.
+// It is used to register the bundled migrations, so a failure is a programming error.
+func mustNewMigrationRegistry(migrations []migrationProvider) *migrationRegistry {
+
+	registry, err := newMigrationRegistry(migrations)
+	if err != nil {
+		panic(fmt.Sprintf("Registering the bundled migrations failed: %s", err))
+	}
+
+	return registry
+}
+
+// newMigrationRegistry creates a registry containing the specified migrations.
+func newMigrationRegistry(migrations []migrationProvider) (*migrationRegistry, error) {
+
+	registry := &migrationRegistry{
+		up:    map[Version][]migrationProvider{},
+		down:  map[Version][]reverseMigrationProvider{},
+		known: map[Version]bool{},
+	}
+
+	for _, migration := range migrations {
+
+		from := migration.FromVersion().release()
+		to := migration.ToVersion().release()
+		if from.CompareRelease(to) >= 0 {
+			return nil, fmt.Errorf("The migration from version %s to version %s does not lead to a higher version", from, to)
+		}
+
+		for _, other := range registry.up[from] {
+			if other.ToVersion().CompareRelease(to) == 0 {
+				return nil, fmt.Errorf("The migration from version %s to version %s is registered multiple times", from, to)
+			}
+		}
+
+		registry.up[from] = append(registry.up[from], migration)
+		if reverseMigration, ok := migration.(reverseMigrationProvider); ok {
+			registry.down[to] = append(registry.down[to], reverseMigration)
+		}
+
+		for _, version := range []Version{migration.FromVersion(), migration.ToVersion()} {
+			if !registry.known[version.release()] {
+				registry.known[version.release()] = true
+				registry.versions = append(registry.versions, version)
+			}
+		}
+	}
+
+	sort.Slice(registry.versions, func(i, j int) bool {
+		return registry.versions[i].CompareRelease(registry.versions[j]) < 0
+	})
+
+	return registry, nil
+}
+
+// steps returns the migration steps leading away from the specified version in the specified direction.
+func (registry *migrationRegistry) steps(version Version, upwards bool) []migrationPathStep {
+
+	var steps []migrationPathStep
+	if upwards {
+		for _, migration := range registry.up[version.release()] {
+			steps = append(steps, migrationPathStep{migration: migration})
+		}
+	} else {
+		for _, migration := range registry.down[version.release()] {
+			steps = append(steps, migrationPathStep{migration: migration, down: true})
+		}
+	}
+
+	return steps
+}
+
+// path works out the migration steps leading from the specified version to the specified target version (the path
+// with the least number of steps). Suffixes are not taken into account, so no steps are returned, if the versions
+// differ in their suffix only. If there is no such path, the error names the missing migration step.
+func (registry *migrationRegistry) path(from Version, to Version) ([]migrationPathStep, error) {
+
+	source, target := from.release(), to.release()
+	if source == target {
+		return nil, nil
+	}
+
+	// search the graph breadth-first
+	// (steps leading beyond the target version are skipped)
+	upwards := source.CompareRelease(target) < 0
+	previous := map[Version]migrationPathStep{}
+	visited := map[Version]bool{source: true}
+	queue := []Version{source}
+	for len(queue) > 0 && !visited[target] {
+
+		version := queue[0]
+		queue = queue[1:]
+
+		for _, step := range registry.steps(version, upwards) {
+			next := step.to().release()
+			if upwards && next.CompareRelease(target) > 0 || !upwards && next.CompareRelease(target) < 0 {
+				continue
+			}
+			if !visited[next] {
+				visited[next] = true
+				previous[next] = step
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	if !visited[target] {
+		return nil, registry.missingStepError(from, to, visited, upwards)
+	}
+
+	// collect the steps leading to the target version
+	var path []migrationPathStep
+	for version := target; version != source; {
+		step := previous[version]
+		path = append([]migrationPathStep{step}, path...)
+		version = step.from().release()
+	}
+
+	return path, nil
+}
+
+// missingStepError returns an error naming the migration step that is missing to get from the specified version to
+// the specified target version. The step starts with the reachable version that is closest to the target version
+// and ends with the next known version in the direction of the target version (or the target version itself).
+func (registry *migrationRegistry) missingStepError(from Version, to Version, reachable map[Version]bool, upwards bool) error {
+
+	closest := from.release()
+	for version := range reachable {
+		if upwards && version.CompareRelease(closest) > 0 || !upwards && version.CompareRelease(closest) < 0 {
+			closest = version
+		}
+	}
+
+	next := to.release()
+	for _, version := range registry.versions {
+		version = version.release()
+		if upwards && version.CompareRelease(closest) > 0 && version.CompareRelease(next) < 0 ||
+			!upwards && version.CompareRelease(closest) < 0 && version.CompareRelease(next) > 0 {
+			next = version
+		}
+	}
+
+	return fmt.Errorf(
+		"Migrating from version %s to version %s is not possible, the migration from version %s to version %s is missing",
+		from, to, closest, next)
+}
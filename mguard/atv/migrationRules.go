@@ -0,0 +1,738 @@
+package atv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// migrationRulesFile is a file with the migration rules of a migration step (YAML, see migrations/README.md).
+type migrationRulesFile struct {
+	name string // name of the file (for error messages)
+	data string // content of the file
+}
+
+// migrationStepDefinition describes the migration from one firmware version to the next one (the root of a migration
+// rules file). Steps without rules just change the version of the document.
+type migrationStepDefinition struct {
+	From  string                     `yaml:"from"`
+	To    string                     `yaml:"to"`
+	Rules []*migrationRuleDefinition `yaml:"rules"`
+}
+
+// migrationRuleDefinition describes a rule of a migration step.
+// The fields that are needed depend on the type of the rule.
+type migrationRuleDefinition struct {
+	Type      string                       `yaml:"type"`      // type of the rule (see newMigrationRule)
+	Path      string                       `yaml:"path"`      // path of the setting(s) the rule applies to (may contain wildcards)
+	Table     string                       `yaml:"table"`     // path of the table(s) the rule applies to (may contain wildcards)
+	Column    string                       `yaml:"column"`    // name of the column the rule applies to
+	To        string                       `yaml:"to"`        // new name of the setting/column
+	Value     *string                      `yaml:"value"`     // default value of the setting
+	Values    map[string]string            `yaml:"values"`    // mapping of old values to new values
+	Columns   []*migrationColumnDefinition `yaml:"columns"`   // columns to set in the referenced row
+	Converter string                       `yaml:"converter"` // name of the converter to run
+}
+
+// migrationColumnDefinition describes a column that is set in the row a row reference refers to.
+type migrationColumnDefinition struct {
+	Name    string   `yaml:"name"`    // name of the column
+	Value   string   `yaml:"value"`   // value of the column in the referenced row
+	Neutral []string `yaml:"neutral"` // values of the column in other rows that can be dropped without losing anything
+}
+
+// migrationRule is a rule that is applied to a document when migrating it to the next firmware version.
+type migrationRule interface {
+
+	// apply applies the rule to the document (migration to the newer version) and records the changes in the
+	// specified migration step.
+	apply(doc *document, step *MigrationStep) error
+
+	// revert reverts the rule (migration to the older version) and records the changes in the specified migration
+	// step. Settings that cannot be represented in the older version are removed and recorded as lost.
+	revert(doc *document, step *MigrationStep) error
+}
+
+// ruleBasedMigration is a migration that applies the rules of a migration step (see migrations/README.md).
+type ruleBasedMigration struct {
+	from  Version
+	to    Version
+	rules []migrationRule
+}
+
+// FromVersion returns the document version the migration start with.
+func (migration *ruleBasedMigration) FromVersion() Version {
+	return migration.from
+}
+
+// ToVersion returns the document version the migration ends with.
+func (migration *ruleBasedMigration) ToVersion() Version {
+	return migration.to
+}
+
+// Migrate performs the migration.
+func (migration *ruleBasedMigration) Migrate(file *File, step *MigrationStep) (*File, error) {
+
+	newFile := file.Dupe()
+	for _, rule := range migration.rules {
+		err := rule.apply(newFile.doc, step)
+		if err != nil {
+			return nil, fmt.Errorf("Migrating from version %s to version %s failed: %s", migration.from, migration.to, err)
+		}
+	}
+
+	newFile.SetVersion(migration.to)
+	return newFile, nil
+}
+
+// MigrateDown performs the reverse migration (the rules are reverted in reverse order). Afterwards settings the schema
+// of the older version does not support are removed and recorded as lost. A step without rules does not change any
+// settings, so reverting it just changes the version and removes the unsupported settings.
+func (migration *ruleBasedMigration) MigrateDown(file *File, step *MigrationStep) (*File, error) {
+
+	newFile := file.Dupe()
+	for i := len(migration.rules) - 1; i >= 0; i-- {
+		err := migration.rules[i].revert(newFile.doc, step)
+		if err != nil {
+			return nil, fmt.Errorf("Migrating from version %s to version %s failed: %s", migration.to, migration.from, err)
+		}
+	}
+
+	err := dropUnsupportedSettings(newFile.doc, migration.from, step)
+	if err != nil {
+		return nil, fmt.Errorf("Migrating from version %s to version %s failed: %s", migration.to, migration.from, err)
+	}
+
+	newFile.SetVersion(migration.from)
+	return newFile, nil
+}
+
+// mustLoadMigrations loads the migrations from the specified migration rules files and panics, if that fails.
+// It is used to load the bundled migration rules, so a failure is a programming error.
+func mustLoadMigrations(files []migrationRulesFile) []migrationProvider {
+
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		panic(fmt.Sprintf("Loading the bundled migration rules failed: %s", err))
+	}
+
+	return migrations
+}
+
+// loadMigrations loads the migrations from the specified migration rules files (one migration step per file).
+func loadMigrations(files []migrationRulesFile) ([]migrationProvider, error) {
+
+	var migrations []migrationProvider
+	for _, file := range files {
+
+		var step migrationStepDefinition
+		err := yaml.UnmarshalStrict([]byte(file.data), &step)
+		if err != nil {
+			return nil, fmt.Errorf("Reading migration rules (%s) failed: %s", file.name, err)
+		}
+
+		from, err := ParseVersion(step.From)
+		if err != nil {
+			return nil, fmt.Errorf("Migration rules (%s): %s", file.name, err)
+		}
+
+		to, err := ParseVersion(step.To)
+		if err != nil {
+			return nil, fmt.Errorf("Migration rules (%s): %s", file.name, err)
+		}
+
+		if from.CompareRelease(to) >= 0 {
+			return nil, fmt.Errorf("Migration rules (%s): version %s is not lower than version %s", file.name, from, to)
+		}
+
+		migration := &ruleBasedMigration{from: from, to: to}
+		for j, definition := range step.Rules {
+			if definition == nil {
+				return nil, fmt.Errorf("Migration rules (%s): rule %d is empty", file.name, j)
+			}
+			rule, err := newMigrationRule(definition, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("Migration rules (%s): rule %d (%s): %s", file.name, j, definition.Type, err)
+			}
+			migration.rules = append(migration.rules, rule)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+// newMigrationRule creates the migration rule described by the specified definition (a rule of the migration step from
+// the specified version to the specified version).
+func newMigrationRule(definition *migrationRuleDefinition, from Version, to Version) (migrationRule, error) {
+
+	switch definition.Type {
+
+	// renames a setting (rename-setting) or a column in a table (rename-column)
+	case "rename-setting", "rename-column":
+		path := definition.Path
+		if definition.Type == "rename-column" {
+			if len(definition.Table) == 0 || len(definition.Column) == 0 {
+				return nil, fmt.Errorf("The rule requires 'table' and 'column'")
+			}
+			path = definition.Table + ".*." + definition.Column
+		}
+		settingPath, err := parseMigrationRulePath(path)
+		if err != nil {
+			return nil, err
+		}
+		if !settingNameRegex.MatchString(definition.To) {
+			return nil, fmt.Errorf("'%s' is not a valid setting name", definition.To)
+		}
+		return &renameSettingRule{path: settingPath, to: definition.To}, nil
+
+	// maps the values of a setting to other values
+	case "map-values":
+		path, err := parseMigrationRulePath(definition.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(definition.Values) == 0 {
+			return nil, fmt.Errorf("The rule requires 'values'")
+		}
+		reverse := map[string]string{}
+		for from, to := range definition.Values {
+			if _, exists := reverse[to]; exists {
+				return nil, fmt.Errorf("The value '%s' is mapped to multiple times, the mapping cannot be reversed", to)
+			}
+			reverse[to] = from
+		}
+		return &mapValuesRule{path: path, values: definition.Values, reverse: reverse}, nil
+
+	// removes a setting that does not exist in the newer version
+	case "drop-setting":
+		path, err := parseMigrationRulePath(definition.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &dropSettingRule{path: path}, nil
+
+	// adds a setting that is new in the newer version
+	case "add-default":
+		path, err := parseMigrationRulePath(definition.Path)
+		if err != nil {
+			return nil, err
+		}
+		if definition.Value == nil {
+			return nil, fmt.Errorf("The rule requires 'value'")
+		}
+		return &addDefaultRule{path: path, value: *definition.Value}, nil
+
+	// a setting accepts row references in the newer version
+	case "allow-rowrefs":
+		path, err := parseMigrationRulePath(definition.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &allowRowReferencesRule{path: path}, nil
+
+	// replaces a row reference with columns in the referenced row
+	case "convert-rowref":
+		path, err := parseMigrationRulePath(definition.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) != 1 {
+			return nil, fmt.Errorf("The row reference '%s' must be a top-level setting", definition.Path)
+		}
+		table, err := parseMigrationRulePath(definition.Table)
+		if err != nil {
+			return nil, err
+		}
+		if len(definition.Columns) == 0 {
+			return nil, fmt.Errorf("The rule requires 'columns'")
+		}
+		for _, column := range definition.Columns {
+			if column == nil || !settingNameRegex.MatchString(column.Name) {
+				return nil, fmt.Errorf("The rule contains a column without a valid name")
+			}
+		}
+		return &convertRowReferenceRule{path: path, table: table, columns: definition.Columns}, nil
+
+	// warns about settings that are not known to exist in the newer version
+	case "warn-unknown":
+		return &warnUnknownRule{from: from, to: to}, nil
+
+	// runs a converter implemented in Go (for conversions the generic rules cannot describe)
+	case "convert":
+		converter, ok := migrationConverters[definition.Converter]
+		if !ok {
+			return nil, fmt.Errorf("There is no converter named '%s'", definition.Converter)
+		}
+		return converter, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a valid rule type", definition.Type)
+}
+
+// parseMigrationRulePath parses the specified path of a setting in a migration rule.
+// The path must address settings, so it must end with a setting name.
+func parseMigrationRulePath(s string) (documentSettingPath, error) {
+
+	path, err := parseDocumentSettingPath(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if path[len(path)-1].name == nil {
+		return nil, fmt.Errorf("The path '%s' does not address a setting", s)
+	}
+
+	return path, nil
+}
+
+// renameSettingRule renames a setting or a column in a table.
+type renameSettingRule struct {
+	path documentSettingPath
+	to   string
+}
+
+func (rule *renameSettingRule) apply(doc *document, step *MigrationStep) error {
+	renameSettings(doc, step, rule.path, rule.to)
+	return nil
+}
+
+func (rule *renameSettingRule) revert(doc *document, step *MigrationStep) error {
+	renameSettings(doc, step, rule.path.withName(rule.to), *rule.path[len(rule.path)-1].name)
+	return nil
+}
+
+// renameSettings renames the settings selected by the specified path.
+// A setting that has the new name already is replaced.
+func renameSettings(doc *document, step *MigrationStep, path documentSettingPath, to string) {
+
+	for _, match := range matchSettings(doc, path) {
+		for _, existing := range matchSettings(doc, path.withName(to)) {
+			if existing.row == match.row {
+				log.Debugf("Setting '%s' is replaced with setting '%s'.", existing.path, match.path)
+				step.add(MigrationChange{Kind: ChangeRemoved, Path: existing.path, OldValue: reportValue(existing.setting)})
+				existing.remove(doc)
+			}
+		}
+		newPath := match.path[:len(match.path)-len(match.setting.Name)] + to
+		step.add(MigrationChange{Kind: ChangeRenamed, Path: match.path, NewPath: newPath})
+		match.setting.Name = to
+	}
+}
+
+// mapValuesRule maps the values of a setting to other values.
+// The migration fails, if a value is not mapped. Values that are not mapped cannot be reverted and are removed.
+type mapValuesRule struct {
+	path    documentSettingPath
+	values  map[string]string
+	reverse map[string]string
+}
+
+func (rule *mapValuesRule) apply(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, rule.path) {
+
+		value, err := match.setting.GetValue()
+		if err != nil {
+			return fmt.Errorf("The setting '%s' is not a simple value", match.path)
+		}
+
+		newValue, ok := rule.values[value]
+		if !ok {
+			keys := make([]string, 0, len(rule.values))
+			for key := range rule.values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("The setting '%s' has the value '%s' that cannot be migrated (expecting %s)", match.path, value, quoteAll(keys))
+		}
+
+		step.add(MigrationChange{Kind: ChangeValueMapped, Path: match.path, OldValue: reportString(value), NewValue: reportString(newValue)})
+		match.setting.setSimpleValue(newValue)
+	}
+
+	return nil
+}
+
+func (rule *mapValuesRule) revert(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, rule.path) {
+		value, err := match.setting.GetValue()
+		oldValue, ok := rule.reverse[value]
+		if err != nil || !ok {
+			step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, OldValue: reportValue(match.setting), Lost: true})
+			match.remove(doc)
+			continue
+		}
+		step.add(MigrationChange{Kind: ChangeValueMapped, Path: match.path, OldValue: reportString(value), NewValue: reportString(oldValue)})
+		match.setting.setSimpleValue(oldValue)
+	}
+
+	return nil
+}
+
+// dropSettingRule removes a setting that does not exist in the newer version.
+type dropSettingRule struct {
+	path documentSettingPath
+}
+
+func (rule *dropSettingRule) apply(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, rule.path) {
+		step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, OldValue: reportValue(match.setting), Lost: true})
+		match.remove(doc)
+	}
+
+	return nil
+}
+
+func (rule *dropSettingRule) revert(doc *document, step *MigrationStep) error {
+	return nil
+}
+
+// addDefaultRule adds a setting that is new in the newer version with its default value.
+// Nested settings are added to all rows selected by the path. Reverting removes the setting, it is recorded as lost,
+// if it does not have the default value.
+type addDefaultRule struct {
+	path  documentSettingPath
+	value string
+}
+
+func (rule *addDefaultRule) apply(doc *document, step *MigrationStep) error {
+
+	name := *rule.path[len(rule.path)-1].name
+
+	// top-level setting
+	if len(rule.path) == 1 {
+		if setting, _ := doc.getSetting(rule.path); setting == nil {
+			doc.Nodes = append(doc.Nodes, &documentNode{Setting: &documentSetting{Name: name, SimpleValue: &documentSimpleValue{Value: rule.value}}})
+			step.add(MigrationChange{Kind: ChangeAdded, Path: name, NewValue: reportString(rule.value)})
+		}
+		return nil
+	}
+
+	// nested setting
+	selector := rule.path[len(rule.path)-2]
+	for _, table := range matchSettings(doc, rule.path[:len(rule.path)-2]) {
+		if !table.setting.isTable() {
+			continue
+		}
+		for i, row := range table.setting.TableValue.Rows {
+			if selector.selectsRow(i, row) && row.getItem(name) == nil {
+				row.Items = append(row.Items, &documentSetting{Name: name, SimpleValue: &documentSimpleValue{Value: rule.value}})
+				step.add(MigrationChange{Kind: ChangeAdded, Path: fmt.Sprintf("%s.%d.%s", table.path, i, name), NewValue: reportString(rule.value)})
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rule *addDefaultRule) revert(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, rule.path) {
+		value, err := match.setting.GetValue()
+		lost := err != nil || value != rule.value
+		step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, OldValue: reportValue(match.setting), Lost: lost})
+		match.remove(doc)
+	}
+
+	return nil
+}
+
+// allowRowReferencesRule marks a setting that accepts row references in the newer version.
+// Applying the rule does not change anything, reverting it removes row references.
+type allowRowReferencesRule struct {
+	path documentSettingPath
+}
+
+func (rule *allowRowReferencesRule) apply(doc *document, step *MigrationStep) error {
+	return nil
+}
+
+func (rule *allowRowReferencesRule) revert(doc *document, step *MigrationStep) error {
+
+	for _, match := range matchSettings(doc, rule.path) {
+		if _, err := match.setting.GetValue(); err != nil {
+			step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, Lost: true})
+			match.remove(doc)
+		}
+	}
+
+	return nil
+}
+
+// convertRowReferenceRule replaces a top-level row reference with columns in the referenced table row.
+// The first column marks the referenced row, reverting the rule turns the first row with that marker back into a row
+// reference. Values of the columns that cannot be represented by the row reference are recorded as lost.
+type convertRowReferenceRule struct {
+	path    documentSettingPath
+	table   documentSettingPath
+	columns []*migrationColumnDefinition
+}
+
+func (rule *convertRowReferenceRule) apply(doc *document, step *MigrationStep) error {
+
+	setting, err := doc.getSetting(rule.path)
+	if err != nil || setting == nil {
+		return err
+	}
+
+	// the setting does not exist in the newer version
+	// => remove it, it does not refer to a row anyway
+	rowref := setting.getAttribute("rowref")
+	if rowref == nil {
+		step.add(MigrationChange{Kind: ChangeRemoved, Path: rule.path.String(), OldValue: reportValue(setting), Lost: true})
+		return doc.removeSetting(rule.path)
+	}
+
+	for _, table := range matchSettings(doc, rule.table) {
+		if !table.setting.isTable() {
+			continue
+		}
+		for i, row := range table.setting.TableValue.Rows {
+			if row.RowID != nil && string(*row.RowID) == *rowref {
+				for _, column := range rule.columns {
+					row.SetSimpleValueByName(column.Name, column.Value)
+					step.add(MigrationChange{Kind: ChangeAdded, Path: fmt.Sprintf("%s.%d.%s", table.path, i, column.Name), NewValue: reportString(column.Value)})
+				}
+				step.add(MigrationChange{Kind: ChangeRemoved, Path: rule.path.String(), OldValue: rowref})
+				return doc.removeSetting(rule.path)
+			}
+		}
+	}
+
+	return fmt.Errorf("The setting '%s' refers to row '%s', but '%s' does not contain a row with that id", rule.path, *rowref, rule.table)
+}
+
+func (rule *convertRowReferenceRule) revert(doc *document, step *MigrationStep) error {
+
+	var rowref *RowID
+	for _, table := range matchSettings(doc, rule.table) {
+		if !table.setting.isTable() {
+			continue
+		}
+		for i, row := range table.setting.TableValue.Rows {
+			for j, column := range rule.columns {
+				item := row.getItem(column.Name)
+				if item == nil {
+					continue
+				}
+				value, err := item.GetValue()
+				lost := false
+				if j == 0 && err == nil && value == column.Value && rowref == nil && row.RowID != nil {
+					rowref = row.RowID
+				} else if err != nil || (value != column.Value || j == 0) && !containsString(column.Neutral, value) {
+					lost = true
+				}
+				step.add(MigrationChange{Kind: ChangeRemoved, Path: fmt.Sprintf("%s.%d.%s", table.path, i, column.Name), OldValue: reportValue(item), Lost: lost})
+				row.removeItem(item)
+			}
+		}
+	}
+
+	if rowref != nil {
+		err := doc.SetSetting(&documentSetting{
+			Name:              *rule.path[0].name,
+			ValueWithMetadata: &documentValueWithMetadata{Data: dictionary{{Key: "rowref", Value: string(*rowref)}}}})
+		if err != nil {
+			return err
+		}
+		step.add(MigrationChange{Kind: ChangeAdded, Path: rule.path.String(), NewValue: reportString(string(*rowref))})
+	}
+
+	return nil
+}
+
+// warnUnknownRule warns about settings that are not known to the schema of the newer version. It marks steps the
+// changes of which are not known completely, i.e. settings may have been renamed or removed, so settings the schema does
+// not know are migrated unchecked. Reverting the rule checks the document against the schema of the older version
+// (settings the older version does not support are removed afterwards, see dropUnsupportedSettings).
+type warnUnknownRule struct {
+	from Version
+	to   Version
+}
+
+// maxReportedUnknownSettings is the maximum number of unknown settings listed in the warning of a warn-unknown rule.
+const maxReportedUnknownSettings = 10
+
+func (rule *warnUnknownRule) apply(doc *document, step *MigrationStep) error {
+	return warnUnknownSettings(doc, rule.to, false)
+}
+
+func (rule *warnUnknownRule) revert(doc *document, step *MigrationStep) error {
+	return warnUnknownSettings(doc, rule.from, true)
+}
+
+// warnUnknownSettings logs a warning listing the settings of the document that are not known to the schema of the
+// specified version. Settings the schema knows from other versions are accepted, if requested.
+// Columns of tables the schema does not list any columns for are not checked.
+func warnUnknownSettings(doc *document, version Version, acceptRemoved bool) error {
+
+	schema, err := SchemaForVersion(version)
+	if err != nil {
+		return err
+	}
+
+	var unknown []string
+	var collect func(path string, setting *documentSetting, supported []*SettingSchema, removed []*SettingSchema)
+	collect = func(path string, setting *documentSetting, supported []*SettingSchema, removed []*SettingSchema) {
+		settingSchema := findSettingSchema(supported, setting.Name)
+		if settingSchema == nil {
+			if !acceptRemoved || findSettingSchema(removed, setting.Name) == nil {
+				unknown = append(unknown, path)
+			}
+			return
+		}
+		if len(settingSchema.Columns) == 0 || !setting.isTable() {
+			return
+		}
+		for i, row := range setting.TableValue.Rows {
+			for _, item := range row.Items {
+				collect(fmt.Sprintf("%s.%d.%s", path, i, item.Name), item, settingSchema.Columns, settingSchema.removed)
+			}
+		}
+	}
+
+	for _, node := range doc.Nodes {
+		if node.Setting != nil {
+			collect(node.Setting.Name, node.Setting, schema.Settings, schema.removed)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	list := strings.Join(unknown, ", ")
+	if len(unknown) > maxReportedUnknownSettings {
+		list = fmt.Sprintf("%s and %d more", strings.Join(unknown[:maxReportedUnknownSettings], ", "), len(unknown)-maxReportedUnknownSettings)
+	}
+
+	log.Warnf(
+		"%d settings are not known to exist in version %s, they were migrated unchecked: %s",
+		len(unknown), version, list)
+
+	return nil
+}
+
+// dropUnsupportedSettings removes the settings the schema of the specified version knows to be unsupported by that
+// version (e.g. settings a newer version added) and records them as lost.
+func dropUnsupportedSettings(doc *document, version Version, step *MigrationStep) error {
+
+	schema, err := SchemaForVersion(version)
+	if err != nil {
+		return err
+	}
+
+	for _, settingPath := range unsupportedSettingPaths("", schema.Settings, schema.removed) {
+		path, err := parseDocumentSettingPath(settingPath)
+		if err != nil {
+			return err
+		}
+		for _, match := range matchSettings(doc, path) {
+			step.add(MigrationChange{Kind: ChangeRemoved, Path: match.path, OldValue: reportValue(match.setting), Lost: true})
+			match.remove(doc)
+		}
+	}
+
+	return nil
+}
+
+// unsupportedSettingPaths returns the paths of the unsupported settings (with wildcards selecting all rows of the
+// tables they are in), including unsupported columns of supported tables.
+func unsupportedSettingPaths(prefix string, supported []*SettingSchema, unsupported []*SettingSchema) []string {
+
+	var paths []string
+	for _, schema := range unsupported {
+		paths = append(paths, prefix+schema.Name)
+	}
+
+	for _, schema := range supported {
+		if schema.Type == TableType {
+			paths = append(paths, unsupportedSettingPaths(prefix+schema.Name+".*.", schema.Columns, schema.removed)...)
+		}
+	}
+
+	return paths
+}
+
+// migrationMatch is a setting selected by the path in a migration rule.
+type migrationMatch struct {
+	path    string            // path of the setting (with row indices)
+	setting *documentSetting  // the setting
+	row     *documentTableRow // the row containing the setting (nil for top-level settings)
+}
+
+// matchSettings returns all settings selected by the specified path.
+// Unlike document.findSettings it skips settings that do not have the expected structure instead of failing.
+func matchSettings(doc *document, path documentSettingPath) []migrationMatch {
+
+	for _, node := range doc.Nodes {
+		if node.Setting != nil && node.Setting.Name == *path[0].name {
+			return collectMatches(nil, node.Setting, node.Setting.Name, nil, path, 1)
+		}
+	}
+
+	return nil
+}
+
+// collectMatches adds the settings selected by the specified path starting at the specified index to the matches.
+func collectMatches(
+	matches []migrationMatch,
+	setting *documentSetting,
+	settingPath string,
+	row *documentTableRow,
+	path documentSettingPath,
+	index int) []migrationMatch {
+
+	if index == len(path) {
+		return append(matches, migrationMatch{path: settingPath, setting: setting, row: row})
+	}
+
+	if !setting.isTable() {
+		return matches
+	}
+
+	for i, tableRow := range setting.TableValue.Rows {
+		if path[index].selectsRow(i, tableRow) {
+			if item := tableRow.getItem(*path[index+1].name); item != nil {
+				matches = collectMatches(matches, item, fmt.Sprintf("%s.%d.%s", settingPath, i, item.Name), tableRow, path, index+2)
+			}
+		}
+	}
+
+	return matches
+}
+
+// remove removes the matched setting from the document.
+func (match migrationMatch) remove(doc *document) {
+
+	if match.row != nil {
+		match.row.removeItem(match.setting)
+		return
+	}
+
+	for i, node := range doc.Nodes {
+		if node.Setting == match.setting {
+			doc.Nodes = append(doc.Nodes[:i], doc.Nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// containsString checks whether the specified slice contains the specified string.
+func containsString(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+package atv
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// flattenSettings returns all settings of the document as sorted 'path = value' lines. Attributes of settings are
+// listed as separate lines ('path.attribute = value'), table rows are identified by their index and their row id.
+// Comparing flattened documents ignores the order of settings and comments.
+func flattenSettings(file *File) []string {
+
+	var lines []string
+	var flatten func(path string, setting *documentSetting)
+	flatten = func(path string, setting *documentSetting) {
+		switch {
+		case setting.SimpleValue != nil:
+			lines = append(lines, fmt.Sprintf("%s = %q", path, setting.SimpleValue.Value))
+		case setting.ValueWithMetadata != nil:
+			for _, kvp := range setting.ValueWithMetadata.Data {
+				lines = append(lines, fmt.Sprintf("%s.%s = %q", path, kvp.Key, kvp.Value))
+			}
+		case setting.TableValue != nil:
+			for _, kvp := range setting.TableValue.Attributes {
+				lines = append(lines, fmt.Sprintf("%s.%s = %q", path, kvp.Key, kvp.Value))
+			}
+			if len(setting.TableValue.Attributes) == 0 && len(setting.TableValue.Rows) == 0 {
+				lines = append(lines, fmt.Sprintf("%s = {}", path))
+			}
+			for i, row := range setting.TableValue.Rows {
+				rowPath := fmt.Sprintf("%s.%d", path, i)
+				if row.RowID != nil {
+					lines = append(lines, fmt.Sprintf("%s.rid = %q", rowPath, *row.RowID))
+				}
+				for _, item := range row.Items {
+					flatten(rowPath+"."+item.Name, item)
+				}
+			}
+		}
+	}
+
+	for _, node := range file.doc.Nodes {
+		if node.Pragma != nil {
+			lines = append(lines, fmt.Sprintf("#%s %s", node.Pragma.Name, node.Pragma.Value))
+		} else if node.Setting != nil {
+			flatten(node.Setting.Name, node.Setting)
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
+
+// expectSameSettings checks whether both documents contain the same settings (ignoring order and comments).
+func expectSameSettings(t *testing.T, name string, expected *File, actual *File) {
+	t.Helper()
+	expectedLines := flattenSettings(expected)
+	actualLines := flattenSettings(actual)
+	if !reflect.DeepEqual(expectedLines, actualLines) {
+		t.Errorf("%s: The migrated document differs from the expected document\n--expected--\n%s\n--got--\n%s\n--document--\n%s",
+			name, strings.Join(expectedLines, "\n"), strings.Join(actualLines, "\n"), actual.String())
+	}
+}
+
+// loadFixture loads the specified ATV document or fails the test.
+func loadFixture(t *testing.T, path string) *File {
+	t.Helper()
+	file, err := FromFile(path)
+	if err != nil {
+		t.Fatalf("Loading fixture '%s' failed: %v", path, err)
+	}
+	return file
+}
+
+// TestMigrationFixtures migrates the documents in testdata/migration/<from>-to-<to>/*.before.atv to the version
+// <to> and compares the result with the corresponding *.after.atv document. Fixtures in directories named
+// <from>-to-<to> with a lower <to> version test migrations down to older versions.
+// loadMigrationRulesFiles loads the migration rules files from the 'migrations' directory.
+func loadMigrationRulesFiles(t *testing.T) []migrationRulesFile {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("migrations", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Listing migration rules files failed: %v", err)
+	}
+	sort.Strings(paths)
+
+	var files []migrationRulesFile
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Reading migration rules file (%s) failed: %v", path, err)
+		}
+		files = append(files, migrationRulesFile{name: filepath.Base(path), data: string(data)})
+	}
+
+	return files
+}
+
+func TestMigrationRulesDataIsUpToDate(t *testing.T) {
+
+	files := loadMigrationRulesFiles(t)
+	if !reflect.DeepEqual(files, migrationRulesFiles) {
+		t.Fatalf("The bundled migration rules differ from the files in the 'migrations' directory, run 'go generate'")
+	}
+
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		t.Fatalf("Loading the migration rules failed: %v", err)
+	}
+	_, err = newMigrationRegistry(migrations)
+	if err != nil {
+		t.Fatalf("Building the migration registry failed: %v", err)
+	}
+}
+
+func TestLoadMigrationsReportsInvalidFiles(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown field", "from: 8.0.2\nto: 8.1.0\nrule: []\n"},
+		{"invalid version", "from: 8.0\nto: 8.1.0\n"},
+		{"versions not ascending", "from: 8.1.0\nto: 8.0.2\n"},
+		{"unknown rule type", "from: 8.0.2\nto: 8.1.0\nrules:\n  - type: rename-everything\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := loadMigrations([]migrationRulesFile{{name: "test.yaml", data: test.data}})
+			if err == nil {
+				t.Fatalf("Loading the migration rules succeeded unexpectedly")
+			}
+			if !strings.Contains(err.Error(), "test.yaml") {
+				t.Errorf("The error does not mention the file: %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrationFixtures(t *testing.T) {
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "migration", "*", "*.before.atv"))
+	if err != nil {
+		t.Fatalf("Searching fixtures failed: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("No migration fixtures found")
+	}
+
+	for _, beforePath := range paths {
+
+		name := filepath.Join(filepath.Base(filepath.Dir(beforePath)), strings.TrimSuffix(filepath.Base(beforePath), ".before.atv"))
+		versions := strings.SplitN(filepath.Base(filepath.Dir(beforePath)), "-to-", 2)
+		if len(versions) != 2 {
+			t.Fatalf("%s: The directory name does not specify the versions to migrate between", name)
+		}
+		targetVersion, err := ParseVersion(versions[1])
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		before := loadFixture(t, beforePath)
+		after := loadFixture(t, strings.TrimSuffix(beforePath, ".before.atv")+".after.atv")
+
+		migrated, err := before.Migrate(targetVersion)
+		if err != nil {
+			t.Errorf("%s: Migrating failed: %v", name, err)
+			continue
+		}
+
+		expectSameSettings(t, name, after, migrated)
+	}
+}
+
+func TestMigrationExternalSwitchRoundTrip(t *testing.T) {
+
+	dir := filepath.Join("testdata", "migration", "8.0.2-to-8.1.0")
+	before := loadFixture(t, filepath.Join(dir, "external-switch.before.atv"))
+	after := loadFixture(t, filepath.Join(dir, "external-switch.after.atv"))
+
+	version, _ := ParseVersion("8.0.2")
+	result, err := after.MigrateWithResult(version)
+	if err != nil {
+		t.Fatalf("Migrating down failed: %v", err)
+	}
+
+	expectSameSettings(t, "external-switch (8.1.0 => 8.0.2)", before, result.File)
+	if len(result.LostSettings) > 0 {
+		t.Errorf("Migrating down lost settings unexpectedly: %v", result.LostSettings)
+	}
+}
+
+func TestMigrationDownDropsUnsupportedSettings(t *testing.T) {
+
+	file := fileFromString(t, `#version 8.0.2.default
+
+SERVICE_SWITCH1_TYPE = "switch"
+VPN_CONNECTION = {
+  {
+    NAME = "plant-a"
+    VPN_ENABLED = "yes"
+    CONTROL_INV = "no"
+  }
+}
+`)
+
+	version, _ := ParseVersion("8.0.2")
+	step := &MigrationStep{}
+	err := dropUnsupportedSettings(file.doc, version, step)
+	if err != nil {
+		t.Fatalf("Dropping unsupported settings failed: %v", err)
+	}
+
+	expected := fileFromString(t, "#version 8.0.2.default\nVPN_CONNECTION = {\n  {\n    NAME = \"plant-a\"\n    VPN_ENABLED = \"yes\"\n  }\n}\n")
+	expectSameSettings(t, "unsupported settings", expected, file)
+
+	report := MigrationReport{Steps: []*MigrationStep{step}}
+	lost := report.LostSettings()
+	sort.Strings(lost)
+	if !reflect.DeepEqual(lost, []string{"SERVICE_SWITCH1_TYPE", "VPN_CONNECTION.0.CONTROL_INV"}) {
+		t.Errorf("Unexpected lost settings: %q", lost)
+	}
+}
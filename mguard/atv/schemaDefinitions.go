@@ -0,0 +1,70 @@
+package atv
+
+// schemaDefinitions contains the definitions of all settings known to the bundled schemas.
+// Settings that exist in some firmware versions only specify the version range they exist in (Since/Until).
+// The definitions are not complete, they cover settings the migrations and the merge configurations deal with.
+var schemaDefinitions = []*SettingSchema{
+
+	// general settings
+	{Name: "MY_HOSTNAME", Type: StringType},
+	{Name: "ROOT_PASSWORD", Type: StringType},
+	{Name: "PRIVATE_CERTS", Type: TableType},
+
+	// remote access via SSH and HTTPS
+	{Name: "SSH_REMOTE_ENABLE", Type: BooleanType},
+	{Name: "SSH_REMOTE_LISTENPORT", Type: PortType},
+	{Name: "SSH_REMOTE_ACCESS_RULES", Type: TableType},
+	{Name: "HTTPS_REMOTE_ENABLE", Type: BooleanType},
+	{Name: "HTTPS_REMOTE_LISTENPORT", Type: PortType},
+	{Name: "HTTPS_REMOTE_ACCESS_RULES", Type: TableType},
+
+	// SNMP
+	{Name: "SNMP_ENABLE_V1", Type: BooleanType, Until: schemaVersion(10, 0, 0)},
+	{Name: "SNMP_COMMUNITY", Type: StringType, Until: schemaVersion(10, 0, 0)},
+	{Name: "SNMP_COMMUNITY_RO", Type: StringType, Until: schemaVersion(10, 0, 0)},
+	{Name: "SNMP_ACCESS_RULES", Type: TableType},
+	{Name: "SNMP_TRAP_DESTINATIONS", Type: TableType},
+
+	// service contacts (button/switch controlling vpn connections)
+	{Name: "VPN_EXTERNAL_SWITCH_REF", Type: RowReferenceType, Until: schemaVersion(8, 1, 0)},
+	{Name: "VPN_RS_EXTERNAL_SWITCH_TYPE", Type: EnumType, Values: []string{"button", "switch"}, Until: schemaVersion(8, 1, 0)},
+	{Name: "SERVICE_SWITCH1_TYPE", Type: EnumType, Values: []string{"button", "switch"}, Since: schemaVersion(8, 1, 0)},
+
+	// vpn
+	{Name: "VPN_IPSEC0_MTU", Type: IntegerType},
+	{Name: "VPN_DYNIP_WATCH", Type: BooleanType},
+	{Name: "VPN_DYNIP_WATCH_INTERVAL", Type: IntegerType},
+	{Name: "VPN_CONNECTION", Type: TableType, Columns: []*SettingSchema{
+		{Name: "NAME", Type: StringType},
+		{Name: "VPN_ENABLED", Type: BooleanType, Until: schemaVersion(8, 1, 0)},
+		{Name: "VPN_START", Type: EnumType, Values: []string{"started", "stopped"}, Since: schemaVersion(8, 1, 0)},
+		{Name: "CONTROL", Type: StringType, Since: schemaVersion(8, 1, 0)},
+		{Name: "CONTROL_INV", Type: BooleanType, Since: schemaVersion(8, 1, 0)},
+		{Name: "PSK_SECRET", Type: StringType},
+		{Name: "CA_CERT_REF", Type: RowReferenceType},
+		{Name: "TUNNEL", Type: TableType, Columns: []*SettingSchema{
+			{Name: "LOCAL", Type: NetworkType},
+			{Name: "LOCAL_1TO1NAT", Type: IPAddressType, Until: schemaVersion(8, 1, 0)},
+			{Name: "LOCAL_N_TO_N_NAT", Type: TableType, Since: schemaVersion(8, 1, 0), Complete: true, Columns: []*SettingSchema{
+				{Name: "COMMENT", Type: StringType},
+				{Name: "FROM_NET", Type: IPAddressType},
+				{Name: "MASK", Type: NetmaskType},
+				{Name: "TO_NET", Type: IPAddressType},
+			}},
+		}},
+		{Name: "FW_INCOMING", Type: TableType, Columns: firewallRuleColumns},
+		{Name: "FW_OUTGOING", Type: TableType, Columns: firewallRuleColumns},
+	}},
+}
+
+// firewallRuleColumns contains the known columns of the firewall rule tables of vpn connections.
+var firewallRuleColumns = []*SettingSchema{
+	{Name: "COMMENT", Type: StringType},
+	{Name: "TARGET", Type: StringType, Until: schemaVersion(8, 1, 0)},
+	{Name: "TARGET_REF", Type: RowReferenceType, Since: schemaVersion(8, 1, 0)},
+}
+
+// schemaVersion returns a version for use in setting definitions.
+func schemaVersion(major, minor, patch int) *Version {
+	return &Version{Major: major, Minor: minor, Patch: patch, Suffix: "default"}
+}
@@ -4,46 +4,55 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto"
 	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 
 	"github.com/griffinplus/mguard-config-tool/mguard/atv"
 	"github.com/griffinplus/mguard-config-tool/shadow"
+	"github.com/griffinplus/mguard-config-tool/snmpd"
 	log "github.com/sirupsen/logrus"
 )
 
 // Container represents a mGuard ECS container.
+// The container keeps all entries of the underlying tar archive (names, modes, ownership and order), the configuration
+// ('aca/cfg'), the users ('aca/users') and the SNMPv3 users ('aca/snmpd') are generated from Atv, Users and SnmpUsers
+// when writing the container.
 type Container struct {
 	Atv       *atv.File
 	Users     *shadow.File
-	fileCfg   file
-	filePass  file
-	fileSnmpd file
-	fileUsers file
+	SnmpUsers *snmpd.File
+	entries   []*tarEntry // entries of the container (in the order they appear in the tar archive)
 }
 
-type file struct {
-	Name string // name of the file in the ECS container
-	Data []byte // content of the file in the ECS container
-}
+// names of the files in an ECS container the container deals with
+const (
+	cfgFileName   = "aca/cfg"   // configuration (ATV document)
+	passFileName  = "aca/pass"  // password of the root user
+	snmpdFileName = "aca/snmpd" // SNMPv3 users
+	usersFileName = "aca/users" // users and password hashes (shadow file)
+)
 
 // NewContainer returns a new and empty ECS container.
 func NewContainer() *Container {
 
+	now := time.Now()
 	container := Container{
 		Atv:       nil,
 		Users:     nil,
-		fileCfg:   file{Name: "aca/cfg"},
-		filePass:  file{Name: "aca/pass", Data: []byte(DefaultPassFileContent)},
-		fileSnmpd: file{Name: "aca/snmpd", Data: []byte(DefaultSnmpdFileContent)},
-		fileUsers: file{Name: "aca/users"},
+		SnmpUsers: nil,
+		entries: []*tarEntry{
+			newDirectoryEntry("aca", 0700, now),
+			newFileEntry(cfgFileName, nil, 0600, now),
+			newFileEntry(passFileName, []byte(DefaultPassFileContent), 0600, now),
+			newFileEntry(snmpdFileName, []byte(DefaultSnmpdFileContent), 0600, now),
+			newFileEntry(usersFileName, nil, 0600, now),
+		},
 	}
 
 	return &container
@@ -55,6 +64,7 @@ func ContainerFromATV(atv *atv.File) *Container {
 	container := NewContainer()
 	container.Atv = atv
 	container.Users = createDefaultShadowFile()
+	container.SnmpUsers = createDefaultSnmpdFile()
 	return container
 }
 
@@ -75,10 +85,10 @@ func ContainerFromFile(path string) (*Container, error) {
 // ContainerFromReader reads an ECS container from the specified io.Reader.
 func ContainerFromReader(reader io.Reader) (*Container, error) {
 
-	container := NewContainer()
+	container := &Container{}
 
 	// an ECS container is a simple gzip'ed tar archive
-	// => unzip and extract files from the archive
+	// => unzip and keep all entries of the archive
 	log.Debug("Processing ECS container...")
 
 	gzf, err := gzip.NewReader(reader)
@@ -99,76 +109,135 @@ func ContainerFromReader(reader io.Reader) (*Container, error) {
 			return nil, err
 		}
 
-		name := header.Name
-		log.Debugf("  - file: %s...", name)
+		log.Debugf("  - entry: %s...", header.Name)
 
-		if header.Typeflag == tar.TypeReg {
-
-			data, err := ioutil.ReadAll(tarReader)
+		entry := &tarEntry{header: *header}
+		if entry.isRegular() {
+			entry.data, err = ioutil.ReadAll(tarReader)
 			if err != nil {
 				log.Debugf("    ERROR: %s", err)
 				return nil, err
 			}
-
-			switch name {
-			case container.fileCfg.Name:
-				container.fileCfg.Data = data
-			case container.filePass.Name:
-				container.filePass.Data = data
-			case container.fileSnmpd.Name:
-				container.fileSnmpd.Data = data
-			case container.fileUsers.Name:
-				container.fileUsers.Data = data
-			}
 		}
+
+		container.entries = append(container.entries, entry)
+	}
+
+	// add files the mGuard expects, but the archive does not contain
+	// (the files get their default content)
+	now := time.Now()
+	if container.entry(passFileName) == nil {
+		container.entries = append(container.entries, newFileEntry(passFileName, []byte(DefaultPassFileContent), 0600, now))
+	}
+	if container.entry(snmpdFileName) == nil {
+		container.entries = append(container.entries, newFileEntry(snmpdFileName, []byte(DefaultSnmpdFileContent), 0600, now))
 	}
 
 	// ensure that the container contains the expected configuration file
-	if len(container.fileCfg.Data) == 0 {
-		log.Errorf("The ECS container does not contain a configuration file at '%s'", container.fileCfg.Name)
-		return nil, fmt.Errorf("The ECS container does not contain a configuration file at '%s'", container.fileCfg.Name)
+	cfgFile := container.entry(cfgFileName)
+	if cfgFile == nil || len(cfgFile.data) == 0 {
+		log.Errorf("The ECS container does not contain a configuration file at '%s'", cfgFileName)
+		return nil, fmt.Errorf("The ECS container does not contain a configuration file at '%s'", cfgFileName)
 	}
 
 	// parse ATV document stored within the ECS container
-	log.Debugf("Parsing configuration file '%s' in ECS container...", container.fileCfg.Name)
-	atv, err := atv.FromReader(bytes.NewReader(container.fileCfg.Data))
+	log.Debugf("Parsing configuration file '%s' in ECS container...", cfgFileName)
+	atv, err := atv.FromReader(bytes.NewReader(cfgFile.data))
 	if err != nil {
-		log.Debugf("Parsing configuration file '%s' in ECS container failed: %s", container.fileCfg.Name, err)
+		log.Debugf("Parsing configuration file '%s' in ECS container failed: %s", cfgFileName, err)
 		return nil, err
 	}
 	container.Atv = atv
-	log.Debugf("Parsing configuration file '%s' succeeded.", container.fileCfg.Name)
+	log.Debugf("Parsing configuration file '%s' succeeded.", cfgFileName)
 
 	// ensure that the container contains the expected users file
-	if len(container.fileUsers.Data) == 0 {
-		log.Errorf("The ECS container does not contain a password file at '%s'", container.fileUsers.Name)
-		return nil, fmt.Errorf("The ECS container does not contain a password file at '%s'", container.fileUsers.Name)
+	usersFile := container.entry(usersFileName)
+	if usersFile == nil || len(usersFile.data) == 0 {
+		log.Errorf("The ECS container does not contain a password file at '%s'", usersFileName)
+		return nil, fmt.Errorf("The ECS container does not contain a password file at '%s'", usersFileName)
 	}
 
 	// load users file stored within the ECS container
-	log.Debugf("Parsing user file '%s' in ECS container...", container.fileUsers.Name)
-	users, err := shadow.FileFromReader(bytes.NewReader(container.fileUsers.Data))
+	log.Debugf("Parsing user file '%s' in ECS container...", usersFileName)
+	users, err := shadow.FileFromReader(bytes.NewReader(usersFile.data))
 	if err != nil {
-		log.Debugf("Parsing user file '%s' in ECS container failed: %s", container.fileCfg.Name, err)
+		log.Debugf("Parsing user file '%s' in ECS container failed: %s", cfgFileName, err)
 		return nil, err
 	}
 	container.Users = users
-	log.Debugf("Parsing user file '%s' succeeded.", container.fileUsers.Name)
+	log.Debugf("Parsing user file '%s' succeeded.", usersFileName)
+
+	// load SNMPv3 users stored within the ECS container
+	log.Debugf("Parsing SNMP user file '%s' in ECS container...", snmpdFileName)
+	snmpUsers, err := snmpd.FileFromReader(bytes.NewReader(container.entry(snmpdFileName).data))
+	if err != nil {
+		log.Debugf("Parsing SNMP user file '%s' in ECS container failed: %s", snmpdFileName, err)
+		return nil, err
+	}
+	container.SnmpUsers = snmpUsers
+	log.Debugf("Parsing SNMP user file '%s' succeeded.", snmpdFileName)
 
 	log.Debug("Processing ECS container succeeded.")
 	return container, nil
 }
 
+// ContainerFromEncryptedFile reads an encrypted ECS container from the specified file and decrypts it using the
+// specified device certificate and private key (the certificate is optional and may be nil).
+func ContainerFromEncryptedFile(path string, deviceCertificate *x509.Certificate, privateKey crypto.PrivateKey) (*Container, error) {
+
+	// open the file for reading
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// read the encrypted ECS container
+	return ContainerFromEncryptedReader(file, deviceCertificate, privateKey)
+}
+
+// ContainerFromEncryptedReader reads an encrypted ECS container from the specified io.Reader and decrypts it using
+// the specified device certificate and private key (the certificate is optional and may be nil).
+func ContainerFromEncryptedReader(reader io.Reader, deviceCertificate *x509.Certificate, privateKey crypto.PrivateKey) (*Container, error) {
+
+	log.Debug("Decrypting ECS container...")
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// decrypt the ECS container
+	decryptedData, err := decryptWithCms(data, deviceCertificate, privateKey)
+	if err != nil {
+		log.Debugf("Decrypting ECS container failed: %s", err)
+		return nil, err
+	}
+
+	log.Debug("Decrypting ECS container succeeded.")
+
+	// read the decrypted ECS container
+	return ContainerFromReader(bytes.NewReader(decryptedData))
+}
+
+// IsEncryptedContainer checks whether the specified data looks like an encrypted ECS container
+// (CMS/PKCS#7 EnvelopedData structure, PEM or DER encoded).
+func IsEncryptedContainer(data []byte) bool {
+	return isCmsEnvelopedData(data)
+}
+
 // Dupe returns a copy of the ECS container.
 func (container *Container) Dupe() *Container {
 
 	copy := Container{
 		Atv:       container.Atv.Dupe(),
 		Users:     container.Users.Dupe(),
-		fileCfg:   container.fileCfg,
-		filePass:  container.filePass,
-		fileSnmpd: container.fileSnmpd,
-		fileUsers: container.fileUsers,
+		SnmpUsers: container.SnmpUsers.Dupe(),
+		entries:   make([]*tarEntry, 0, len(container.entries)),
+	}
+
+	for _, entry := range container.entries {
+		copy.entries = append(copy.entries, entry.dupe())
 	}
 
 	return &copy
@@ -209,35 +278,40 @@ func (container *Container) ToWriter(writer io.Writer) error {
 		return err
 	}
 
+	// determine the timestamp to clamp timestamps of entries to, if writing deterministically
+	var clampTime *time.Time
+	if IsDeterministicOutputEnabled() {
+		timestamp, err := GetDeterministicTimestamp()
+		if err != nil {
+			return err
+		}
+		clampTime = &timestamp
+	}
+
+	// the gzip header does not contain a file name or a timestamp
+	// (the header is the same each time)
 	gzipWriter := gzip.NewWriter(writer)
+	gzipWriter.Header = gzip.Header{OS: 255}
 	defer gzipWriter.Close()
 
 	tarWriter := tar.NewWriter(gzipWriter)
 	defer tarWriter.Close()
 
-	time := time.Now()
-	err = writeDirectoryToTar(tarWriter, time, "aca")
-	if err != nil {
-		return err
-	}
+	for _, entry := range container.entries {
 
-	files := []file{
-		container.fileCfg,
-		container.filePass,
-		container.fileSnmpd,
-		container.fileUsers,
-	}
+		// skip the configuration and the users, if they do not have any content
+		// (a new container without configuration/users, an empty 'aca/snmpd' is written to keep the container free of
+		// SNMPv3 users)
+		if entry.isRegular() && len(entry.data) == 0 && (entry.header.Name == cfgFileName || entry.header.Name == usersFileName) {
+			log.Debugf("  - entry: %s (SKIPPING)", entry.header.Name)
+			continue
+		}
 
-	for _, file := range files {
-		if len(file.Data) > 0 {
-			log.Debugf("  - file: %s...", file.Name)
-			err := writeFileToTar(tarWriter, file, time)
-			if err != nil {
-				log.Debugf("    ERROR: %s", err)
-				return err
-			}
-		} else {
-			log.Debugf("  - file: %s (SKIPPING)", container.fileCfg)
+		log.Debugf("  - entry: %s...", entry.header.Name)
+		err := entry.writeToTar(tarWriter, clampTime)
+		if err != nil {
+			log.Debugf("    ERROR: %s", err)
+			return err
 		}
 	}
 
@@ -274,56 +348,29 @@ func (container *Container) ToEncryptedWriter(writer io.Writer, deviceCertificat
 
 	log.Debug("Writing encrypted ECS container...")
 
-	// determine the path of the openssl executable
-	opensslExecutablePath, err := GetOpensslExecutablePath()
-	if err != nil {
-		return err
-	}
-
-	// create temporary directory to perform the encryption in
-	scratchDir, err := ioutil.TempDir("", "ecs-encryption")
-	if err != nil {
-		log.Errorf("%v", err)
-		return err
-	}
-	defer os.RemoveAll(scratchDir)
-
-	// save device certificate (PEM encoded)
-	block := &pem.Block{
-		Type:    "CERTIFICATE",
-		Headers: map[string]string{},
-		Bytes:   deviceCertificate.Raw,
-	}
-	err = ioutil.WriteFile(filepath.Join(scratchDir, "device.pem"), pem.EncodeToMemory(block), 644)
-	if err != nil {
-		return err
-	}
-
 	// write ECS container into a buffer
 	var ecsBuffer bytes.Buffer
-	err = container.ToWriter(&ecsBuffer)
+	err := container.ToWriter(&ecsBuffer)
 	if err != nil {
 		log.Errorf("Serializing ECS container failed: %s", err)
 		return err
 	}
 
 	// encrypt the ECS container
-	var encryptedEcs bytes.Buffer
-	var stderr bytes.Buffer
-	opensslCmd := exec.Command(opensslExecutablePath, "smime", "-encrypt", "-binary", "-outform", "PEM", "device.pem")
-	opensslCmd.Dir = scratchDir
-	opensslCmd.Stdin = bytes.NewReader(ecsBuffer.Bytes())
-	opensslCmd.Stdout = &encryptedEcs
-	opensslCmd.Stderr = &stderr
-	err = opensslCmd.Run()
+	// (use the built-in implementation, openssl only if explicitly enabled)
+	var encryptedEcs []byte
+	if IsOpensslEncryptionEnabled() {
+		encryptedEcs, err = encryptWithOpenssl(ecsBuffer.Bytes(), deviceCertificate)
+	} else {
+		encryptedEcs, err = encryptWithCms(ecsBuffer.Bytes(), deviceCertificate)
+	}
 	if err != nil {
-		log.Debugf("OpenSSL failed:\n%s\n", stderr.String())
 		log.Errorf("Encrypting ECS container failed: %s", err)
 		return err
 	}
 
 	// write the encrypted ECS container to the final destination
-	_, err = writer.Write(encryptedEcs.Bytes())
+	_, err = writer.Write(encryptedEcs)
 	if err != nil {
 		log.Errorf("Writing encrypted ECS container failed: %s", err)
 		return err
@@ -333,67 +380,77 @@ func (container *Container) ToEncryptedWriter(writer io.Writer, deviceCertificat
 	return nil
 }
 
+// updateFileBuffers updates the files the container generates from the configuration, the users and the SNMPv3 users.
 func (container *Container) updateFileBuffers() error {
 
 	// update the configuration in the container
 	if container.Atv != nil {
-		log.Debugf("Updating '%s' in ECS container...", container.fileCfg.Name)
+		log.Debugf("Updating '%s' in ECS container...", cfgFileName)
 		buffer := bytes.Buffer{}
 		err := container.Atv.ToWriter(&buffer)
 		if err != nil {
 			return err
 		}
-		container.fileCfg.Data = buffer.Bytes()
+		container.setFileData(cfgFileName, buffer.Bytes())
 	}
 
 	// update the user file in the container
 	if container.Users != nil {
-		log.Debugf("Updating '%s' in ECS container...", container.fileUsers.Name)
+		log.Debugf("Updating '%s' in ECS container...", usersFileName)
 		buffer := bytes.Buffer{}
 		err := container.Users.ToWriter(&buffer)
 		if err != nil {
 			return err
 		}
-		container.fileUsers.Data = buffer.Bytes()
+		container.setFileData(usersFileName, buffer.Bytes())
+	}
+
+	// update the SNMPv3 user file in the container
+	if container.SnmpUsers != nil {
+		log.Debugf("Updating '%s' in ECS container...", snmpdFileName)
+		buffer := bytes.Buffer{}
+		err := container.SnmpUsers.ToWriter(&buffer)
+		if err != nil {
+			return err
+		}
+		container.setFileData(snmpdFileName, buffer.Bytes())
 	}
 
 	return nil
 }
 
-// writeDirectoryToTar writes the specified directory into the tar archive.
-func writeDirectoryToTar(tarWriter *tar.Writer, time time.Time, dirPath string) error {
+// entry returns the entry with the specified name (nil, if the container does not contain such an entry).
+func (container *Container) entry(name string) *tarEntry {
 
-	header := &tar.Header{
-		Typeflag:   tar.TypeDir,
-		Name:       dirPath,
-		Size:       0,
-		Mode:       0700,
-		AccessTime: time,
-		ChangeTime: time,
-		ModTime:    time,
+	for _, entry := range container.entries {
+		if entry.header.Name == name {
+			return entry
+		}
 	}
 
-	return tarWriter.WriteHeader(header)
+	return nil
 }
 
-// writeFileToTar writes the specified regular file into the tar archive.
-func writeFileToTar(tarWriter *tar.Writer, file file, time time.Time) error {
+// setFileData sets the content of the regular file with the specified name. The file is added, if the container does
+// not contain it, yet.
+func (container *Container) setFileData(name string, data []byte) {
 
-	header := &tar.Header{
-		Typeflag:   tar.TypeReg,
-		Name:       file.Name,
-		Size:       int64(len(file.Data)),
-		Mode:       0600,
-		AccessTime: time,
-		ChangeTime: time,
-		ModTime:    time,
+	entry := container.entry(name)
+	if entry == nil {
+		container.entries = append(container.entries, newFileEntry(name, data, 0600, time.Now()))
+		return
 	}
 
-	err := tarWriter.WriteHeader(header)
-	if err != nil {
-		return err
+	entry.setData(data)
+}
+
+// isGeneratedFile checks whether the specified name is the name of a file the container generates from its documents.
+func isGeneratedFile(name string) bool {
+
+	switch name {
+	case cfgFileName, snmpdFileName, usersFileName:
+		return true
 	}
 
-	_, err = tarWriter.Write(file.Data)
-	return err
+	return false
 }
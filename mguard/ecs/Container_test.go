@@ -0,0 +1,76 @@
+package ecs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/griffinplus/mguard-config-tool/mguard/atv"
+)
+
+// writeContainerFromAtv wraps the specified ATV document in a new ECS container and returns the written container.
+func writeContainerFromAtv(t *testing.T, s string) []byte {
+	t.Helper()
+
+	file, err := atv.FromReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Parsing the ATV document failed: %v", err)
+	}
+
+	buffer := bytes.Buffer{}
+	err = ContainerFromATV(file).ToWriter(&buffer)
+	if err != nil {
+		t.Fatalf("Writing the ECS container failed: %v", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestContainerFromAtvIsDeterministic(t *testing.T) {
+
+	timestamp := time.Unix(1600000000, 0)
+	EnableDeterministicOutput(true)
+	SetDeterministicTimestamp(&timestamp)
+	defer EnableDeterministicOutput(false)
+	defer SetDeterministicTimestamp(nil)
+
+	document := "#version 8.8.1.default\nMY_HOSTNAME = \"mguard\"\n"
+	first := writeContainerFromAtv(t, document)
+	time.Sleep(1100 * time.Millisecond) // let the time of the second container differ
+	second := writeContainerFromAtv(t, document)
+
+	if !bytes.Equal(first, second) {
+		t.Fatalf("Writing the same ATV document twice resulted in different ECS containers")
+	}
+
+	// the default users must still have their default passwords
+	container, err := ContainerFromReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatalf("Reading the ECS container failed: %v", err)
+	}
+	for _, user := range defaultUsers {
+		if len(user.password) == 0 {
+			continue
+		}
+		ok, err := container.Users.VerifyPassword(user.username, user.password)
+		if err != nil || !ok {
+			t.Errorf("The default password of user '%s' does not match (error: %v)", user.username, err)
+		}
+	}
+	if !strings.Contains(container.Users.String(), ":18518:") {
+		t.Errorf("The day of the last password change is not taken from the deterministic timestamp\n%s", container.Users.String())
+	}
+}
+
+func TestContainerFromAtvHasNoSnmpUsers(t *testing.T) {
+
+	data := writeContainerFromAtv(t, "#version 8.8.1.default\nMY_HOSTNAME = \"mguard\"\n")
+	container, err := ContainerFromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Reading the ECS container failed: %v", err)
+	}
+	if users := container.SnmpUsers.Users(); len(users) != 0 {
+		t.Errorf("Expected no SNMPv3 users, got %v", users)
+	}
+}
@@ -0,0 +1,101 @@
+package ecs
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Entry describes an entry (file, directory, link, ...) in an ECS container.
+type Entry struct {
+	Name     string      // name of the entry (path in the container)
+	Mode     os.FileMode // type and permissions of the entry
+	UID      int         // user id of the owner
+	GID      int         // group id of the owner
+	Uname    string      // user name of the owner (may be empty)
+	Gname    string      // group name of the owner (may be empty)
+	ModTime  time.Time   // time of the last modification
+	Linkname string      // target of the link (links only)
+	Size     int64       // size of the content (regular files only)
+}
+
+// Entries returns all entries in the ECS container (in the order they appear in the container).
+func (container *Container) Entries() ([]Entry, error) {
+
+	// update file buffers first to reflect the correct state of the documents
+	err := container.updateFileBuffers()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(container.entries))
+	for _, entry := range container.entries {
+		entries = append(entries, entry.info())
+	}
+
+	return entries, nil
+}
+
+// ReadEntry returns the content of the regular file with the specified name in the ECS container.
+func (container *Container) ReadEntry(name string) ([]byte, error) {
+
+	// update file buffers first to reflect the correct state of the documents
+	err := container.updateFileBuffers()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := container.entry(name)
+	if entry == nil {
+		return nil, fmt.Errorf("The ECS container does not contain an entry named '%s'", name)
+	}
+
+	if !entry.isRegular() {
+		return nil, fmt.Errorf("The entry '%s' in the ECS container is not a regular file", name)
+	}
+
+	return append([]byte{}, entry.data...), nil
+}
+
+// AddEntry adds a regular file with the specified name, content and permissions to the ECS container.
+// If the container contains the file already, its content and permissions are replaced, its position and ownership
+// are kept. The configuration ('aca/cfg'), the users ('aca/users') and the SNMPv3 users ('aca/snmpd') cannot be
+// replaced, they are generated from Atv, Users and SnmpUsers.
+func (container *Container) AddEntry(name string, data []byte, mode os.FileMode) error {
+
+	if isGeneratedFile(name) {
+		return fmt.Errorf("The entry '%s' is generated by the ECS container and cannot be replaced", name)
+	}
+
+	entry := container.entry(name)
+	if entry == nil {
+		container.entries = append(container.entries, newFileEntry(name, append([]byte{}, data...), mode, time.Now()))
+		return nil
+	}
+
+	if !entry.isRegular() {
+		return fmt.Errorf("The entry '%s' in the ECS container is not a regular file", name)
+	}
+
+	entry.setData(append([]byte{}, data...))
+	entry.header.Mode = int64(mode.Perm())
+	return nil
+}
+
+// RemoveEntry removes the entry with the specified name from the ECS container.
+// The configuration ('aca/cfg'), the users ('aca/users') and the SNMPv3 users ('aca/snmpd') cannot be removed.
+func (container *Container) RemoveEntry(name string) error {
+
+	if isGeneratedFile(name) {
+		return fmt.Errorf("The entry '%s' is needed by the ECS container and cannot be removed", name)
+	}
+
+	for i, entry := range container.entries {
+		if entry.header.Name == name {
+			container.entries = append(container.entries[:i], container.entries[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The ECS container does not contain an entry named '%s'", name)
+}
@@ -0,0 +1,281 @@
+package ecs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// Object identifiers used in CMS/PKCS#7 structures
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRsaEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidDesEde3Cbc    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidDesCbc        = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 7}
+	oidAes128Cbc     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAes192Cbc     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAes256Cbc     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// cmsContentInfo is the outer structure of a CMS/PKCS#7 message (RFC 5652, section 3).
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// cmsEnvelopedData is the content of an encrypted CMS/PKCS#7 message (RFC 5652, section 6.1).
+type cmsEnvelopedData struct {
+	Version              int
+	RecipientInfos       []cmsKeyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo cmsEncryptedContentInfo
+}
+
+// cmsKeyTransRecipientInfo carries the content encryption key encrypted for a recipient (RFC 5652, section 6.2.1).
+type cmsKeyTransRecipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  cmsIssuerAndSerialNumber
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+// cmsIssuerAndSerialNumber identifies the certificate of a recipient (RFC 5652, section 10.2.4).
+type cmsIssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// cmsEncryptedContentInfo carries the encrypted content (RFC 5652, section 6.1).
+type cmsEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+// encryptWithCms encrypts the specified data for the owner of the specified certificate and returns a PEM encoded
+// CMS/PKCS#7 EnvelopedData structure. The output corresponds to 'openssl smime -encrypt -binary -outform PEM', i.e.
+// the content encryption key is transported using RSA (PKCS#1 v1.5) and the content is encrypted using Triple-DES-CBC.
+func encryptWithCms(data []byte, certificate *x509.Certificate) ([]byte, error) {
+
+	// the certificate must contain a RSA public key
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("The certificate does not contain a RSA public key")
+	}
+
+	// generate a random content encryption key and initialization vector
+	key := make([]byte, 24)
+	iv := make([]byte, des.BlockSize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypt the data (padding according to PKCS#7)
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := des.BlockSize - len(data)%des.BlockSize
+	encryptedData := make([]byte, 0, len(data)+padding)
+	encryptedData = append(encryptedData, data...)
+	encryptedData = append(encryptedData, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encryptedData, encryptedData)
+
+	// encrypt the content encryption key for the recipient
+	encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// assemble the EnvelopedData structure
+	ivParameter, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	envelopedData := cmsEnvelopedData{
+		Version: 0,
+		RecipientInfos: []cmsKeyTransRecipientInfo{
+			{
+				Version: 0,
+				IssuerAndSerialNumber: cmsIssuerAndSerialNumber{
+					Issuer:       asn1.RawValue{FullBytes: certificate.RawIssuer},
+					SerialNumber: certificate.SerialNumber,
+				},
+				KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+					Algorithm:  oidRsaEncryption,
+					Parameters: asn1.NullRawValue,
+				},
+				EncryptedKey: encryptedKey,
+			},
+		},
+		EncryptedContentInfo: cmsEncryptedContentInfo{
+			ContentType: oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidDesEde3Cbc,
+				Parameters: asn1.RawValue{FullBytes: ivParameter},
+			},
+			EncryptedContent: encryptedData,
+		},
+	}
+	envelopedDataBytes, err := asn1.Marshal(envelopedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// wrap the EnvelopedData structure in a ContentInfo structure
+	contentInfoBytes, err := asn1.Marshal(cmsContentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: envelopedDataBytes},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// encode the message as PEM
+	return pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: contentInfoBytes}), nil
+}
+
+// isCmsEnvelopedData checks whether the specified data is a CMS/PKCS#7 EnvelopedData structure (PEM or DER encoded).
+func isCmsEnvelopedData(data []byte) bool {
+	contentInfo, err := parseCmsContentInfo(data)
+	return err == nil && contentInfo.ContentType.Equal(oidEnvelopedData)
+}
+
+// parseCmsContentInfo parses the outer structure of a CMS/PKCS#7 message (PEM or DER encoded).
+func parseCmsContentInfo(data []byte) (*cmsContentInfo, error) {
+
+	// decode PEM, if the message is PEM encoded
+	der := data
+	block, _ := pem.Decode(data)
+	if block != nil {
+		if block.Type != "PKCS7" && block.Type != "CMS" {
+			return nil, fmt.Errorf("The PEM block has an unexpected type (%s)", block.Type)
+		}
+		der = block.Bytes
+	}
+
+	var contentInfo cmsContentInfo
+	_, err := asn1.Unmarshal(der, &contentInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contentInfo, nil
+}
+
+// decryptWithCms decrypts the specified CMS/PKCS#7 EnvelopedData structure (PEM or DER encoded) using the specified
+// private key and returns the decrypted data. If a certificate is specified, only the recipient issued by the certificate
+// is taken into account. Otherwise all recipients are tried. Only RSA key transport is supported.
+func decryptWithCms(data []byte, certificate *x509.Certificate, privateKey crypto.PrivateKey) ([]byte, error) {
+
+	// the private key must be a RSA key
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("The private key is not a RSA key")
+	}
+
+	// parse the EnvelopedData structure
+	contentInfo, err := parseCmsContentInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	if !contentInfo.ContentType.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("The message does not contain encrypted data (content type: %s)", contentInfo.ContentType)
+	}
+	var envelopedData cmsEnvelopedData
+	_, err = asn1.Unmarshal(contentInfo.Content.Bytes, &envelopedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// decrypt the content encryption key
+	var key []byte
+	for _, recipient := range envelopedData.RecipientInfos {
+
+		if certificate != nil {
+			if !bytes.Equal(recipient.IssuerAndSerialNumber.Issuer.FullBytes, certificate.RawIssuer) ||
+				recipient.IssuerAndSerialNumber.SerialNumber.Cmp(certificate.SerialNumber) != 0 {
+				continue
+			}
+		}
+
+		// skip recipients using other key encryption algorithms, unless the recipient was selected by the certificate
+		// (the message may be encrypted for other recipients using different algorithms)
+		if !recipient.KeyEncryptionAlgorithm.Algorithm.Equal(oidRsaEncryption) {
+			if certificate != nil {
+				return nil, fmt.Errorf("The key encryption algorithm (%s) is not supported", recipient.KeyEncryptionAlgorithm.Algorithm)
+			}
+			continue
+		}
+
+		key, err = rsa.DecryptPKCS1v15(rand.Reader, rsaPrivateKey, recipient.EncryptedKey)
+		if err == nil {
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("The message is not encrypted for the specified key")
+	}
+
+	// decrypt the content
+	encryptedContentInfo := &envelopedData.EncryptedContentInfo
+	var block cipher.Block
+	algorithm := encryptedContentInfo.ContentEncryptionAlgorithm.Algorithm
+	switch {
+	case algorithm.Equal(oidDesEde3Cbc):
+		block, err = des.NewTripleDESCipher(key)
+	case algorithm.Equal(oidDesCbc):
+		block, err = des.NewCipher(key)
+	case algorithm.Equal(oidAes128Cbc), algorithm.Equal(oidAes192Cbc), algorithm.Equal(oidAes256Cbc):
+		block, err = aes.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("The content encryption algorithm (%s) is not supported", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	_, err = asn1.Unmarshal(encryptedContentInfo.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("The initialization vector has an invalid length (%d)", len(iv))
+	}
+
+	encryptedData := encryptedContentInfo.EncryptedContent
+	if len(encryptedData) == 0 || len(encryptedData)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("The encrypted content has an invalid length (%d)", len(encryptedData))
+	}
+	decryptedData := make([]byte, len(encryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decryptedData, encryptedData)
+
+	// remove padding (PKCS#7)
+	padding := int(decryptedData[len(decryptedData)-1])
+	if padding == 0 || padding > block.BlockSize() || padding > len(decryptedData) {
+		return nil, fmt.Errorf("The decrypted content has an invalid padding")
+	}
+	for _, b := range decryptedData[len(decryptedData)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("The decrypted content has an invalid padding")
+		}
+	}
+
+	return decryptedData[:len(decryptedData)-padding], nil
+}
@@ -0,0 +1,271 @@
+package ecs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cmsTestData is the data encrypted in the tests (not a multiple of the block size to check the padding).
+var cmsTestData = []byte("#version 8.8.1.default\n\nACCESS_FTP = \"no\"\nROOT_PASSWORD = \"$1$ZL0WsCrL$iQgd6BqvPGBs6eMt5b6Zt0\"\n")
+
+// createTestCertificate creates a RSA key and a self-signed certificate for it.
+func createTestCertificate(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Generating the RSA key failed: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(4711),
+		Subject:      pkix.Name{CommonName: "mGuard Test Device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatalf("Creating the certificate failed: %v", err)
+	}
+
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	if err != nil {
+		t.Fatalf("Parsing the certificate failed: %v", err)
+	}
+
+	return certificate, privateKey
+}
+
+// writeTestCredentials writes the certificate and the private key as PEM files into the specified directory and
+// returns their paths.
+func writeTestCredentials(t *testing.T, dir string, certificate *x509.Certificate, privateKey *rsa.PrivateKey) (string, string) {
+
+	certificatePath := filepath.Join(dir, "cert.pem")
+	certificatePem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+	if err := ioutil.WriteFile(certificatePath, certificatePem, 0600); err != nil {
+		t.Fatalf("Writing the certificate failed: %v", err)
+	}
+
+	privateKeyPath := filepath.Join(dir, "key.pem")
+	privateKeyPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	if err := ioutil.WriteFile(privateKeyPath, privateKeyPem, 0600); err != nil {
+		t.Fatalf("Writing the private key failed: %v", err)
+	}
+
+	return certificatePath, privateKeyPath
+}
+
+// findOpensslForTest returns the path of the openssl executable or skips the test, if openssl is not installed.
+func findOpensslForTest(t *testing.T) string {
+	path, err := FindOpensslExecutablePath()
+	if err != nil {
+		t.Skipf("The openssl executable was not found: %v", err)
+	}
+	return path
+}
+
+func TestEncryptWithCmsCreatesEnvelopedData(t *testing.T) {
+
+	certificate, _ := createTestCertificate(t)
+
+	encrypted, err := encryptWithCms(cmsTestData, certificate)
+	if err != nil {
+		t.Fatalf("Encrypting failed: %v", err)
+	}
+
+	if !isCmsEnvelopedData(encrypted) {
+		t.Fatalf("The encrypted data is not recognized as CMS EnvelopedData")
+	}
+
+	// the content encryption key is random, so encrypting the same data twice must result in different messages
+	other, err := encryptWithCms(cmsTestData, certificate)
+	if err != nil {
+		t.Fatalf("Encrypting failed: %v", err)
+	}
+	if bytes.Equal(encrypted, other) {
+		t.Errorf("Encrypting the same data twice resulted in the same message")
+	}
+}
+
+func TestEncryptWithCmsDecryptWithOpenssl(t *testing.T) {
+
+	opensslPath := findOpensslForTest(t)
+
+	dir, err := ioutil.TempDir("", "mguard-config-tool-test")
+	if err != nil {
+		t.Fatalf("Creating the temporary directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certificate, privateKey := createTestCertificate(t)
+	certificatePath, privateKeyPath := writeTestCredentials(t, dir, certificate, privateKey)
+
+	encrypted, err := encryptWithCms(cmsTestData, certificate)
+	if err != nil {
+		t.Fatalf("Encrypting failed: %v", err)
+	}
+
+	encryptedPath := filepath.Join(dir, "encrypted.pem")
+	if err := ioutil.WriteFile(encryptedPath, encrypted, 0600); err != nil {
+		t.Fatalf("Writing the encrypted data failed: %v", err)
+	}
+
+	output, err := exec.Command(opensslPath, "smime", "-decrypt", "-binary",
+		"-inform", "PEM", "-in", encryptedPath,
+		"-recip", certificatePath, "-inkey", privateKeyPath).Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			t.Fatalf("Decrypting with openssl failed: %v\n%s", err, exitError.Stderr)
+		}
+		t.Fatalf("Decrypting with openssl failed: %v", err)
+	}
+
+	if !bytes.Equal(output, cmsTestData) {
+		t.Errorf("The data decrypted by openssl differs from the original data\nexpected: %q\ngot:      %q", cmsTestData, output)
+	}
+}
+
+func TestDecryptWithCmsRoundTrip(t *testing.T) {
+
+	certificate, privateKey := createTestCertificate(t)
+
+	encrypted, err := encryptWithCms(cmsTestData, certificate)
+	if err != nil {
+		t.Fatalf("Encrypting failed: %v", err)
+	}
+
+	decrypted, err := decryptWithCms(encrypted, certificate, privateKey)
+	if err != nil {
+		t.Fatalf("Decrypting failed: %v", err)
+	}
+
+	if !bytes.Equal(decrypted, cmsTestData) {
+		t.Errorf("The decrypted data differs from the original data\nexpected: %q\ngot:      %q", cmsTestData, decrypted)
+	}
+
+	// decrypting without the certificate tries all recipients
+	decrypted, err = decryptWithCms(encrypted, nil, privateKey)
+	if err != nil {
+		t.Fatalf("Decrypting without certificate failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, cmsTestData) {
+		t.Errorf("The decrypted data (without certificate) differs from the original data")
+	}
+
+	// decrypting with a different key must fail
+	_, otherPrivateKey := createTestCertificate(t)
+	_, err = decryptWithCms(encrypted, nil, otherPrivateKey)
+	if err == nil {
+		t.Errorf("Decrypting with a different key succeeded unexpectedly")
+	}
+}
+
+func TestDecryptWithCmsSkipsRecipientsWithOtherAlgorithms(t *testing.T) {
+
+	certificate, privateKey := createTestCertificate(t)
+
+	encrypted, err := encryptWithCms(cmsTestData, certificate)
+	if err != nil {
+		t.Fatalf("Encrypting failed: %v", err)
+	}
+
+	// add a recipient using RSAES-OAEP (encoded shorter than the RSA recipient, so it comes first in the DER encoded set)
+	contentInfo, err := parseCmsContentInfo(encrypted)
+	if err != nil {
+		t.Fatalf("Parsing the encrypted data failed: %v", err)
+	}
+	var envelopedData cmsEnvelopedData
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &envelopedData); err != nil {
+		t.Fatalf("Parsing the EnvelopedData structure failed: %v", err)
+	}
+	otherRecipient := cmsKeyTransRecipientInfo{
+		IssuerAndSerialNumber: cmsIssuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: certificate.RawIssuer},
+			SerialNumber: big.NewInt(4712),
+		},
+		KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}},
+		EncryptedKey:           []byte{1, 2, 3, 4},
+	}
+	envelopedData.RecipientInfos = append([]cmsKeyTransRecipientInfo{otherRecipient}, envelopedData.RecipientInfos...)
+	envelopedDataBytes, err := asn1.Marshal(envelopedData)
+	if err != nil {
+		t.Fatalf("Encoding the EnvelopedData structure failed: %v", err)
+	}
+	message, err := asn1.Marshal(cmsContentInfo{
+		ContentType: oidEnvelopedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: envelopedDataBytes},
+	})
+	if err != nil {
+		t.Fatalf("Encoding the message failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		certificate *x509.Certificate
+	}{
+		{"with certificate", certificate},
+		{"without certificate", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decrypted, err := decryptWithCms(message, test.certificate, privateKey)
+			if err != nil {
+				t.Fatalf("Decrypting failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, cmsTestData) {
+				t.Errorf("The decrypted data differs from the original data")
+			}
+		})
+	}
+}
+
+func TestDecryptWithCmsEncryptedWithOpenssl(t *testing.T) {
+
+	opensslPath := findOpensslForTest(t)
+
+	dir, err := ioutil.TempDir("", "mguard-config-tool-test")
+	if err != nil {
+		t.Fatalf("Creating the temporary directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certificate, privateKey := createTestCertificate(t)
+	certificatePath, _ := writeTestCredentials(t, dir, certificate, privateKey)
+
+	dataPath := filepath.Join(dir, "data")
+	if err := ioutil.WriteFile(dataPath, cmsTestData, 0600); err != nil {
+		t.Fatalf("Writing the data failed: %v", err)
+	}
+
+	for _, cipher := range []string{"-des3", "-aes256"} {
+
+		encrypted, err := exec.Command(opensslPath, "smime", "-encrypt", "-binary", cipher,
+			"-outform", "PEM", "-in", dataPath, certificatePath).Output()
+		if err != nil {
+			t.Fatalf("Encrypting with openssl (%s) failed: %v", cipher, err)
+		}
+
+		decrypted, err := decryptWithCms(encrypted, certificate, privateKey)
+		if err != nil {
+			t.Fatalf("Decrypting data encrypted by openssl (%s) failed: %v", cipher, err)
+		}
+
+		if !bytes.Equal(decrypted, cmsTestData) {
+			t.Errorf("The decrypted data (%s) differs from the original data\nexpected: %q\ngot:      %q", cipher, cmsTestData, decrypted)
+		}
+	}
+}
@@ -1,23 +1,71 @@
 package ecs
 
-import "github.com/griffinplus/mguard-config-tool/shadow"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/griffinplus/mguard-config-tool/shadow"
+	"github.com/griffinplus/mguard-config-tool/snmpd"
+)
 
 // DefaultPassFileContent contains the default content of the 'aca/pass' file of an ECS container.
 const DefaultPassFileContent = `root\n`
 
-// DefaultSnmpdFileContent contains the default content of the 'aca/snmpd' file of an ECS container.
-const DefaultSnmpdFileContent = `createUser "admin" MD5 "SnmpAdmin" DES "SnmpAdmin"\n`
+// DefaultSnmpdFileContent contains the default content of the 'aca/snmpd' file of an ECS container
+// (no SNMPv3 users, the factory settings contain the user 'admin' with a well-known passphrase that is not added to
+// containers created by the tool).
+const DefaultSnmpdFileContent = ""
+
+// defaultUsers contains the users of a new shadow file with their default passwords (empty to disable the account) and
+// the hashes of the default passwords that are used when writing deterministically (sha512-crypt with a fixed salt, the
+// default passwords are well-known, so a fixed salt does not give away anything).
+var defaultUsers = []struct {
+	username string
+	password string
+	hash     string
+}{
+	{"root", "root", "$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/"},
+	{"admin", "mGuard", "$6$mGuardDefault$BdgANLBEqCiILNkNkYUHEQhpRBiQKObAQzP0rseEjky528gmqub4xmbWEkLZg/RCU690XB2/DemxTV9.7LzR90"},
+	{"user", "", ""},
+	{"netadmin", "", ""},
+	{"audit", "", ""},
+	{"userfwd", "", ""},
+}
 
 // createDefaultShadowFile creates a new shadow file with default passwords that can be put into the 'aca/users'
-// file of an ECS container.
+// file of an ECS container. When writing deterministically, the passwords get fixed hashes and the day of the last
+// password change is taken from the deterministic timestamp, so the same input always results in the same file.
 func createDefaultShadowFile() *shadow.File {
 
+	if IsDeterministicOutputEnabled() {
+		// an invalid timestamp is reported when writing the container
+		if timestamp, err := GetDeterministicTimestamp(); err == nil {
+			buffer := strings.Builder{}
+			for _, user := range defaultUsers {
+				password := user.hash
+				if len(password) == 0 {
+					password = "!" // disabled account
+				}
+				buffer.WriteString(fmt.Sprintf("%s:%s:%d::::::\n", user.username, password, shadow.Day(timestamp)))
+			}
+			file, err := shadow.FileFromReader(strings.NewReader(buffer.String()))
+			if err != nil {
+				// should not occur...
+				panic("Unexpected error when parsing the default shadow file")
+			}
+			return file
+		}
+	}
+
 	file := shadow.NewFile()
-	file.AddUser("root", "root")
-	file.AddUser("admin", "mGuard")
-	file.AddUser("user", "")
-	file.AddUser("netadmin", "")
-	file.AddUser("audit", "")
-	file.AddUser("userfwd", "")
+	for _, user := range defaultUsers {
+		file.AddUser(user.username, user.password)
+	}
 	return file
 }
+
+// createDefaultSnmpdFile creates a new snmpd file without SNMPv3 users that can be put into the 'aca/snmpd' file of an
+// ECS container.
+func createDefaultSnmpdFile() *snmpd.File {
+	return snmpd.NewFile()
+}
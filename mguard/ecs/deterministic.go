@@ -0,0 +1,70 @@
+package ecs
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Determines whether ECS containers are written deterministically (explicitly enabled).
+var deterministicOutputEnabled bool
+
+// Timestamp that is used when writing ECS containers deterministically
+// (can be set to explicitly use it instead of SOURCE_DATE_EPOCH or the default timestamp).
+var deterministicTimestamp *time.Time
+
+// DefaultDeterministicTimestamp is the timestamp that is used when writing deterministically, if neither a timestamp
+// was set explicitly nor SOURCE_DATE_EPOCH is set (1980-01-01 00:00:00 UTC, the earliest time zip archives support).
+var DefaultDeterministicTimestamp = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsDeterministicOutputEnabled checks whether ECS containers are written deterministically, i.e. whether the same
+// container always results in the same bytes. Deterministic output is enabled explicitly (EnableDeterministicOutput)
+// or by setting the environment variable SOURCE_DATE_EPOCH (see https://reproducible-builds.org/specs/source-date-epoch/).
+func IsDeterministicOutputEnabled() bool {
+	return deterministicOutputEnabled || len(os.Getenv("SOURCE_DATE_EPOCH")) > 0
+}
+
+// EnableDeterministicOutput controls whether ECS containers are written deterministically (true) or not (false,
+// default). When writing deterministically, timestamps of entries that are newer than the timestamp returned by
+// GetDeterministicTimestamp() are set to it, so entries that are created or modified get the same timestamp each time.
+func EnableDeterministicOutput(enable bool) {
+	deterministicOutputEnabled = enable
+}
+
+// GetDeterministicTimestamp returns the timestamp that is used when writing deterministically.
+// If SetDeterministicTimestamp was called, it simply returns the set timestamp.
+// If SetDeterministicTimestamp was not called, it returns the timestamp specified by the environment variable
+// SOURCE_DATE_EPOCH (seconds since 1970-01-01 00:00:00 UTC) or DefaultDeterministicTimestamp, if it is not set.
+func GetDeterministicTimestamp() (time.Time, error) {
+
+	if deterministicTimestamp != nil {
+		return *deterministicTimestamp, nil
+	}
+
+	epoch := strings.TrimSpace(os.Getenv("SOURCE_DATE_EPOCH"))
+	if len(epoch) == 0 {
+		return DefaultDeterministicTimestamp, nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, fmt.Errorf("SOURCE_DATE_EPOCH (%s) is not a valid number of seconds since 1970-01-01 00:00:00 UTC", epoch)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// SetDeterministicTimestamp explicitly sets the timestamp that is used when writing deterministically.
+// Specify nil to use SOURCE_DATE_EPOCH or the default timestamp.
+func SetDeterministicTimestamp(timestamp *time.Time) {
+
+	if timestamp == nil {
+		deterministicTimestamp = nil
+		return
+	}
+
+	utc := timestamp.UTC()
+	deterministicTimestamp = &utc
+}
@@ -1,13 +1,22 @@
 package ecs
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// Determines whether ECS containers are encrypted using the openssl executable instead of the built-in implementation.
+var opensslEncryptionEnabled bool
+
 // Path of the the openssl executable that is used to encrypt ECS containers
 // (can be set to explicitly use it instead of an executable that can be found searching the PATH variable).
 var opensslExecutablePath string
@@ -21,6 +30,13 @@ func GetOpensslExecutablePath() (string, error) {
 		return opensslExecutablePath, nil
 	}
 
+	return FindOpensslExecutablePath()
+}
+
+// FindOpensslExecutablePath tries to find the openssl executable using the PATH variable and returns the absolute
+// path to it (ignores the path set using SetOpensslExecutablePath).
+func FindOpensslExecutablePath() (string, error) {
+
 	// determine the name of the openssl binary
 	var opensslBinaryFilename string
 	if runtime.GOOS == "windows" {
@@ -67,3 +83,61 @@ func SetOpensslExecutablePath(path string) error {
 	opensslExecutablePath = path
 	return nil
 }
+
+// IsOpensslEncryptionEnabled checks whether ECS containers are encrypted using the openssl executable.
+func IsOpensslEncryptionEnabled() bool {
+	return opensslEncryptionEnabled
+}
+
+// EnableOpensslEncryption controls whether ECS containers are encrypted using the openssl executable (true) or
+// using the built-in implementation (false, default). The openssl executable is looked up as described at
+// GetOpensslExecutablePath().
+func EnableOpensslEncryption(enable bool) {
+	opensslEncryptionEnabled = enable
+}
+
+// encryptWithOpenssl encrypts the specified data for the owner of the specified certificate using the openssl
+// executable and returns the encrypted data (PEM encoded CMS/PKCS#7 EnvelopedData structure).
+func encryptWithOpenssl(data []byte, certificate *x509.Certificate) ([]byte, error) {
+
+	// determine the path of the openssl executable
+	opensslExecutablePath, err := GetOpensslExecutablePath()
+	if err != nil {
+		return nil, err
+	}
+
+	// create temporary directory to perform the encryption in
+	scratchDir, err := ioutil.TempDir("", "ecs-encryption")
+	if err != nil {
+		log.Errorf("%v", err)
+		return nil, err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	// save certificate (PEM encoded)
+	block := &pem.Block{
+		Type:    "CERTIFICATE",
+		Headers: map[string]string{},
+		Bytes:   certificate.Raw,
+	}
+	err = ioutil.WriteFile(filepath.Join(scratchDir, "device.pem"), pem.EncodeToMemory(block), 644)
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypt the data
+	var encryptedData bytes.Buffer
+	var stderr bytes.Buffer
+	opensslCmd := exec.Command(opensslExecutablePath, "smime", "-encrypt", "-binary", "-outform", "PEM", "device.pem")
+	opensslCmd.Dir = scratchDir
+	opensslCmd.Stdin = bytes.NewReader(data)
+	opensslCmd.Stdout = &encryptedData
+	opensslCmd.Stderr = &stderr
+	err = opensslCmd.Run()
+	if err != nil {
+		log.Debugf("OpenSSL failed:\n%s\n", stderr.String())
+		return nil, err
+	}
+
+	return encryptedData.Bytes(), nil
+}
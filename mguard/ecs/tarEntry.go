@@ -0,0 +1,143 @@
+package ecs
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"time"
+)
+
+// tarEntry is an entry (file, directory, link, ...) of the tar archive in an ECS container.
+// The header is kept as read to preserve name, mode, ownership and timestamps when writing the archive.
+type tarEntry struct {
+	header tar.Header // header of the entry
+	data   []byte     // content of the entry (regular files only)
+}
+
+// newFileEntry returns a new entry for a regular file.
+func newFileEntry(name string, data []byte, mode os.FileMode, modTime time.Time) *tarEntry {
+
+	return &tarEntry{
+		header: tar.Header{
+			Typeflag:   tar.TypeReg,
+			Name:       name,
+			Size:       int64(len(data)),
+			Mode:       int64(mode.Perm()),
+			AccessTime: modTime,
+			ChangeTime: modTime,
+			ModTime:    modTime,
+		},
+		data: data,
+	}
+}
+
+// newDirectoryEntry returns a new entry for a directory.
+func newDirectoryEntry(name string, mode os.FileMode, modTime time.Time) *tarEntry {
+
+	return &tarEntry{
+		header: tar.Header{
+			Typeflag:   tar.TypeDir,
+			Name:       name,
+			Mode:       int64(mode.Perm()),
+			AccessTime: modTime,
+			ChangeTime: modTime,
+			ModTime:    modTime,
+		},
+	}
+}
+
+// isRegular checks whether the entry is a regular file.
+func (entry *tarEntry) isRegular() bool {
+	return entry.header.Typeflag == tar.TypeReg || entry.header.Typeflag == tar.TypeRegA
+}
+
+// setData sets the content of the entry. The modification time is updated, if the content changes.
+func (entry *tarEntry) setData(data []byte) {
+
+	if bytes.Equal(entry.data, data) {
+		return
+	}
+
+	now := time.Now()
+	entry.data = data
+	entry.header.Size = int64(len(data))
+	entry.header.ModTime = now
+	if !entry.header.AccessTime.IsZero() {
+		entry.header.AccessTime = now
+	}
+	if !entry.header.ChangeTime.IsZero() {
+		entry.header.ChangeTime = now
+	}
+}
+
+// dupe returns a copy of the entry.
+func (entry *tarEntry) dupe() *tarEntry {
+
+	copy := &tarEntry{header: entry.header}
+	if entry.data != nil {
+		copy.data = append([]byte{}, entry.data...)
+	}
+
+	if entry.header.PAXRecords != nil {
+		copy.header.PAXRecords = make(map[string]string, len(entry.header.PAXRecords))
+		for key, value := range entry.header.PAXRecords {
+			copy.header.PAXRecords[key] = value
+		}
+	}
+
+	return copy
+}
+
+// info returns the public description of the entry.
+func (entry *tarEntry) info() Entry {
+
+	return Entry{
+		Name:     entry.header.Name,
+		Mode:     entry.header.FileInfo().Mode(),
+		UID:      entry.header.Uid,
+		GID:      entry.header.Gid,
+		Uname:    entry.header.Uname,
+		Gname:    entry.header.Gname,
+		ModTime:  entry.header.ModTime,
+		Linkname: entry.header.Linkname,
+		Size:     int64(len(entry.data)),
+	}
+}
+
+// writeToTar writes the entry into the specified tar archive.
+// If a clamp time is specified, timestamps that are newer than the clamp time are set to the clamp time.
+func (entry *tarEntry) writeToTar(tarWriter *tar.Writer, clampTime *time.Time) error {
+
+	header := entry.header
+	if entry.isRegular() {
+		header.Size = int64(len(entry.data))
+	}
+
+	if clampTime != nil {
+		header.ModTime = clampTimestamp(header.ModTime, *clampTime)
+		header.AccessTime = clampTimestamp(header.AccessTime, *clampTime)
+		header.ChangeTime = clampTimestamp(header.ChangeTime, *clampTime)
+	}
+
+	err := tarWriter.WriteHeader(&header)
+	if err != nil {
+		return err
+	}
+
+	if entry.isRegular() {
+		_, err = tarWriter.Write(entry.data)
+	}
+
+	return err
+}
+
+// clampTimestamp returns the specified timestamp, if it is not newer than the clamp time, otherwise the clamp time.
+// Timestamps that are not set are kept.
+func clampTimestamp(timestamp time.Time, clampTime time.Time) time.Time {
+
+	if timestamp.IsZero() || !timestamp.After(clampTime) {
+		return timestamp
+	}
+
+	return clampTime
+}
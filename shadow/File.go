@@ -98,6 +98,19 @@ func (file *File) SetPassword(username string, password string) error {
 	return fmt.Errorf("The specified user (%s) does not exist", username)
 }
 
+// SetPasswordHash sets the hashed password of the specified user (sha256-crypt: '$5$...', sha512-crypt: '$6$...').
+// Use HashPassword() to hash passwords with a specific algorithm and number of rounds.
+func (file *File) SetPasswordHash(username string, hash string) error {
+
+	for _, line := range file.lines {
+		if line.Username == username {
+			return line.SetPasswordHash(hash)
+		}
+	}
+
+	return fmt.Errorf("The specified user (%s) does not exist", username)
+}
+
 // VerifyPassword verifys the password of the specified user.
 func (file *File) VerifyPassword(username string, password string) (bool, error) {
 
@@ -110,6 +123,102 @@ func (file *File) VerifyPassword(username string, password string) (bool, error)
 	return false, fmt.Errorf("The specified user (%s) does not exist", username)
 }
 
+// Users returns the user accounts in the shadow file (in the order they appear in the file).
+func (file *File) Users() ([]User, error) {
+
+	users := make([]User, 0, len(file.lines))
+	for _, line := range file.lines {
+		user, err := line.user()
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// User returns the user account with the specified name.
+func (file *File) User(username string) (User, error) {
+
+	line := file.line(username)
+	if line == nil {
+		return User{}, fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	return line.user()
+}
+
+// RemoveUser removes the specified user from the shadow file.
+func (file *File) RemoveUser(username string) error {
+
+	for i, line := range file.lines {
+		if line.Username == username {
+			file.lines = append(file.lines[:i], file.lines[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The specified user (%s) does not exist", username)
+}
+
+// LockUser locks the password of the specified user, so the user cannot log in with the password any more.
+// The password is kept, so unlocking the user restores the password.
+func (file *File) LockUser(username string) error {
+
+	line := file.line(username)
+	if line == nil {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	if !strings.HasPrefix(line.Password, "!") {
+		line.Password = "!" + line.Password
+	}
+
+	return nil
+}
+
+// UnlockUser unlocks the password of the specified user that was locked using LockUser().
+func (file *File) UnlockUser(username string) error {
+
+	line := file.line(username)
+	if line == nil {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	password := strings.TrimLeft(line.Password, "!")
+	if len(password) == 0 || password == "*" {
+		return fmt.Errorf("The specified user (%s) does not have a password, please set a password instead", username)
+	}
+
+	line.Password = password
+	return nil
+}
+
+// ExpirePassword expires the password of the specified user, so the user must change the password at the next login.
+func (file *File) ExpirePassword(username string) error {
+
+	line := file.line(username)
+	if line == nil {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	line.LastChanged = "0"
+	return nil
+}
+
+// SetAging sets the password aging fields of the specified user.
+func (file *File) SetAging(username string, aging Aging) error {
+
+	line := file.line(username)
+	if line == nil {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	line.setAging(aging)
+	return nil
+}
+
 // String returns the entire shadow file as a string.
 func (file *File) String() string {
 	builder := strings.Builder{}
@@ -119,3 +228,15 @@ func (file *File) String() string {
 	}
 	return builder.String()
 }
+
+// line returns the line of the specified user (nil, if the user does not exist).
+func (file *File) line(username string) *line {
+
+	for _, line := range file.lines {
+		if line.Username == username {
+			return line
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package shadow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// shadowTestFile is a shadow file with users in different states.
+const shadowTestFile = `root:$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/:18518:0:99999:7:::
+admin:!$6$mGuardDefault$BdgANLBEqCiILNkNkYUHEQhpRBiQKObAQzP0rseEjky528gmqub4xmbWEkLZg/RCU690XB2/DemxTV9.7LzR90:18518::::::
+user:!:18518::::::
+audit:::::::20000:
+`
+
+// fileFromString parses the specified shadow file.
+func fileFromString(t *testing.T, s string) *File {
+	t.Helper()
+
+	file, err := FileFromReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Parsing the shadow file failed: %v", err)
+	}
+
+	return file
+}
+
+// days returns a pointer to the specified number of days.
+func days(n int) *int {
+	return &n
+}
+
+func TestFileUsers(t *testing.T) {
+
+	file := fileFromString(t, shadowTestFile)
+	users, err := file.Users()
+	if err != nil {
+		t.Fatalf("Getting the users failed: %v", err)
+	}
+
+	expected := []User{
+		{Name: "root", PasswordStatus: UsablePassword, Aging: Aging{LastChanged: days(18518), Minimum: days(0), Maximum: days(99999), Warn: days(7)}},
+		{Name: "admin", PasswordStatus: LockedPassword, Aging: Aging{LastChanged: days(18518)}},
+		{Name: "user", PasswordStatus: LockedPassword, Aging: Aging{LastChanged: days(18518)}},
+		{Name: "audit", PasswordStatus: NoPassword, Aging: Aging{Expire: days(20000)}},
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("Unexpected users\nexpected: %+v\ngot:      %+v", expected, users)
+	}
+}
+
+func TestFileUsersReportsInvalidAgingFields(t *testing.T) {
+
+	file := fileFromString(t, "root:!:yesterday::::::\n")
+	if _, err := file.Users(); err == nil {
+		t.Errorf("Getting the users succeeded unexpectedly")
+	}
+	if _, err := file.User("root"); err == nil {
+		t.Errorf("Getting the user succeeded unexpectedly")
+	}
+}
+
+func TestFileFromReaderReportsInvalidLines(t *testing.T) {
+
+	for _, s := range []string{"root:!:18518:::::\n", "root:!:18518:::::::\n", "root\n"} {
+		if _, err := FileFromReader(strings.NewReader(s)); err == nil {
+			t.Errorf("Parsing '%s' succeeded unexpectedly", strings.TrimSpace(s))
+		}
+	}
+}
+
+func TestFileOperations(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		modify   func(file *File) error
+		username string
+		expected string // the line of the user after the operation (empty, if the user was removed)
+	}{
+		{
+			"lock user",
+			func(file *File) error { return file.LockUser("root") },
+			"root",
+			"root:!$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/:18518:0:99999:7:::",
+		},
+		{
+			"lock locked user",
+			func(file *File) error { return file.LockUser("admin") },
+			"admin",
+			"admin:!$6$mGuardDefault$BdgANLBEqCiILNkNkYUHEQhpRBiQKObAQzP0rseEjky528gmqub4xmbWEkLZg/RCU690XB2/DemxTV9.7LzR90:18518::::::",
+		},
+		{
+			"unlock user",
+			func(file *File) error { return file.UnlockUser("admin") },
+			"admin",
+			"admin:$6$mGuardDefault$BdgANLBEqCiILNkNkYUHEQhpRBiQKObAQzP0rseEjky528gmqub4xmbWEkLZg/RCU690XB2/DemxTV9.7LzR90:18518::::::",
+		},
+		{
+			"expire password",
+			func(file *File) error { return file.ExpirePassword("root") },
+			"root",
+			"root:$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/:0:0:99999:7:::",
+		},
+		{
+			"set aging",
+			func(file *File) error {
+				return file.SetAging("user", Aging{LastChanged: days(18600), Maximum: days(90), Warn: days(14), Expire: days(19000)})
+			},
+			"user",
+			"user:!:18600::90:14::19000:",
+		},
+		{
+			"clear aging",
+			func(file *File) error { return file.SetAging("root", Aging{}) },
+			"root",
+			"root:$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/:::::::",
+		},
+		{
+			"remove user",
+			func(file *File) error { return file.RemoveUser("user") },
+			"user",
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			file := fileFromString(t, shadowTestFile)
+			if err := test.modify(file); err != nil {
+				t.Fatalf("Modifying the file failed: %v", err)
+			}
+
+			// the line of the user must have changed as expected, the other lines must be kept
+			var expectedLines []string
+			for _, line := range strings.SplitAfter(shadowTestFile, "\n") {
+				if strings.HasPrefix(line, test.username+":") {
+					if len(test.expected) > 0 {
+						expectedLines = append(expectedLines, test.expected+"\n")
+					}
+					continue
+				}
+				expectedLines = append(expectedLines, line)
+			}
+			expected := strings.Join(expectedLines, "")
+			if file.String() != expected {
+				t.Errorf("Unexpected file\nexpected:\n%s\ngot:\n%s", expected, file.String())
+			}
+		})
+	}
+}
+
+func TestFileOperationsReportErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(file *File) error
+	}{
+		{"add existing user", func(file *File) error { return file.AddUser("root", "secret") }},
+		{"set password of unknown user", func(file *File) error { return file.SetPassword("unknown", "secret") }},
+		{"remove unknown user", func(file *File) error { return file.RemoveUser("unknown") }},
+		{"lock unknown user", func(file *File) error { return file.LockUser("unknown") }},
+		{"unlock unknown user", func(file *File) error { return file.UnlockUser("unknown") }},
+		{"unlock user without password", func(file *File) error { return file.UnlockUser("user") }},
+		{"unlock user with empty password", func(file *File) error { return file.UnlockUser("audit") }},
+		{"expire password of unknown user", func(file *File) error { return file.ExpirePassword("unknown") }},
+		{"set aging of unknown user", func(file *File) error { return file.SetAging("unknown", Aging{}) }},
+		{"get unknown user", func(file *File) error { _, err := file.User("unknown"); return err }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := fileFromString(t, shadowTestFile)
+			if err := test.modify(file); err == nil {
+				t.Fatalf("The operation succeeded unexpectedly")
+			}
+			if file.String() != shadowTestFile {
+				t.Errorf("The file was modified although the operation failed")
+			}
+		})
+	}
+}
+
+func TestFileSetPassword(t *testing.T) {
+
+	file := fileFromString(t, shadowTestFile)
+	if err := file.AddUser("operator", "secret"); err != nil {
+		t.Fatalf("Adding the user failed: %v", err)
+	}
+	if err := file.SetPassword("root", "changed"); err != nil {
+		t.Fatalf("Setting the password failed: %v", err)
+	}
+
+	tests := []struct {
+		username string
+		password string
+		expected bool
+	}{
+		{"operator", "secret", true},
+		{"operator", "wrong", false},
+		{"root", "changed", true},
+		{"root", "root", false},
+	}
+
+	for _, test := range tests {
+		ok, err := file.VerifyPassword(test.username, test.password)
+		if err != nil {
+			t.Fatalf("Verifying the password of user '%s' failed: %v", test.username, err)
+		}
+		if ok != test.expected {
+			t.Errorf("Verifying password '%s' of user '%s' returned %v, expected %v", test.password, test.username, ok, test.expected)
+		}
+	}
+
+	// an empty password disables the account
+	if err := file.SetPassword("root", ""); err != nil {
+		t.Fatalf("Setting an empty password failed: %v", err)
+	}
+	if user, _ := file.User("root"); user.PasswordStatus != LockedPassword {
+		t.Errorf("Setting an empty password did not lock the account (status: %s)", user.PasswordStatus)
+	}
+}
@@ -0,0 +1,118 @@
+package shadow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tredoe/osutil/user/crypt"
+	"github.com/tredoe/osutil/user/crypt/common"
+	"github.com/tredoe/osutil/user/crypt/sha256_crypt"
+	"github.com/tredoe/osutil/user/crypt/sha512_crypt"
+)
+
+// Algorithm is a crypt algorithm passwords are hashed with.
+type Algorithm string
+
+const (
+	// SHA256Crypt is the sha256-crypt algorithm (hashes start with '$5$').
+	SHA256Crypt Algorithm = "sha256"
+
+	// SHA512Crypt is the sha512-crypt algorithm (hashes start with '$6$', default).
+	SHA512Crypt Algorithm = "sha512"
+)
+
+// DefaultRounds is the number of rounds passwords are hashed with, if the number of rounds is not specified.
+const DefaultRounds = 5000
+
+// MinRounds is the minimum number of rounds passwords can be hashed with.
+const MinRounds = 1000
+
+// MaxRounds is the maximum number of rounds passwords can be hashed with.
+const MaxRounds = 999999999
+
+// regular expression matching password hashes that can be set
+// (sha256-crypt and sha512-crypt, optionally with the number of rounds)
+var passwordHashRegex = regexp.MustCompile(`^\$(5\$(rounds=\d+\$)?[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]{43}|6\$(rounds=\d+\$)?[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]{86})$`)
+
+// ParseAlgorithm parses the name of a crypt algorithm ('sha256', 'sha512', case-insensitive).
+func ParseAlgorithm(s string) (Algorithm, error) {
+
+	switch algorithm := Algorithm(strings.ToLower(s)); algorithm {
+	case SHA256Crypt, SHA512Crypt:
+		return algorithm, nil
+	}
+
+	return "", fmt.Errorf("The algorithm (%s) is not supported, please choose one of the following: 'sha256', 'sha512'", s)
+}
+
+// regular expression matching salts that can be used to hash passwords
+var saltRegex = regexp.MustCompile(`^[./0-9A-Za-z]{1,16}$`)
+
+// HashPassword hashes the specified password with the specified algorithm and number of rounds using a random salt.
+// The number of rounds may be 0 to use the default number of rounds.
+func HashPassword(password string, algorithm Algorithm, rounds int) (string, error) {
+	return hashPassword(password, algorithm, rounds, "")
+}
+
+// HashPasswordWithSalt hashes the specified password with the specified algorithm and number of rounds using the
+// specified salt (1 to 16 characters out of [./0-9A-Za-z]). The number of rounds may be 0 to use the default number of
+// rounds. Hashing the same password with the same salt always results in the same hash, so the salt should differ for
+// every password that is hashed.
+func HashPasswordWithSalt(password string, algorithm Algorithm, rounds int, salt string) (string, error) {
+
+	if !saltRegex.MatchString(salt) {
+		return "", fmt.Errorf("The salt (%s) must consist of 1 to 16 characters out of [./0-9A-Za-z]", salt)
+	}
+
+	return hashPassword(password, algorithm, rounds, salt)
+}
+
+// hashPassword hashes the specified password with the specified algorithm, number of rounds and salt
+// (empty to generate a random salt).
+func hashPassword(password string, algorithm Algorithm, rounds int, salt string) (string, error) {
+
+	if rounds == 0 {
+		rounds = DefaultRounds
+	}
+
+	if rounds < MinRounds || rounds > MaxRounds {
+		return "", fmt.Errorf("The number of rounds (%d) must be in the range [%d,%d]", rounds, MinRounds, MaxRounds)
+	}
+
+	var crypter crypt.Crypter
+	var saltGenerator common.Salt
+	switch algorithm {
+	case SHA256Crypt:
+		crypter, saltGenerator = sha256_crypt.New(), sha256_crypt.GetSalt()
+	case SHA512Crypt:
+		crypter, saltGenerator = sha512_crypt.New(), sha512_crypt.GetSalt()
+	default:
+		return "", fmt.Errorf("The algorithm (%s) is not supported, please choose one of the following: 'sha256', 'sha512'", algorithm)
+	}
+
+	// generate a random salt (if no salt is specified) and put the number of rounds between the prefix and the salt,
+	// if necessary (the salt contains the number of rounds only, if it differs from the default)
+	saltWithPrefix := append([]byte(saltGenerator.MagicPrefix), salt...)
+	if len(salt) == 0 {
+		saltWithPrefix = saltGenerator.Generate(saltGenerator.SaltLenMax)
+	}
+	if rounds != DefaultRounds {
+		prefixLength := len(saltGenerator.MagicPrefix)
+		roundsText := fmt.Sprintf("rounds=%d$", rounds)
+		saltWithPrefix = append(append(append([]byte{}, saltWithPrefix[:prefixLength]...), roundsText...), saltWithPrefix[prefixLength:]...)
+	}
+
+	return crypter.Generate([]byte(password), saltWithPrefix)
+}
+
+// ValidatePasswordHash checks whether the specified string is a password hash that can be set
+// (sha256-crypt: '$5$...', sha512-crypt: '$6$...').
+func ValidatePasswordHash(hash string) error {
+
+	if !passwordHashRegex.MatchString(hash) {
+		return fmt.Errorf("The password hash is not a valid sha256-crypt ($5$...) or sha512-crypt ($6$...) hash")
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package shadow
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+
+	tests := []struct {
+		s        string
+		expected Algorithm
+		valid    bool
+	}{
+		{"sha256", SHA256Crypt, true},
+		{"SHA512", SHA512Crypt, true},
+		{"md5", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		algorithm, err := ParseAlgorithm(test.s)
+		if (err == nil) != test.valid || algorithm != test.expected {
+			t.Errorf("ParseAlgorithm(%s) returned (%q, %v)", test.s, algorithm, err)
+		}
+	}
+}
+
+func TestHashPasswordRounds(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		rounds    int
+		prefix    string // expected prefix of the hash (empty, if hashing must fail)
+	}{
+		{"sha256 default rounds", SHA256Crypt, 0, "$5$"},
+		{"sha512 default rounds", SHA512Crypt, 0, "$6$"},
+		{"explicit default rounds", SHA512Crypt, DefaultRounds, "$6$"},
+		{"minimum rounds", SHA256Crypt, MinRounds, "$5$rounds=1000$"},
+		{"more rounds", SHA512Crypt, 10000, "$6$rounds=10000$"},
+		{"too few rounds", SHA512Crypt, MinRounds - 1, ""},
+		{"too many rounds", SHA512Crypt, MaxRounds + 1, ""},
+		{"negative rounds", SHA256Crypt, -1, ""},
+		{"unknown algorithm", Algorithm("md5"), 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			hash, err := HashPassword("secret", test.algorithm, test.rounds)
+			if len(test.prefix) == 0 {
+				if err == nil {
+					t.Fatalf("Hashing succeeded unexpectedly (%s)", hash)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Hashing failed: %v", err)
+			}
+
+			if !strings.HasPrefix(hash, test.prefix) {
+				t.Errorf("The hash (%s) does not start with '%s'", hash, test.prefix)
+			}
+			if test.prefix == "$5$" || test.prefix == "$6$" {
+				if strings.Contains(hash, "rounds=") {
+					t.Errorf("The hash (%s) contains the default number of rounds", hash)
+				}
+			}
+			if err := ValidatePasswordHash(hash); err != nil {
+				t.Errorf("The hash (%s) cannot be set: %v", hash, err)
+			}
+
+			// the hash must verify the password
+			line := &line{Username: "test", Password: hash}
+			ok, err := line.VerifyPassword("secret")
+			if err != nil || !ok {
+				t.Errorf("The hash (%s) does not verify the password (error: %v)", hash, err)
+			}
+
+			// the salt is random, so hashing the password again must result in a different hash
+			other, err := HashPassword("secret", test.algorithm, test.rounds)
+			if err != nil {
+				t.Fatalf("Hashing failed: %v", err)
+			}
+			if other == hash {
+				t.Errorf("Hashing the password twice resulted in the same hash (%s)", hash)
+			}
+		})
+	}
+}
+
+func TestHashPasswordWithSalt(t *testing.T) {
+
+	tests := []struct {
+		algorithm Algorithm
+		rounds    int
+		salt      string
+		expected  string // expected hash (empty, if hashing must fail)
+	}{
+		{SHA256Crypt, 10000, "abc", "$5$rounds=10000$abc$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfBA"},
+		{SHA256Crypt, 0, "abc", "$5$abc$1qHIjNUAnXn/DW./nuTVf.YPE6le/q8rXofL7ytw184"},
+		{SHA512Crypt, 0, "mGuardDefault", "$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/"},
+		{SHA512Crypt, 0, "", ""},
+		{SHA512Crypt, 0, "0123456789abcdefg", ""},
+		{SHA512Crypt, 0, "a$b", ""},
+		{SHA512Crypt, 0, "a b", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s-%d-%s", test.algorithm, test.rounds, test.salt), func(t *testing.T) {
+			hash, err := HashPasswordWithSalt("root", test.algorithm, test.rounds, test.salt)
+			if len(test.expected) == 0 {
+				if err == nil {
+					t.Fatalf("Hashing succeeded unexpectedly (%s)", hash)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Hashing failed: %v", err)
+			}
+			if hash != test.expected {
+				t.Errorf("Expected hash %s, got %s", test.expected, hash)
+			}
+		})
+	}
+}
+
+func TestHashPasswordWithSaltMatchesOpenssl(t *testing.T) {
+
+	opensslPath, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skipf("The openssl executable was not found: %v", err)
+	}
+
+	tests := []struct {
+		algorithm Algorithm
+		rounds    int
+		salt      string
+		password  string
+	}{
+		{SHA256Crypt, 0, "abc", "root"},
+		{SHA256Crypt, 10000, "abc", "root"},
+		{SHA256Crypt, MinRounds, "./0123456789AZaz", "pass word"},
+		{SHA512Crypt, 0, "mGuardDefault", "root"},
+		{SHA512Crypt, 20000, "xyz", "mGuard"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s-%d-%s", test.algorithm, test.rounds, test.salt), func(t *testing.T) {
+
+			hash, err := HashPasswordWithSalt(test.password, test.algorithm, test.rounds, test.salt)
+			if err != nil {
+				t.Fatalf("Hashing failed: %v", err)
+			}
+
+			algorithmFlag := "-5"
+			if test.algorithm == SHA512Crypt {
+				algorithmFlag = "-6"
+			}
+			salt := test.salt
+			if test.rounds != 0 {
+				salt = fmt.Sprintf("rounds=%d$%s", test.rounds, test.salt)
+			}
+			output, err := exec.Command(opensslPath, "passwd", algorithmFlag, "-salt", salt, test.password).Output()
+			if err != nil {
+				t.Skipf("The openssl executable does not support %s (%v)", algorithmFlag, err)
+			}
+
+			expected := strings.TrimSpace(string(output))
+			if hash != expected {
+				t.Errorf("The hash differs from the hash generated by openssl\nexpected: %s\ngot:      %s", expected, hash)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordHash(t *testing.T) {
+
+	sha256Hash := "$5$rounds=10000$abc$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfBA"
+	sha512Hash := "$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/"
+
+	tests := []struct {
+		name  string
+		hash  string
+		valid bool
+	}{
+		{"sha256-crypt", sha256Hash, true},
+		{"sha512-crypt", sha512Hash, true},
+		{"md5-crypt", "$1$ZL0WsCrL$iQgd6BqvPGBs6eMt5b6Zt0", false},
+		{"locked", "!" + sha512Hash, false},
+		{"empty", "", false},
+		{"truncated", sha512Hash[:len(sha512Hash)-1], false},
+		{"wrong length for algorithm", "$5$mGuardDefault" + sha512Hash[len("$6$mGuardDefault"):], false},
+		{"salt too long", "$5$0123456789abcdefg$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfBA", false},
+		{"invalid rounds", "$5$rounds=x$abc$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfBA", false},
+		{"field separator", "$5$abc$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfB:", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidatePasswordHash(test.hash)
+			if (err == nil) != test.valid {
+				t.Errorf("ValidatePasswordHash(%s) returned %v", test.hash, err)
+			}
+		})
+	}
+}
+
+func TestFileSetPasswordHash(t *testing.T) {
+
+	hash := "$5$rounds=10000$abc$6t6RtTfCaSESS6Q/JwjYOcJ/LX9Xk9XtA7LO6UAZfBA"
+
+	tests := []struct {
+		name     string
+		username string
+		hash     string
+		valid    bool
+		changed  bool
+	}{
+		{"new hash", "root", hash, true, true},
+		{"same hash", "root", "$6$mGuardDefault$JOZT6Kha0l9fHxwQRvjATgO5r6L.XZVkcOrkAICXX0aqOKPl6r/ssDgA/jsXYCUjicmY9SZPT43UIL3v3I6gg/", true, false},
+		{"invalid hash", "root", "$1$ZL0WsCrL$iQgd6BqvPGBs6eMt5b6Zt0", false, false},
+		{"plain password", "root", "root", false, false},
+		{"unknown user", "unknown", hash, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			file := fileFromString(t, shadowTestFile)
+			err := file.SetPasswordHash(test.username, test.hash)
+			if (err == nil) != test.valid {
+				t.Fatalf("SetPasswordHash() returned %v", err)
+			}
+
+			if changed := file.String() != shadowTestFile; changed != test.changed {
+				t.Fatalf("Expected the file to be changed: %v, got:\n%s", test.changed, file.String())
+			}
+			if !test.changed {
+				return
+			}
+
+			// the password is verified by the hash and the day of the last change is updated
+			ok, err := file.VerifyPassword(test.username, "root")
+			if err != nil || !ok {
+				t.Errorf("The hash does not verify the password (error: %v)", err)
+			}
+			user, _ := file.User(test.username)
+			if user.LastChanged == nil || *user.LastChanged == 18518 {
+				t.Errorf("The day of the last password change was not updated")
+			}
+		})
+	}
+}
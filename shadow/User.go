@@ -0,0 +1,108 @@
+package shadow
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// PasswordStatus describes the state of the password of a user account.
+type PasswordStatus string
+
+const (
+	// UsablePassword indicates that the user can log in with a password.
+	UsablePassword PasswordStatus = "P"
+
+	// LockedPassword indicates that the password is locked (the user cannot log in with a password).
+	LockedPassword PasswordStatus = "L"
+
+	// NoPassword indicates that the account does not have a password.
+	NoPassword PasswordStatus = "NP"
+)
+
+// Aging contains the password aging fields of a user account.
+// Days are counted since 1970-01-01, a field that is not set is nil.
+type Aging struct {
+	LastChanged *int `json:"last_changed"` // day of the last password change (0 => the password must be changed at the next login)
+	Minimum     *int `json:"minimum"`      // days until a change is allowed
+	Maximum     *int `json:"maximum"`      // days before a change is required
+	Warn        *int `json:"warn"`         // days to warn the user before the password expires
+	Inactive    *int `json:"inactive"`     // days after the password expired before the account is locked
+	Expire      *int `json:"expire"`       // day the account expires
+}
+
+// User describes a user account in a shadow file (without the password hash).
+type User struct {
+	Name           string         `json:"name"`            // login name
+	PasswordStatus PasswordStatus `json:"password_status"` // state of the password
+	Aging                         // password aging fields
+}
+
+// Day returns the specified time as the number of days since 1970-01-01, as used by the aging fields.
+func Day(t time.Time) int {
+	return int(t.Unix() / (24 * 60 * 60))
+}
+
+// DayToTime returns the time of the specified day (days since 1970-01-01, UTC).
+func DayToTime(day int) time.Time {
+	return time.Unix(int64(day)*24*60*60, 0).UTC()
+}
+
+// user returns the description of the user account in the line.
+func (line *line) user() (User, error) {
+
+	user := User{Name: line.Username}
+
+	switch {
+	case len(line.Password) == 0:
+		user.PasswordStatus = NoPassword
+	case line.Password[0] == '!' || line.Password[0] == '*':
+		user.PasswordStatus = LockedPassword
+	default:
+		user.PasswordStatus = UsablePassword
+	}
+
+	fields := []struct {
+		name  string
+		value string
+		days  **int
+	}{
+		{"last changed", line.LastChanged, &user.LastChanged},
+		{"minimum", line.Minimum, &user.Minimum},
+		{"maximum", line.Maximum, &user.Maximum},
+		{"warn", line.Warn, &user.Warn},
+		{"inactive", line.Inactive, &user.Inactive},
+		{"expire", line.Expire, &user.Expire},
+	}
+
+	for _, field := range fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		days, err := strconv.Atoi(field.value)
+		if err != nil {
+			return User{}, fmt.Errorf("The field '%s' of user (%s) is not a number (%s)", field.name, line.Username, field.value)
+		}
+		*field.days = &days
+	}
+
+	return user, nil
+}
+
+// setAging sets the password aging fields of the line.
+func (line *line) setAging(aging Aging) {
+
+	format := func(days *int) string {
+		if days == nil {
+			return ""
+		}
+		return strconv.Itoa(*days)
+	}
+
+	line.LastChanged = format(aging.LastChanged)
+	line.Minimum = format(aging.Minimum)
+	line.Maximum = format(aging.Maximum)
+	line.Warn = format(aging.Warn)
+	line.Inactive = format(aging.Inactive)
+	line.Expire = format(aging.Expire)
+}
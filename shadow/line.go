@@ -2,10 +2,11 @@ package shadow
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tredoe/osutil/user/crypt"
-	"github.com/tredoe/osutil/user/crypt/sha512_crypt"
 )
 
 // line represents a line in a shadow file
@@ -65,22 +66,41 @@ func (line *line) String() string {
 		line.Reserved)
 }
 
-// SetPassword sets the password of the specified user
+// SetPassword sets the password of the specified user and the day of the last password change.
 func (line *line) SetPassword(password string) error {
 
 	if len(password) == 0 {
 		line.Password = "!" // disabled account
+		line.LastChanged = strconv.Itoa(Day(time.Now()))
 		return nil
 	}
 
 	// generate hash
-	c := sha512_crypt.New()
-	hash, err := c.Generate([]byte(password), []byte{})
+	hash, err := HashPassword(password, SHA512Crypt, 0)
 	if err != nil {
 		return err
 	}
 
 	line.Password = hash
+	line.LastChanged = strconv.Itoa(Day(time.Now()))
+	return nil
+}
+
+// SetPasswordHash sets the hashed password of the specified user and the day of the last password change.
+// If the user has the specified hash already, the line is not modified.
+func (line *line) SetPasswordHash(hash string) error {
+
+	err := ValidatePasswordHash(hash)
+	if err != nil {
+		return err
+	}
+
+	if line.Password == hash {
+		return nil
+	}
+
+	line.Password = hash
+	line.LastChanged = strconv.Itoa(Day(time.Now()))
 	return nil
 }
 
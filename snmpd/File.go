@@ -0,0 +1,300 @@
+// Package snmpd provides functions to read, modify and write the SNMPv3 users in the configuration file of the SNMP
+// daemon of a mGuard ('aca/snmpd' in ECS containers).
+package snmpd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// File represents the configuration file of the SNMP daemon with the SNMPv3 users.
+type File struct {
+	lines []*line
+}
+
+// User describes a SNMPv3 user (without passphrases).
+type User struct {
+	Name         string       // name of the user
+	EngineID     string       // engine id the user is created for (empty => local engine id)
+	AuthProtocol AuthProtocol // authentication protocol (empty => no authentication)
+	PrivProtocol PrivProtocol // privacy protocol (empty => no encryption)
+}
+
+// FactoryPassphrase is the well-known passphrase of the SNMPv3 user 'admin' in the factory settings of a mGuard.
+const FactoryPassphrase = "SnmpAdmin"
+
+// NewFile returns a new configuration file without any users.
+func NewFile() *File {
+
+	file := File{
+		lines: []*line{},
+	}
+
+	return &file
+}
+
+// FileFromReader loads a configuration file from the specified reader.
+func FileFromReader(reader io.Reader) (*File, error) {
+
+	file := NewFile()
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+
+		// earlier versions of the tool wrote an escaped newline instead of a newline character
+		// => remove it to parse the line properly
+		text := strings.TrimSuffix(scanner.Text(), `\n`)
+
+		line, err := lineFromString(text)
+		if err != nil {
+			return nil, fmt.Errorf("Error in snmpd file (line: %d): %s", lineNumber, err)
+		}
+		file.lines = append(file.lines, line)
+	}
+
+	// handle scanner error
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// ToWriter writes the configuration file using the specified writer.
+func (file *File) ToWriter(writer io.Writer) error {
+	_, err := writer.Write([]byte(file.String()))
+	return err
+}
+
+// Dupe returns a copy of the configuration file.
+func (file *File) Dupe() *File {
+
+	buffer := bytes.Buffer{}
+	err := file.ToWriter(&buffer)
+	if err != nil {
+		// should not occur...
+		panic("Unexpected error when serializing the snmpd file")
+	}
+
+	other, err := FileFromReader(&buffer)
+	if err != nil {
+		// should not occur...
+		panic("Unexpected error when deserializing the snmpd file")
+	}
+
+	return other
+}
+
+// Users returns the SNMPv3 users in the configuration file (in the order they appear in the file).
+func (file *File) Users() []User {
+
+	users := []User{}
+	for _, line := range file.lines {
+		if line.IsUser {
+			users = append(users, User{
+				Name:         line.Username,
+				EngineID:     line.EngineID,
+				AuthProtocol: line.AuthProtocol,
+				PrivProtocol: line.PrivProtocol,
+			})
+		}
+	}
+
+	return users
+}
+
+// HasUser checks whether the configuration file contains the specified user.
+func (file *File) HasUser(username string) bool {
+	return len(file.userLines(username)) > 0
+}
+
+// UsersWithFactoryPassphrase returns the names of the users that authenticate or encrypt messages using the well-known
+// passphrase of the factory settings (in the order they appear in the file).
+func (file *File) UsersWithFactoryPassphrase() []string {
+
+	usernames := []string{}
+	for _, line := range file.lines {
+		if !line.IsUser {
+			continue
+		}
+		factoryAuth := len(line.AuthKeyType) == 0 && line.AuthPassphrase == FactoryPassphrase
+		factoryPriv := len(line.PrivKeyType) == 0 && line.PrivPassphrase == FactoryPassphrase
+		if (factoryAuth || factoryPriv) && !containsString(usernames, line.Username) {
+			usernames = append(usernames, line.Username)
+		}
+	}
+
+	return usernames
+}
+
+// AddUser adds a new user to the configuration file. The privacy passphrase may be empty to use the authentication
+// passphrase for encryption as well.
+func (file *File) AddUser(
+	username string,
+	authProtocol AuthProtocol,
+	authPassphrase string,
+	privProtocol PrivProtocol,
+	privPassphrase string) error {
+
+	// ensure that the user does not exist, yet
+	if file.HasUser(username) {
+		return fmt.Errorf("The specified user (%s) exists already", username)
+	}
+
+	if len(username) == 0 || strings.ContainsAny(username, "\r\n") {
+		return fmt.Errorf("The specified username (%s) is invalid", username)
+	}
+
+	err := validateAuthentication(authProtocol, authPassphrase)
+	if err != nil {
+		return err
+	}
+
+	err = validatePrivacy(privProtocol, privPassphrase)
+	if err != nil {
+		return err
+	}
+
+	line := &line{IsUser: true, Username: username}
+	line.SetAuthentication(authProtocol, authPassphrase)
+	line.SetPrivacy(privProtocol, privPassphrase)
+	line.modified = true
+	file.lines = append(file.lines, line)
+	return nil
+}
+
+// RemoveUser removes the specified user from the configuration file.
+func (file *File) RemoveUser(username string) error {
+
+	lines := make([]*line, 0, len(file.lines))
+	for _, line := range file.lines {
+		if !line.IsUser || line.Username != username {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == len(file.lines) {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	file.lines = lines
+	return nil
+}
+
+// SetAuthentication sets the authentication protocol and passphrase of the specified user.
+// The file is only modified, if the settings differ from the current settings.
+func (file *File) SetAuthentication(username string, protocol AuthProtocol, passphrase string) error {
+
+	lines := file.userLines(username)
+	if len(lines) == 0 {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	err := validateAuthentication(protocol, passphrase)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		line.SetAuthentication(protocol, passphrase)
+	}
+
+	return nil
+}
+
+// SetPrivacy sets the privacy protocol and passphrase of the specified user. The passphrase may be empty to use the
+// authentication passphrase for encryption as well. NoPriv disables encryption.
+// The file is only modified, if the settings differ from the current settings.
+func (file *File) SetPrivacy(username string, protocol PrivProtocol, passphrase string) error {
+
+	lines := file.userLines(username)
+	if len(lines) == 0 {
+		return fmt.Errorf("The specified user (%s) does not exist", username)
+	}
+
+	err := validatePrivacy(protocol, passphrase)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		if line.AuthProtocol == NoAuth && protocol != NoPriv {
+			return fmt.Errorf("The specified user (%s) does not authenticate, encryption requires authentication", username)
+		}
+	}
+
+	for _, line := range lines {
+		line.SetPrivacy(protocol, passphrase)
+	}
+
+	return nil
+}
+
+// String returns the entire configuration file as a string.
+func (file *File) String() string {
+	builder := strings.Builder{}
+	for _, line := range file.lines {
+		builder.WriteString(line.String())
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// userLines returns the lines creating the specified user
+// (usually one line, but a user may be created for different engine ids).
+func (file *File) userLines(username string) []*line {
+
+	lines := []*line{}
+	for _, line := range file.lines {
+		if line.IsUser && line.Username == username {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// containsString checks whether the specified slice contains the specified string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAuthentication checks whether the specified authentication settings can be set.
+func validateAuthentication(protocol AuthProtocol, passphrase string) error {
+
+	if _, err := ParseAuthProtocol(string(protocol)); err != nil {
+		return err
+	}
+
+	return validatePassphrase(passphrase)
+}
+
+// validatePrivacy checks whether the specified privacy settings can be set.
+func validatePrivacy(protocol PrivProtocol, passphrase string) error {
+
+	if protocol == NoPriv {
+		if len(passphrase) > 0 {
+			return fmt.Errorf("A privacy passphrase cannot be set without a privacy protocol")
+		}
+		return nil
+	}
+
+	if _, err := ParsePrivProtocol(string(protocol)); err != nil {
+		return err
+	}
+
+	// an empty passphrase selects the authentication passphrase
+	if len(passphrase) == 0 {
+		return nil
+	}
+
+	return validatePassphrase(passphrase)
+}
@@ -0,0 +1,208 @@
+package snmpd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fileFromString parses the specified snmpd file.
+func fileFromString(t *testing.T, s string) *File {
+	t.Helper()
+
+	file, err := FileFromReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Parsing the snmpd file failed: %v", err)
+	}
+
+	return file
+}
+
+func TestUsersWithFactoryPassphrase(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{"no users", "", []string{}},
+		{"factory user", "createUser \"admin\" MD5 \"SnmpAdmin\" DES \"SnmpAdmin\"\n", []string{"admin"}},
+		{"factory privacy passphrase", "createUser monitor SHA \"Secret123\" AES \"SnmpAdmin\"\n", []string{"monitor"}},
+		{"changed passphrases", "createUser \"admin\" SHA \"Secret123\" AES \"Secret456\"\n", []string{}},
+		{"key instead of passphrase", "createUser admin MD5 -l SnmpAdmin DES -m SnmpAdmin\n", []string{}},
+		{"comment", "# createUser admin MD5 SnmpAdmin DES SnmpAdmin\n", []string{}},
+		{
+			"user for several engine ids",
+			"createUser -e 0x0102 admin MD5 SnmpAdmin DES\ncreateUser admin MD5 SnmpAdmin DES\ncreateUser other SHA SnmpAdmin\n",
+			[]string{"admin", "other"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := fileFromString(t, test.content)
+			usernames := file.UsersWithFactoryPassphrase()
+			if !reflect.DeepEqual(usernames, test.expected) {
+				t.Errorf("Expected users %v, got %v", test.expected, usernames)
+			}
+		})
+	}
+}
+
+// snmpdTestFile is a configuration file with users, comments and other directives.
+const snmpdTestFile = `# SNMPv3 users
+createUser "admin" MD5 "SnmpAdmin" DES "SnmpAdmin"
+createUser -e 0x0102 monitor SHA secret123
+createUser monitor SHA secret123
+rouser monitor auth
+`
+
+func TestFileOperations(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		modify   func(file *File) error
+		expected string
+	}{
+		{
+			"unmodified",
+			func(file *File) error { return nil },
+			snmpdTestFile,
+		},
+		{
+			"add user",
+			func(file *File) error { return file.AddUser("operator", SHA, "operator1", AES, "") },
+			snmpdTestFile + "createUser \"operator\" SHA \"operator1\" AES\n",
+		},
+		{
+			"add user without privacy",
+			func(file *File) error { return file.AddUser("operator", MD5, "operator1", NoPriv, "") },
+			snmpdTestFile + "createUser \"operator\" MD5 \"operator1\"\n",
+		},
+		{
+			"remove user",
+			func(file *File) error { return file.RemoveUser("admin") },
+			"# SNMPv3 users\ncreateUser -e 0x0102 monitor SHA secret123\ncreateUser monitor SHA secret123\nrouser monitor auth\n",
+		},
+		{
+			"remove user with several engine ids",
+			func(file *File) error { return file.RemoveUser("monitor") },
+			"# SNMPv3 users\ncreateUser \"admin\" MD5 \"SnmpAdmin\" DES \"SnmpAdmin\"\nrouser monitor auth\n",
+		},
+		{
+			"set authentication of all lines of a user",
+			func(file *File) error { return file.SetAuthentication("monitor", MD5, "changed123") },
+			"# SNMPv3 users\ncreateUser \"admin\" MD5 \"SnmpAdmin\" DES \"SnmpAdmin\"\ncreateUser -e 0x0102 \"monitor\" MD5 \"changed123\"\ncreateUser \"monitor\" MD5 \"changed123\"\nrouser monitor auth\n",
+		},
+		{
+			"disable privacy",
+			func(file *File) error { return file.SetPrivacy("admin", NoPriv, "") },
+			"# SNMPv3 users\ncreateUser \"admin\" MD5 \"SnmpAdmin\"\ncreateUser -e 0x0102 monitor SHA secret123\ncreateUser monitor SHA secret123\nrouser monitor auth\n",
+		},
+		{
+			"same settings",
+			func(file *File) error { return file.SetAuthentication("monitor", SHA, "secret123") },
+			snmpdTestFile,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := fileFromString(t, snmpdTestFile)
+			if err := test.modify(file); err != nil {
+				t.Fatalf("Modifying the file failed: %v", err)
+			}
+			if file.String() != test.expected {
+				t.Errorf("Unexpected file\nexpected:\n%s\ngot:\n%s", test.expected, file.String())
+			}
+		})
+	}
+}
+
+func TestFileOperationsReportErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		modify func(file *File) error
+	}{
+		{"add existing user", func(file *File) error { return file.AddUser("admin", MD5, "secret123", NoPriv, "") }},
+		{"add user without name", func(file *File) error { return file.AddUser("", MD5, "secret123", NoPriv, "") }},
+		{"add user with line break", func(file *File) error { return file.AddUser("a\nb", MD5, "secret123", NoPriv, "") }},
+		{"add user with short passphrase", func(file *File) error { return file.AddUser("operator", MD5, "short", NoPriv, "") }},
+		{"add user with unknown protocol", func(file *File) error { return file.AddUser("operator", "SHA256", "secret123", NoPriv, "") }},
+		{"add user with short privacy passphrase", func(file *File) error { return file.AddUser("operator", MD5, "secret123", DES, "short") }},
+		{"privacy passphrase without protocol", func(file *File) error { return file.AddUser("operator", MD5, "secret123", NoPriv, "secret456") }},
+		{"remove unknown user", func(file *File) error { return file.RemoveUser("unknown") }},
+		{"set authentication of unknown user", func(file *File) error { return file.SetAuthentication("unknown", MD5, "secret123") }},
+		{"set passphrase with line break", func(file *File) error { return file.SetAuthentication("admin", MD5, "secret\n123") }},
+		{"set privacy of unknown user", func(file *File) error { return file.SetPrivacy("unknown", DES, "secret123") }},
+		{"set privacy without authentication", func(file *File) error { return file.SetPrivacy("guest", DES, "secret123") }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := fileFromString(t, snmpdTestFile+"createUser guest\n")
+			before := file.String()
+			if err := test.modify(file); err == nil {
+				t.Fatalf("Modifying the file succeeded unexpectedly")
+			}
+			if file.String() != before {
+				t.Errorf("The file was modified although the operation failed")
+			}
+		})
+	}
+}
+
+func TestFileUsers(t *testing.T) {
+
+	file := fileFromString(t, snmpdTestFile)
+	expected := []User{
+		{Name: "admin", AuthProtocol: MD5, PrivProtocol: DES},
+		{Name: "monitor", EngineID: "0x0102", AuthProtocol: SHA},
+		{Name: "monitor", AuthProtocol: SHA},
+	}
+
+	if users := file.Users(); !reflect.DeepEqual(users, expected) {
+		t.Errorf("Expected users %+v, got %+v", expected, users)
+	}
+	if !file.HasUser("monitor") || file.HasUser("rouser") {
+		t.Errorf("HasUser() does not match the users in the file")
+	}
+}
+
+func TestParseProtocols(t *testing.T) {
+
+	authTests := []struct {
+		s        string
+		expected AuthProtocol
+		valid    bool
+	}{
+		{"MD5", MD5, true},
+		{"sha", SHA, true},
+		{"none", NoAuth, false},
+		{"SHA256", NoAuth, false},
+	}
+	for _, test := range authTests {
+		protocol, err := ParseAuthProtocol(test.s)
+		if (err == nil) != test.valid || protocol != test.expected {
+			t.Errorf("ParseAuthProtocol(%s) returned (%q, %v)", test.s, protocol, err)
+		}
+	}
+
+	privTests := []struct {
+		s        string
+		expected PrivProtocol
+		valid    bool
+	}{
+		{"DES", DES, true},
+		{"aes", AES, true},
+		{"None", NoPriv, true},
+		{"AES256", NoPriv, false},
+	}
+	for _, test := range privTests {
+		protocol, err := ParsePrivProtocol(test.s)
+		if (err == nil) != test.valid || protocol != test.expected {
+			t.Errorf("ParsePrivProtocol(%s) returned (%q, %v)", test.s, protocol, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package snmpd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AuthProtocol is a protocol SNMPv3 users authenticate with.
+type AuthProtocol string
+
+const (
+	// NoAuth indicates that the user does not authenticate (noAuthNoPriv).
+	NoAuth AuthProtocol = ""
+
+	// MD5 is the HMAC-MD5-96 authentication protocol.
+	MD5 AuthProtocol = "MD5"
+
+	// SHA is the HMAC-SHA-96 authentication protocol.
+	SHA AuthProtocol = "SHA"
+)
+
+// PrivProtocol is a protocol SNMPv3 users encrypt messages with.
+type PrivProtocol string
+
+const (
+	// NoPriv indicates that messages are not encrypted (authNoPriv).
+	NoPriv PrivProtocol = ""
+
+	// DES is the CBC-DES privacy protocol.
+	DES PrivProtocol = "DES"
+
+	// AES is the CFB128-AES-128 privacy protocol.
+	AES PrivProtocol = "AES"
+)
+
+// MinPassphraseLength is the minimum length of passphrases (the SNMP daemon ignores users with shorter passphrases).
+const MinPassphraseLength = 8
+
+// ParseAuthProtocol parses the name of an authentication protocol ('MD5', 'SHA', case-insensitive).
+func ParseAuthProtocol(s string) (AuthProtocol, error) {
+
+	switch protocol := AuthProtocol(strings.ToUpper(s)); protocol {
+	case MD5, SHA:
+		return protocol, nil
+	}
+
+	return NoAuth, fmt.Errorf("The authentication protocol (%s) is not supported, please choose one of the following: 'MD5', 'SHA'", s)
+}
+
+// ParsePrivProtocol parses the name of a privacy protocol ('DES', 'AES', case-insensitive).
+// 'none' selects no privacy protocol.
+func ParsePrivProtocol(s string) (PrivProtocol, error) {
+
+	if strings.EqualFold(s, "none") {
+		return NoPriv, nil
+	}
+
+	switch protocol := PrivProtocol(strings.ToUpper(s)); protocol {
+	case DES, AES:
+		return protocol, nil
+	}
+
+	return NoPriv, fmt.Errorf("The privacy protocol (%s) is not supported, please choose one of the following: 'DES', 'AES', 'none'", s)
+}
+
+// validatePassphrase checks whether the specified passphrase can be used to authenticate or encrypt messages.
+func validatePassphrase(passphrase string) error {
+
+	if len(passphrase) < MinPassphraseLength {
+		return fmt.Errorf("The passphrase must be at least %d characters long", MinPassphraseLength)
+	}
+
+	if strings.ContainsAny(passphrase, "\r\n") {
+		return fmt.Errorf("The passphrase must not contain line breaks")
+	}
+
+	return nil
+}
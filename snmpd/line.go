@@ -0,0 +1,218 @@
+package snmpd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// line represents a line in the configuration file of the SNMP daemon.
+// Lines creating SNMPv3 users are parsed, all other lines (comments, other directives) are kept as they are
+// (also see http://www.net-snmp.org/docs/man/snmpd.conf.html, section 'SNMPv3 Users').
+type line struct {
+	raw            string       // the line as read (written as is, unless the line is modified)
+	modified       bool         // true, if the line was modified and must be generated from the fields
+	IsUser         bool         // true, if the line creates a user ('createUser')
+	EngineID       string       // engine id the user is created for (empty => local engine id)
+	Username       string       // name of the user
+	AuthProtocol   AuthProtocol // authentication protocol (empty => no authentication)
+	AuthKeyType    string       // '-l' (localized key) or '-m' (master key), if a key is specified instead of a passphrase
+	AuthPassphrase string       // authentication passphrase (or key)
+	PrivProtocol   PrivProtocol // privacy protocol (empty => no encryption)
+	PrivKeyType    string       // '-l' (localized key) or '-m' (master key), if a key is specified instead of a passphrase
+	PrivPassphrase string       // privacy passphrase (or key, empty => authentication passphrase)
+}
+
+// createUserDirective is the directive that creates SNMPv3 users.
+const createUserDirective = "createUser"
+
+// lineFromString parses the specified line of the configuration file of the SNMP daemon.
+func lineFromString(s string) (*line, error) {
+
+	line := &line{raw: s}
+
+	// keep comments and empty lines
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+		return line, nil
+	}
+
+	tokens, err := tokenize(trimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	// keep other directives
+	if !strings.EqualFold(tokens[0], createUserDirective) {
+		return line, nil
+	}
+
+	// createUser [-e ENGINEID] username [(MD5|SHA) [-l|-m] authpassphrase [(DES|AES) [-l|-m] [privpassphrase]]]
+	line.IsUser = true
+	next := 1
+	if next < len(tokens) && tokens[next] == "-e" {
+		if next+1 >= len(tokens) {
+			return nil, fmt.Errorf("The engine id is missing")
+		}
+		line.EngineID = tokens[next+1]
+		next += 2
+	}
+
+	if next >= len(tokens) {
+		return nil, fmt.Errorf("The username is missing")
+	}
+	line.Username = tokens[next]
+	next++
+
+	if next < len(tokens) {
+		line.AuthProtocol = AuthProtocol(tokens[next])
+		next++
+		if next < len(tokens) && isKeyType(tokens[next]) {
+			line.AuthKeyType = tokens[next]
+			next++
+		}
+		if next >= len(tokens) {
+			return nil, fmt.Errorf("The authentication passphrase of user '%s' is missing", line.Username)
+		}
+		line.AuthPassphrase = tokens[next]
+		next++
+	}
+
+	if next < len(tokens) {
+		line.PrivProtocol = PrivProtocol(tokens[next])
+		next++
+		if next < len(tokens) && isKeyType(tokens[next]) {
+			line.PrivKeyType = tokens[next]
+			next++
+		}
+		if next < len(tokens) {
+			line.PrivPassphrase = tokens[next]
+			next++
+		}
+	}
+
+	if next < len(tokens) {
+		return nil, fmt.Errorf("Unexpected token '%s' after the settings of user '%s'", tokens[next], line.Username)
+	}
+
+	return line, nil
+}
+
+// String returns the line as it occurs in the configuration file of the SNMP daemon
+// (without the newline character at the end).
+func (line *line) String() string {
+
+	if !line.modified {
+		return line.raw
+	}
+
+	tokens := []string{createUserDirective}
+	if len(line.EngineID) > 0 {
+		tokens = append(tokens, "-e", line.EngineID)
+	}
+	tokens = append(tokens, quote(line.Username))
+
+	if line.AuthProtocol != NoAuth {
+		tokens = append(tokens, string(line.AuthProtocol))
+		if len(line.AuthKeyType) > 0 {
+			tokens = append(tokens, line.AuthKeyType)
+		}
+		tokens = append(tokens, quote(line.AuthPassphrase))
+
+		if line.PrivProtocol != NoPriv {
+			tokens = append(tokens, string(line.PrivProtocol))
+			if len(line.PrivKeyType) > 0 {
+				tokens = append(tokens, line.PrivKeyType)
+			}
+			if len(line.PrivPassphrase) > 0 {
+				tokens = append(tokens, quote(line.PrivPassphrase))
+			}
+		}
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// SetAuthentication sets the authentication protocol and passphrase of the user.
+// The line is only modified, if the settings differ from the current settings.
+func (line *line) SetAuthentication(protocol AuthProtocol, passphrase string) {
+
+	if line.AuthProtocol == protocol && len(line.AuthKeyType) == 0 && line.AuthPassphrase == passphrase {
+		return
+	}
+
+	line.AuthProtocol = protocol
+	line.AuthKeyType = ""
+	line.AuthPassphrase = passphrase
+	line.modified = true
+}
+
+// SetPrivacy sets the privacy protocol and passphrase of the user.
+// The line is only modified, if the settings differ from the current settings.
+func (line *line) SetPrivacy(protocol PrivProtocol, passphrase string) {
+
+	if line.PrivProtocol == protocol && len(line.PrivKeyType) == 0 && line.PrivPassphrase == passphrase {
+		return
+	}
+
+	line.PrivProtocol = protocol
+	line.PrivKeyType = ""
+	line.PrivPassphrase = passphrase
+	line.modified = true
+}
+
+// isKeyType checks whether the specified token indicates that a key is specified instead of a passphrase.
+func isKeyType(token string) bool {
+	return token == "-l" || token == "-m"
+}
+
+// tokenize splits the specified line into whitespace separated tokens.
+// Tokens may be enclosed in single or double quotes to contain whitespaces, a backslash escapes the next character
+// within quotes.
+func tokenize(s string) ([]string, error) {
+
+	tokens := []string{}
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+
+		// skip whitespaces between tokens
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		token := strings.Builder{}
+		if runes[i] == '"' || runes[i] == '\'' {
+			quote := runes[i]
+			terminated := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				} else if runes[i] == quote {
+					terminated = true
+					i++
+					break
+				}
+				token.WriteRune(runes[i])
+			}
+			if !terminated {
+				return nil, fmt.Errorf("The quoted string '%s' is not terminated", token.String())
+			}
+		} else {
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				token.WriteRune(runes[i])
+			}
+		}
+
+		tokens = append(tokens, token.String())
+	}
+
+	return tokens, nil
+}
+
+// quote encloses the specified string in double quotes and escapes double quotes and backslashes in it.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
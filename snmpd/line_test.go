@@ -0,0 +1,187 @@
+package snmpd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{"plain tokens", "createUser admin MD5 secret123", []string{"createUser", "admin", "MD5", "secret123"}},
+		{"multiple whitespaces", "  createUser\t admin  ", []string{"createUser", "admin"}},
+		{"double quotes", `createUser "my user"`, []string{"createUser", "my user"}},
+		{"single quotes", `createUser 'my user'`, []string{"createUser", "my user"}},
+		{"empty quoted token", `createUser ""`, []string{"createUser", ""}},
+		{"escaped quote", `"say \"hello\""`, []string{`say "hello"`}},
+		{"escaped backslash", `"a\\b"`, []string{`a\b`}},
+		{"other quote inside quotes", `"it's" 'a "b"'`, []string{"it's", `a "b"`}},
+		{"backslash outside quotes", `a\b`, []string{`a\b`}},
+		{"empty line", "", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := tokenize(test.line)
+			if err != nil {
+				t.Fatalf("Tokenizing failed: %v", err)
+			}
+			if !reflect.DeepEqual(tokens, test.expected) {
+				t.Errorf("Expected tokens %q, got %q", test.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestTokenizeReportsUnterminatedQuotes(t *testing.T) {
+
+	for _, line := range []string{`createUser "admin`, `createUser 'admin`, `"escaped at the end\"`} {
+		if _, err := tokenize(line); err == nil {
+			t.Errorf("Tokenizing '%s' succeeded unexpectedly", line)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+
+	for _, s := range []string{"", "plain", "with space", `with "quotes"`, `with \backslash`, `\"`} {
+		tokens, err := tokenize(quote(s))
+		if err != nil {
+			t.Fatalf("Tokenizing the quoted string (%s) failed: %v", quote(s), err)
+		}
+		if !reflect.DeepEqual(tokens, []string{s}) {
+			t.Errorf("Quoting '%s' and tokenizing it again resulted in %q", s, tokens)
+		}
+	}
+}
+
+func TestLineFromString(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		line     string
+		expected line
+	}{
+		{"comment", "# createUser admin", line{}},
+		{"other directive", "rouser admin priv", line{}},
+		{"user without authentication", "createUser guest", line{IsUser: true, Username: "guest"}},
+		{
+			"user with authentication",
+			`createUser admin SHA "secret 123"`,
+			line{IsUser: true, Username: "admin", AuthProtocol: SHA, AuthPassphrase: "secret 123"},
+		},
+		{
+			"user with privacy",
+			`createUser "admin" MD5 "SnmpAdmin" DES "SnmpAdmin"`,
+			line{IsUser: true, Username: "admin", AuthProtocol: MD5, AuthPassphrase: "SnmpAdmin", PrivProtocol: DES, PrivPassphrase: "SnmpAdmin"},
+		},
+		{
+			"privacy without passphrase",
+			"createUser admin MD5 secret123 AES",
+			line{IsUser: true, Username: "admin", AuthProtocol: MD5, AuthPassphrase: "secret123", PrivProtocol: AES},
+		},
+		{
+			"engine id",
+			"createUser -e 0x80001f8880 admin MD5 secret123",
+			line{IsUser: true, EngineID: "0x80001f8880", Username: "admin", AuthProtocol: MD5, AuthPassphrase: "secret123"},
+		},
+		{
+			"localized and master keys",
+			"createUser admin MD5 -l 0x0102 DES -m 0x0304",
+			line{IsUser: true, Username: "admin", AuthProtocol: MD5, AuthKeyType: "-l", AuthPassphrase: "0x0102", PrivProtocol: DES, PrivKeyType: "-m", PrivPassphrase: "0x0304"},
+		},
+		{"directive case-insensitive", "CREATEUSER admin", line{IsUser: true, Username: "admin"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := lineFromString(test.line)
+			if err != nil {
+				t.Fatalf("Parsing failed: %v", err)
+			}
+			test.expected.raw = test.line
+			if !reflect.DeepEqual(*parsed, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, *parsed)
+			}
+			if parsed.String() != test.line {
+				t.Errorf("An unmodified line must be written as read, got '%s'", parsed.String())
+			}
+		})
+	}
+}
+
+func TestLineFromStringReportsInvalidLines(t *testing.T) {
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"missing username", "createUser"},
+		{"missing engine id", "createUser -e"},
+		{"missing username after engine id", "createUser -e 0x0102"},
+		{"missing authentication passphrase", "createUser admin MD5"},
+		{"missing authentication key", "createUser admin MD5 -l"},
+		{"unexpected token", "createUser admin MD5 secret123 DES secret456 extra"},
+		{"unterminated quote", `createUser "admin`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := lineFromString(test.line); err == nil {
+				t.Errorf("Parsing '%s' succeeded unexpectedly", test.line)
+			}
+		})
+	}
+}
+
+func TestLineStringAfterModification(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		line     string
+		modify   func(line *line)
+		expected string
+	}{
+		{
+			"set authentication",
+			"createUser admin MD5 -l 0x0102",
+			func(line *line) { line.SetAuthentication(SHA, "new secret") },
+			`createUser "admin" SHA "new secret"`,
+		},
+		{
+			"set privacy keeps engine id",
+			"createUser -e 0x0102 admin MD5 secret123",
+			func(line *line) { line.SetPrivacy(AES, `pass"phrase`) },
+			`createUser -e 0x0102 "admin" MD5 "secret123" AES "pass\"phrase"`,
+		},
+		{
+			"privacy without passphrase",
+			"createUser admin MD5 secret123 DES secret456",
+			func(line *line) { line.SetPrivacy(AES, "") },
+			`createUser "admin" MD5 "secret123" AES`,
+		},
+		{
+			"same settings keep the line",
+			"createUser  admin  MD5  secret123",
+			func(line *line) { line.SetAuthentication(MD5, "secret123") },
+			"createUser  admin  MD5  secret123",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := lineFromString(test.line)
+			if err != nil {
+				t.Fatalf("Parsing failed: %v", err)
+			}
+			test.modify(parsed)
+			if parsed.String() != test.expected {
+				t.Errorf("Expected '%s', got '%s'", test.expected, parsed.String())
+			}
+		})
+	}
+}